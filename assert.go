@@ -71,7 +71,10 @@ func (a DefaultVerifier) JSONEq(t TestingT, expected string, actual string, msgA
 		return a.Fail(t, fmt.Sprintf("Input ('%s') needs to be valid json.\nJSON parsing error: '%s'", actual, err.Error()), msgAndArgs...)
 	}
 
-	return a.Equal(t, expectedJSONAsInterface, actualJSONAsInterface, msgAndArgs...)
+	if !objectsAreEqual(expectedJSONAsInterface, actualJSONAsInterface) {
+		return a.Fail(t, "Not equal JSON:\n"+bodyDiff(expected, actual), msgAndArgs...)
+	}
+	return true
 }
 
 // Equal asserts that two values are equal
@@ -94,6 +97,9 @@ func (a DefaultVerifier) Equal(t TestingT, expected, actual interface{}, msgAndA
 
 // Fail reports a failure
 func (a DefaultVerifier) Fail(t TestingT, failureMessage string, msgAndArgs ...interface{}) bool {
+	if useColor() {
+		failureMessage = colorizeDiffs(failureMessage)
+	}
 	content := []labeledContent{
 		{"Error Trace", strings.Join(callerInfo(), "\n")},
 		{"Error", failureMessage},
@@ -0,0 +1,175 @@
+package spectest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	difflib "github.com/nao1215/diff"
+)
+
+// The ANSI escape codes of the colors of the diffs
+const (
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorCyan  = "\x1b[36m"
+	colorReset = "\x1b[0m"
+)
+
+// useColor returns true if the diffs of the failures written to the test log are colored: the standard output,
+// where the test log is written, is a terminal and NO_COLOR is not set.
+var useColor = func() bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// bodyDiff describes the differences between the expected and the actual bodies.
+// JSON bodies are compared by path, e.g.
+//
+//	Changes:
+//	  $.items[3].price: 10 → 12
+//	  +$.items[4]: {"sku":"pen"}
+//	  -$.meta.total: 5
+//
+// followed by a unified diff of the indented bodies. Other bodies get a unified diff of their lines.
+func bodyDiff(expected, actual string) string {
+	var expectedJSON, actualJSON interface{}
+	if json.Unmarshal([]byte(expected), &expectedJSON) != nil || json.Unmarshal([]byte(actual), &actualJSON) != nil {
+		return "Diff:\n" + unifiedDiff(expected, actual)
+	}
+
+	var b strings.Builder
+	b.WriteString("Changes:\n")
+	for _, change := range jsonChanges(nil, expectedJSON, actualJSON) {
+		b.WriteString("  " + change + "\n")
+	}
+	b.WriteString("\nDiff:\n")
+	b.WriteString(unifiedDiff(indentJSON(expectedJSON), indentJSON(actualJSON)))
+	return b.String()
+}
+
+// jsonChanges lists the changes from the expected value to the actual value by path:
+// a changed value, an added value (+) and a removed value (-).
+func jsonChanges(path jsonPath, expected, actual interface{}) []string {
+	switch expected := expected.(type) {
+	case map[string]interface{}:
+		if actual, ok := actual.(map[string]interface{}); ok {
+			var changes []string
+			for _, key := range sortedKeys(expected) {
+				if value, ok := actual[key]; ok {
+					changes = append(changes, jsonChanges(path.key(key), expected[key], value)...)
+				} else {
+					changes = append(changes, fmt.Sprintf("-%s: %s", path.key(key), formatJSON(expected[key])))
+				}
+			}
+			for _, key := range sortedKeys(actual) {
+				if _, ok := expected[key]; !ok {
+					changes = append(changes, fmt.Sprintf("+%s: %s", path.key(key), formatJSON(actual[key])))
+				}
+			}
+			return changes
+		}
+	case []interface{}:
+		if actual, ok := actual.([]interface{}); ok {
+			var changes []string
+			for i := 0; i < len(expected) || i < len(actual); i++ {
+				switch {
+				case i >= len(actual):
+					changes = append(changes, fmt.Sprintf("-%s: %s", path.index(i), formatJSON(expected[i])))
+				case i >= len(expected):
+					changes = append(changes, fmt.Sprintf("+%s: %s", path.index(i), formatJSON(actual[i])))
+				default:
+					changes = append(changes, jsonChanges(path.index(i), expected[i], actual[i])...)
+				}
+			}
+			return changes
+		}
+	}
+	if reflect.DeepEqual(expected, actual) {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s: %s → %s", path, formatJSON(expected), formatJSON(actual))}
+}
+
+// indentJSON formats the JSON value with indentation and sorted keys, so that it can be diffed by lines
+func indentJSON(v interface{}) string {
+	var b strings.Builder
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return b.String()
+}
+
+// unifiedDiff returns a compact unified diff of the lines of the texts.
+func unifiedDiff(expected, actual string) string {
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(strings.TrimSuffix(expected, "\n")),
+		B:        difflib.SplitLines(strings.TrimSuffix(actual, "\n")),
+		FromFile: "Expected",
+		ToFile:   "Actual",
+		Context:  2,
+	})
+	return diff
+}
+
+// colorizeDiffs colors the unified diffs of a failure message, which may be indented, e.g. in the summary
+// of the soft assertions. The diffs are only colored when the message is written to the test log:
+// the errors returned to the http client and the recorded failures stay plain.
+func colorizeDiffs(message string) string {
+	lines := strings.SplitAfter(message, "\n")
+	indent, inDiff := "", false
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		switch {
+		case strings.HasPrefix(trimmed, "--- ") && i+1 < len(lines) &&
+			strings.HasPrefix(lines[i+1], line[:len(line)-len(trimmed)]+"+++ "):
+			indent, inDiff = line[:len(line)-len(trimmed)], true
+		case inDiff && (!strings.HasPrefix(line, indent) || !isDiffLine(line[len(indent):])):
+			inDiff = false
+		}
+		if inDiff {
+			lines[i] = indent + colorizeDiff(line[len(indent):])
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// isDiffLine returns true if the line belongs to the body of a unified diff
+func isDiffLine(line string) bool {
+	for _, prefix := range []string{" ", "-", "+", "@@", "\\"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// colorizeDiff colors the removed lines of a unified diff in red, the added lines in green and the hunk headers in cyan.
+func colorizeDiff(diff string) string {
+	lines := strings.SplitAfter(diff, "\n")
+	for i, line := range lines {
+		content := strings.TrimSuffix(line, "\n")
+		color := ""
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+		case strings.HasPrefix(line, "-"):
+			color = colorRed
+		case strings.HasPrefix(line, "+"):
+			color = colorGreen
+		case strings.HasPrefix(line, "@@"):
+			color = colorCyan
+		}
+		if color != "" {
+			lines[i] = color + content + colorReset + line[len(content):]
+		}
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,197 @@
+package spectest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func withoutColor(t *testing.T) {
+	t.Helper()
+	original := useColor
+	useColor = func() bool { return false }
+	t.Cleanup(func() { useColor = original })
+}
+
+func withColor(t *testing.T) {
+	t.Helper()
+	original := useColor
+	useColor = func() bool { return true }
+	t.Cleanup(func() { useColor = original })
+}
+
+func TestJSONChanges(t *testing.T) {
+	tests := map[string]struct {
+		expected string
+		actual   string
+		changes  []string
+	}{
+		"equal": {
+			expected: `{"a": 1, "b": [1, 2]}`,
+			actual:   `{"b": [1, 2], "a": 1}`,
+		},
+		"changed values": {
+			expected: `{"items": [{"price": 10}, {"price": 5}], "name": "pen"}`,
+			actual:   `{"items": [{"price": 12}, {"price": 5}], "name": "pencil"}`,
+			changes:  []string{"$.items[0].price: 10 → 12", `$.name: "pen" → "pencil"`},
+		},
+		"added and removed values": {
+			expected: `{"items": [1], "meta": {"total": 5}}`,
+			actual:   `{"items": [1, {"sku": "pen"}], "meta": {}, "next": null}`,
+			changes:  []string{`+$.items[1]: {"sku":"pen"}`, "-$.meta.total: 5", "+$.next: null"},
+		},
+		"changed types": {
+			expected: `{"a": {"b": 1}}`,
+			actual:   `{"a": [1]}`,
+			changes:  []string{`$.a: {"b":1} → [1]`},
+		},
+		"keys that are not identifiers": {
+			expected: `{"content-type": "json", "a b": 1}`,
+			actual:   `{"content-type": "xml", "a b": 2}`,
+			changes:  []string{"$['a b']: 1 → 2", `$.content-type: "json" → "xml"`},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var expected, actual interface{}
+			assert.NoError(t, json.Unmarshal([]byte(test.expected), &expected))
+			assert.NoError(t, json.Unmarshal([]byte(test.actual), &actual))
+			assert.Equal(t, test.changes, jsonChanges(nil, expected, actual))
+		})
+	}
+}
+
+func TestBodyDiffOfJSONBodies(t *testing.T) {
+	withoutColor(t)
+
+	assert.Equal(t, `Changes:
+  $.items[1].price: 10 → 12
+  -$.total: 22
+
+Diff:
+--- Expected
++++ Actual
+@@ -7,7 +7,6 @@
+     {
+       "id": 2,
+-      "price": 10
++      "price": 12
+     }
+-  ],
+-  "total": 22
++  ]
+ }
+`, bodyDiff(
+		`{"items": [{"id": 1, "price": 10}, {"id": 2, "price": 10}], "total": 22}`,
+		`{"items": [{"id": 1, "price": 10}, {"id": 2, "price": 12}]}`,
+	))
+}
+
+func TestBodyDiffOfTextBodies(t *testing.T) {
+	withoutColor(t)
+
+	assert.Equal(t, `Diff:
+--- Expected
++++ Actual
+@@ -1,3 +1,3 @@
+ hello
+-world
++there
+ !
+`, bodyDiff("hello\nworld\n!\n", "hello\nthere\n!\n"))
+}
+
+func TestColorizeDiff(t *testing.T) {
+	diff := "--- Expected\n+++ Actual\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n"
+
+	assert.Equal(t,
+		"--- Expected\n+++ Actual\n"+
+			colorCyan+"@@ -1,2 +1,2 @@"+colorReset+"\n"+
+			" a\n"+
+			colorRed+"-b"+colorReset+"\n"+
+			colorGreen+"+c"+colorReset+"\n",
+		colorizeDiff(diff))
+}
+
+func TestDefaultVerifierJSONEqShowsChanges(t *testing.T) {
+	withoutColor(t)
+	recorder := &errorRecorder{}
+
+	assert.True(t, !DefaultVerifier{}.JSONEq(recorder, `{"a": 1, "b": 2}`, `{"a": 1, "b": 3}`))
+
+	assert.True(t, strings.Contains(recorder.message, "Not equal JSON:"))
+	assert.True(t, strings.Contains(recorder.message, "$.b: 2 → 3"))
+	assert.True(t, strings.Contains(recorder.message, `-  "b": 2`))
+	assert.True(t, strings.Contains(recorder.message, `+  "b": 3`))
+}
+
+func TestBodyMatcherShowsJSONChanges(t *testing.T) {
+	withoutColor(t)
+	req, err := http.NewRequest(http.MethodPost, "http://example.com/user", strings.NewReader(`{"name": "bob", "age": 30}`))
+	assert.NoError(t, err)
+	mockRequest := NewMock().Post("/user").JSON(`{"name": "alice", "age": 30}`)
+
+	err = bodyMatcher(req, mockRequest)
+
+	assert.True(t, err != nil)
+	assert.True(t, strings.HasPrefix(err.Error(),
+		"received body did not match expected mock body\nChanges:\n  $.name: \"alice\" → \"bob\"\n\nDiff:\n"))
+}
+
+func TestColorizeDiffsOnlyColorsTheDiffs(t *testing.T) {
+	message := "response did not match\n- not a diff\nDiff:\n--- Expected\n+++ Actual\n@@ -1 +1 @@\n-a\n+b\n\n+ after the diff"
+
+	assert.Equal(t,
+		"response did not match\n- not a diff\nDiff:\n--- Expected\n+++ Actual\n"+
+			colorCyan+"@@ -1 +1 @@"+colorReset+"\n"+
+			colorRed+"-a"+colorReset+"\n"+
+			colorGreen+"+b"+colorReset+"\n"+
+			"\n+ after the diff",
+		colorizeDiffs(message))
+}
+
+func TestDiffsAreOnlyColoredInTheTestLog(t *testing.T) {
+	withColor(t)
+	recorder := &errorRecorder{}
+
+	result := New().
+		SoftAssertions().
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = w.Write([]byte("<user><name>bob</name></user>"))
+		}).
+		Get("/").
+		Expect(recorder).
+		Body("<user><name>alice</name></user>").
+		End()
+
+	assert.True(t, strings.Contains(recorder.message, colorRed+"-  <name>alice</name>"+colorReset))
+	assert.True(t, strings.Contains(recorder.message, colorGreen+"+  <name>bob</name>"+colorReset))
+	failures := result.Failures()
+	assert.Equal(t, 1, len(failures))
+	assert.True(t, strings.Contains(failures[0].Message, "\n-  <name>alice</name>\n+  <name>bob</name>"))
+	assert.True(t, !strings.Contains(failures[0].Message, "\x1b["))
+}
+
+func TestBodyMatcherErrorIsNotColored(t *testing.T) {
+	withColor(t)
+	req, err := http.NewRequest(http.MethodPost, "http://example.com/user", strings.NewReader(`{"name": "bob"}`))
+	assert.NoError(t, err)
+
+	err = bodyMatcher(req, NewMock().Post("/user").JSON(`{"name": "alice"}`))
+
+	assert.True(t, err != nil)
+	assert.True(t, !strings.Contains(err.Error(), "\x1b["))
+}
+
+// errorRecorder is a TestingT that records the message of its last error
+type errorRecorder struct {
+	mockTestingT
+	message string
+}
+
+func (r *errorRecorder) Errorf(format string, args ...interface{}) {
+	r.message = fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,536 @@
+package spectest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/nao1215/gorky/file"
+)
+
+// CassetteMode is the mode of a cassette
+type CassetteMode string
+
+const (
+	// CassetteAuto replays the cassette if the cassette file exists, and records it otherwise
+	CassetteAuto CassetteMode = "auto"
+	// CassetteRecord sends the outbound requests to the network and records them to the cassette file
+	CassetteRecord CassetteMode = "record"
+	// CassetteReplay serves the outbound requests from the cassette file instead of the network
+	CassetteReplay CassetteMode = "replay"
+)
+
+// CassetteModeEnv is the environment variable that selects the mode of the cassettes
+// that do not set a mode explicitly, e.g. SPECTEST_CASSETTE_MODE=record go test ./...
+const CassetteModeEnv = "SPECTEST_CASSETTE_MODE"
+
+// redacted is the value that replaces the redacted data
+const redacted = "REDACTED"
+
+// cassetteModeFlag selects the mode of the cassettes, e.g. go test ./... -spectest.cassette=record
+// It takes precedence over CassetteModeEnv.
+var cassetteModeFlag = flag.String("spectest.cassette", "", "cassette mode: auto, record or replay")
+
+// Cassette records the outbound http interactions of the handler to a file, and replays them.
+// In replay mode, the recorded interactions are served by mocks, so they are matched
+// by method, url, query and body in the order they were recorded.
+// In record mode, the recorder replaces http.DefaultTransport (or the transport of the client
+// given by HTTPClient) while the test runs, unless the mocks are isolated with IsolateMocks:
+// the outbound requests are then recorded through the mock router, so the test can run in parallel.
+type Cassette struct {
+	// path is the path of the cassette file
+	path string
+	// mode is the explicit mode of the cassette. If empty, the mode is selected by flag or environment variable.
+	mode CassetteMode
+	// strict fails the test if a request is not found in the cassette.
+	// Otherwise, the request is sent to the network.
+	strict bool
+	// redactHeaders is the list of header names whose values are redacted
+	redactHeaders []string
+	// redactPatterns is the list of patterns that are redacted from the urls, headers and bodies
+	redactPatterns []*regexp.Regexp
+
+	// active is the mode of the current run
+	active CassetteMode
+	// recorder is the recorder of the current run if it records through the mock router, or nil
+	recorder *cassetteRecorder
+
+	// mu guards interactions and unmatched
+	mu sync.Mutex
+	// interactions is the list of recorded interactions
+	interactions []CassetteInteraction
+	// unmatched is the list of requests that were not found in the cassette
+	unmatched []string
+}
+
+// cassetteFile is the content of a cassette file
+type cassetteFile struct {
+	// Version is the version of the file format
+	Version int `json:"version"`
+	// Interactions is the list of recorded interactions
+	Interactions []CassetteInteraction `json:"interactions"`
+}
+
+// CassetteInteraction is a recorded request and response pair
+type CassetteInteraction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+// CassetteRequest is a recorded request
+type CassetteRequest struct {
+	Method       string              `json:"method"`
+	URL          string              `json:"url"`
+	Headers      map[string][]string `json:"headers,omitempty"`
+	Body         string              `json:"body,omitempty"`
+	BodyEncoding string              `json:"body_encoding,omitempty"`
+}
+
+// CassetteResponse is a recorded response
+type CassetteResponse struct {
+	Status       int                 `json:"status"`
+	Headers      map[string][]string `json:"headers,omitempty"`
+	Body         string              `json:"body,omitempty"`
+	BodyEncoding string              `json:"body_encoding,omitempty"`
+}
+
+// NewCassette creates a new cassette stored in the given file.
+// The Authorization, Proxy-Authorization, Cookie and Set-Cookie headers are redacted by default.
+func NewCassette(path string) *Cassette {
+	return &Cassette{
+		path:          path,
+		redactHeaders: []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"},
+	}
+}
+
+// Mode sets the mode of the cassette. It takes precedence over the flag and the environment variable.
+func (c *Cassette) Mode(mode CassetteMode) *Cassette {
+	c.mode = mode
+	return c
+}
+
+// Strict fails the test if a request is not found in the cassette in replay mode.
+// By default, such a request is sent to the network.
+func (c *Cassette) Strict() *Cassette {
+	c.strict = true
+	return c
+}
+
+// RedactHeaders replaces the values of the given headers with REDACTED in the cassette
+func (c *Cassette) RedactHeaders(names ...string) *Cassette {
+	c.redactHeaders = append(c.redactHeaders, names...)
+	return c
+}
+
+// RedactPattern replaces the matches of the regular expression with REDACTED in the urls,
+// header values and bodies of the cassette. The request to replay is redacted the same way before it is matched.
+func (c *Cassette) RedactPattern(pattern string) *Cassette {
+	c.redactPatterns = append(c.redactPatterns, regexp.MustCompile(pattern))
+	return c
+}
+
+// Cassette is a builder method to record the outbound http interactions to the cassette, or replay them
+func (s *SpecTest) Cassette(cassette *Cassette) *SpecTest {
+	s.cassette = cassette
+	return s
+}
+
+// resolveMode returns the mode of the cassette: the explicit mode, the flag, the environment variable or auto.
+func (c *Cassette) resolveMode() CassetteMode {
+	mode := c.mode
+	if mode == "" {
+		mode = CassetteMode(*cassetteModeFlag)
+	}
+	if mode == "" {
+		mode = CassetteMode(os.Getenv(CassetteModeEnv))
+	}
+	switch mode {
+	case CassetteRecord, CassetteReplay:
+		return mode
+	}
+	if file.IsFile(c.path) {
+		return CassetteReplay
+	}
+	return CassetteRecord
+}
+
+// start prepares the cassette for a run of the test and returns the function that ends the run.
+// In record mode, the transport is replaced by a recorder, or the recorder is installed by the mock
+// transport if the mocks are isolated. In replay mode, the interactions are served by mocks, so the
+// SpecTest must create its mock transport after start.
+func (c *Cassette) start(s *SpecTest) func() {
+	c.mu.Lock()
+	c.interactions = nil
+	c.unmatched = nil
+	c.mu.Unlock()
+
+	c.recorder = nil
+	c.active = c.resolveMode()
+	if c.active == CassetteReplay {
+		mocks, err := c.load()
+		if err != nil {
+			s.t.Fatal(err)
+			return func() {}
+		}
+		s.mocks = append(s.mocks, mocks...)
+		return func() {
+			s.mocks = s.mocks[:len(s.mocks)-len(mocks)]
+		}
+	}
+
+	recorder := &cassetteRecorder{cassette: c, specTest: s}
+	if s.mocksIsolated {
+		c.recorder = recorder
+	} else {
+		recorder.hijack(s.httpClient)
+	}
+	if network := s.network.Client; s.network.isEnable() && network != s.httpClient {
+		switch {
+		case network.Transport != nil:
+			recorder.hijack(network)
+		case s.httpClient != nil && !s.mocksIsolated:
+			recorder.hijack(nil) // the network client uses http.DefaultTransport
+		}
+	}
+	return func() {
+		recorder.reset()
+		c.recorder = nil
+		if err := c.save(); err != nil {
+			s.t.Fatal(err)
+		}
+	}
+}
+
+// passthrough returns true if the requests that do not match a mock are sent to the network:
+// in replay mode, the requests that are not found in the cassette unless it is strict, and in
+// an isolated recording without mocks, all the requests, since they reach the recorder through
+// the mock transport.
+func (c *Cassette) passthrough() bool {
+	if c.active == CassetteRecord {
+		return c.recorder != nil && c.recorder.specTest.mocks.len() == 0
+	}
+	return !c.strict
+}
+
+// recordUnmatched records a request that was not found in the cassette.
+func (c *Cassette) recordUnmatched(req *http.Request) {
+	if c.active != CassetteReplay {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.unmatched = append(c.unmatched, fmt.Sprintf("%s %s", req.Method, c.redact(req.URL.String())))
+}
+
+// assert fails the test if a request was not found in the cassette in strict replay mode.
+func (c *Cassette) assert(s *SpecTest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.strict || len(c.unmatched) == 0 {
+		return
+	}
+	s.check(FailureCassette, "").Fail(s.t,
+		fmt.Sprintf("requests not found in cassette %s:\n%s", c.path, strings.Join(c.unmatched, "\n")),
+		failureMessageArgs{Name: s.name})
+}
+
+// load reads the cassette file and returns a mock for each interaction.
+func (c *Cassette) load() (Mocks, error) {
+	b, err := os.ReadFile(filepath.Clean(c.path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	var f cassetteFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", c.path, err)
+	}
+
+	mocks := make(Mocks, 0, len(f.Interactions))
+	for _, interaction := range f.Interactions {
+		mock, err := c.newMock(interaction)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse cassette %s: %w", c.path, err)
+		}
+		mocks = append(mocks, mock)
+	}
+	return mocks, nil
+}
+
+// newMock creates a mock that serves the recorded interaction.
+func (c *Cassette) newMock(interaction CassetteInteraction) (*Mock, error) {
+	recorded, err := url.Parse(interaction.Request.URL)
+	if err != nil {
+		return nil, err
+	}
+	requestBody, err := decodeCassetteBody(interaction.Request.Body, interaction.Request.BodyEncoding)
+	if err != nil {
+		return nil, err
+	}
+	responseBody, err := decodeCassetteBody(interaction.Response.Body, interaction.Response.BodyEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	mock := NewMock()
+	mock.parseURL(recorded.Scheme + "://" + recorded.Host)
+	mock.request.method = interaction.Request.Method
+	mock.request.AddMatcher(c.matcher(recorded, string(requestBody)))
+
+	res := mock.request.RespondWith().Status(interaction.Response.Status)
+	for name, values := range interaction.Response.Headers {
+		for _, v := range values {
+			res.Header(name, v)
+		}
+	}
+	res.Body(string(responseBody))
+	return res.End(), nil
+}
+
+// matcher returns a Matcher that compares the path, query and body of the received request
+// with the recorded request, after redacting the received request.
+func (c *Cassette) matcher(recorded *url.URL, recordedBody string) Matcher {
+	return func(req *http.Request, _ *MockRequest) error {
+		received, err := url.Parse(c.redact(req.URL.String()))
+		if err != nil {
+			return err
+		}
+		if received.Path != recorded.Path {
+			return fmt.Errorf("received path %s did not match recorded path %s", received.Path, recorded.Path)
+		}
+		if received.Query().Encode() != recorded.Query().Encode() {
+			return fmt.Errorf("received query %s did not match recorded query %s", received.RawQuery, recorded.RawQuery)
+		}
+
+		body := readCassetteBody(&req.Body)
+		if len(body) == 0 && recordedBody == "" {
+			return nil
+		}
+		redactedReq := &http.Request{Body: io.NopCloser(strings.NewReader(c.redact(string(body))))}
+		if recordedBody == "" {
+			return errors.New("expected no body but received one")
+		}
+		return bodyMatcher(redactedReq, &MockRequest{body: recordedBody})
+	}
+}
+
+// record records the interaction.
+func (c *Cassette) record(req *http.Request, requestBody []byte, res *http.Response, responseBody []byte) {
+	reqBody, reqEncoding := encodeCassetteBody(requestBody)
+	resBody, resEncoding := encodeCassetteBody(responseBody)
+	interaction := CassetteInteraction{
+		Request: CassetteRequest{
+			Method:       req.Method,
+			URL:          c.redact(req.URL.String()),
+			Headers:      c.redactHeaderValues(req.Header),
+			Body:         c.redactBody(reqBody, reqEncoding),
+			BodyEncoding: reqEncoding,
+		},
+		Response: CassetteResponse{
+			Status:       res.StatusCode,
+			Headers:      c.redactHeaderValues(res.Header),
+			Body:         c.redactBody(resBody, resEncoding),
+			BodyEncoding: resEncoding,
+		},
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, interaction)
+}
+
+// save writes the recorded interactions to the cassette file.
+func (c *Cassette) save() error {
+	c.mu.Lock()
+	interactions := append([]CassetteInteraction{}, c.interactions...)
+	c.mu.Unlock()
+
+	b, err := json.MarshalIndent(cassetteFile{Version: 1, Interactions: interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(c.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return fmt.Errorf("failed to write cassette: %w", err)
+		}
+	}
+	if err := os.WriteFile(c.path, append(b, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// redact replaces the redact patterns in the value.
+func (c *Cassette) redact(value string) string {
+	for _, pattern := range c.redactPatterns {
+		value = pattern.ReplaceAllString(value, redacted)
+	}
+	return value
+}
+
+// redactBody redacts the body unless it is base64 encoded.
+func (c *Cassette) redactBody(body, encoding string) string {
+	if encoding != "" {
+		return body
+	}
+	return c.redact(body)
+}
+
+// redactHeaderValues returns a copy of the header with redacted values.
+func (c *Cassette) redactHeaderValues(header http.Header) map[string][]string {
+	if len(header) == 0 {
+		return nil
+	}
+	redactedNames := map[string]bool{}
+	for _, name := range c.redactHeaders {
+		redactedNames[textproto.CanonicalMIMEHeaderKey(name)] = true
+	}
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	copied := make(map[string][]string, len(header))
+	for _, name := range names {
+		for _, v := range header[name] {
+			if redactedNames[textproto.CanonicalMIMEHeaderKey(name)] {
+				v = redacted
+			}
+			copied[name] = append(copied[name], c.redact(v))
+		}
+	}
+	return copied
+}
+
+// encodeCassetteBody returns the body as a string. Bodies that are not valid UTF-8 are base64 encoded.
+func encodeCassetteBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+// decodeCassetteBody decodes the body of the cassette.
+func decodeCassetteBody(body, encoding string) ([]byte, error) {
+	switch encoding {
+	case "":
+		return []byte(body), nil
+	case "base64":
+		return base64.StdEncoding.DecodeString(body)
+	}
+	return nil, fmt.Errorf("unknown body encoding %s", encoding)
+}
+
+// readCassetteBody reads and closes the body, and restores it so that it can be read again.
+func readCassetteBody(body *io.ReadCloser) []byte {
+	if *body == nil || *body == http.NoBody {
+		return nil
+	}
+	b, err := io.ReadAll(*body)
+	(*body).Close() //nolint
+	*body = io.NopCloser(bytes.NewReader(b))
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// cassetteRecorder is a http.RoundTripper that sends the requests to the network and records them.
+type cassetteRecorder struct {
+	// cassette is the cassette to record to
+	cassette *Cassette
+	// specTest is the spectest instance
+	specTest *SpecTest
+	// hijacked is the list of clients whose transport was replaced. A nil client is http.DefaultTransport.
+	hijacked []*http.Client
+	// natives is the list of replaced transports in the order of hijacked
+	natives []http.RoundTripper
+	// installed is the list of round trippers that replaced the transports in the order of hijacked
+	installed []http.RoundTripper
+}
+
+// hijack replaces the transport of the client, or http.DefaultTransport if client is nil, with the recorder.
+func (r *cassetteRecorder) hijack(client *http.Client) {
+	mockRoutersMu.Lock()
+	defer mockRoutersMu.Unlock()
+
+	var native http.RoundTripper
+	var installed *cassetteRoundTripper
+	if client == nil {
+		native = http.DefaultTransport
+		installed = &cassetteRoundTripper{recorder: r, native: native}
+		http.DefaultTransport = installed
+	} else {
+		native = client.Transport
+		if native == nil {
+			native = http.DefaultTransport
+		}
+		installed = &cassetteRoundTripper{recorder: r, native: native}
+		client.Transport = installed
+	}
+	r.hijacked = append(r.hijacked, client)
+	r.natives = append(r.natives, native)
+	r.installed = append(r.installed, installed)
+}
+
+// reset restores the replaced transports, unless a transport was replaced again in the meantime.
+func (r *cassetteRecorder) reset() {
+	mockRoutersMu.Lock()
+	defer mockRoutersMu.Unlock()
+
+	for i := len(r.hijacked) - 1; i >= 0; i-- {
+		if r.hijacked[i] == nil {
+			if http.DefaultTransport == r.installed[i] {
+				http.DefaultTransport = r.natives[i]
+			}
+			continue
+		}
+		if r.hijacked[i].Transport == r.installed[i] {
+			r.hijacked[i].Transport = r.natives[i]
+		}
+	}
+}
+
+// cassetteRoundTripper records the interactions sent to the native transport.
+type cassetteRoundTripper struct {
+	// recorder is the recorder that installed the round tripper
+	recorder *cassetteRecorder
+	// native is the replaced transport
+	native http.RoundTripper
+	// observed is true if the mock transport already passes the interactions to the observers
+	observed bool
+}
+
+// RoundTrip sends the request to the native transport and records the interaction
+func (r *cassetteRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestBody := readCassetteBody(&req.Body)
+	res, err := r.native.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	responseBody := readCassetteBody(&res.Body)
+	r.recorder.cassette.record(req, requestBody, res, responseBody)
+
+	s := r.recorder.specTest
+	if r.observed {
+		return res, nil
+	}
+	for _, observe := range s.mocksObservers {
+		observe(res, req, s)
+	}
+	return res, nil
+}
@@ -0,0 +1,230 @@
+package spectest_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nao1215/spectest"
+	"github.com/nao1215/spectest/mocks"
+)
+
+// cassetteHandler returns a handler that calls the downstream service with a secret token
+func cassetteHandler(t *testing.T, downstreamURL string) http.HandlerFunc {
+	t.Helper()
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := http.NewRequest(http.MethodPost, downstreamURL+"/users?token=s3cr3t", strings.NewReader(`{"name": "Bob"}`))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		req.Header.Set("Authorization", "Bearer s3cr3t")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		w.WriteHeader(res.StatusCode)
+		_, _ = w.Write(body)
+	}
+}
+
+func newDownstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, `{"id": %d, "name": "Bob"}`, calls)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCassetteRecordsAndReplays(t *testing.T) {
+	downstream := newDownstream(t)
+	path := filepath.Join(t.TempDir(), "cassettes", "users.json")
+
+	spectest.New().
+		Cassette(spectest.NewCassette(path).Mode(spectest.CassetteRecord).RedactPattern(`s3cr3t`)).
+		HandlerFunc(cassetteHandler(t, downstream.URL)).
+		Get("/").
+		Expect(t).
+		Status(http.StatusCreated).
+		Body(`{"id": 1, "name": "Bob"}`).
+		End()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cassette := string(b)
+	spectest.DefaultVerifier{}.True(t, !strings.Contains(cassette, "s3cr3t"))
+	spectest.DefaultVerifier{}.True(t, strings.Contains(cassette, `"Bearer REDACTED"`) || strings.Contains(cassette, `"REDACTED"`))
+	spectest.DefaultVerifier{}.True(t, strings.Contains(cassette, "/users?token=REDACTED"))
+
+	downstream.Close()
+
+	spectest.New().
+		Cassette(spectest.NewCassette(path).Strict().RedactPattern(`s3cr3t`)).
+		HandlerFunc(cassetteHandler(t, downstream.URL)).
+		Get("/").
+		Expect(t).
+		Status(http.StatusCreated).
+		Body(`{"id": 1, "name": "Bob"}`).
+		End()
+}
+
+func TestCassetteStrictModeFailsOnUnknownRequest(t *testing.T) {
+	downstream := newDownstream(t)
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := os.WriteFile(path, []byte(`{"version": 1, "interactions": []}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var failures []string
+	verifier := mocks.NewVerifier()
+	verifier.FailFn = func(t spectest.TestingT, msg string, msgAndArgs ...interface{}) bool {
+		failures = append(failures, msg)
+		return false
+	}
+
+	spectest.New().
+		Verifier(verifier).
+		Cassette(spectest.NewCassette(path).Mode(spectest.CassetteReplay).Strict()).
+		HandlerFunc(cassetteHandler(t, downstream.URL)).
+		Get("/").
+		Expect(t).
+		Status(http.StatusBadGateway).
+		End()
+
+	spectest.DefaultVerifier{}.Equal(t, []string{
+		fmt.Sprintf("requests not found in cassette %s:\nPOST %s/users?token=s3cr3t", path, downstream.URL),
+	}, failures)
+}
+
+func TestCassetteReplaySendsUnknownRequestToNetwork(t *testing.T) {
+	downstream := newDownstream(t)
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := os.WriteFile(path, []byte(`{"version": 1, "interactions": []}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	spectest.New().
+		Cassette(spectest.NewCassette(path).Mode(spectest.CassetteReplay)).
+		HandlerFunc(cassetteHandler(t, downstream.URL)).
+		Get("/").
+		Expect(t).
+		Status(http.StatusCreated).
+		End()
+}
+
+func TestCassetteModeFromEnvironmentVariable(t *testing.T) {
+	downstream := newDownstream(t)
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := os.WriteFile(path, []byte(`{"version": 1, "interactions": []}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(spectest.CassetteModeEnv, string(spectest.CassetteRecord))
+
+	spectest.New().
+		Cassette(spectest.NewCassette(path).Strict()).
+		HandlerFunc(cassetteHandler(t, downstream.URL)).
+		Get("/").
+		Expect(t).
+		Status(http.StatusCreated).
+		End()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spectest.DefaultVerifier{}.True(t, strings.Contains(string(b), `"status": 201`))
+	spectest.DefaultVerifier{}.True(t, strings.Contains(string(b), `"Authorization": [`))
+}
+
+func TestCassetteRedactsSetCookieByDefault(t *testing.T) {
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3cr3t"})
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(downstream.Close)
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	spectest.New().
+		Cassette(spectest.NewCassette(path).Mode(spectest.CassetteRecord)).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res, err := http.Get(downstream.URL + "/login")
+			if err != nil {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			res.Body.Close() //nolint
+			w.WriteHeader(res.StatusCode)
+		}).
+		Get("/").
+		Expect(t).
+		Status(http.StatusNoContent).
+		End()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cassette := string(b)
+	spectest.DefaultVerifier{}.True(t, !strings.Contains(cassette, "s3cr3t"))
+	spectest.DefaultVerifier{}.True(t, strings.Contains(cassette, `"Set-Cookie": [`))
+}
+
+func TestCassetteRecordsThroughTheMockRouterWhenMocksAreIsolated(t *testing.T) {
+	downstream := newDownstream(t)
+	path := filepath.Join(t.TempDir(), "isolated.json")
+	native := http.DefaultTransport
+
+	spectest.New().
+		IsolateMocks().
+		Cassette(spectest.NewCassette(path).Mode(spectest.CassetteRecord)).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the request of another test does not carry the context of the inbound request
+			other, err := http.Get(downstream.URL + "/other")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			other.Body.Close() //nolint
+
+			req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, downstream.URL+"/users", nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			defer res.Body.Close()
+			w.WriteHeader(res.StatusCode)
+		}).
+		Get("/").
+		Expect(t).
+		Status(http.StatusCreated).
+		End()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cassette := string(b)
+	spectest.DefaultVerifier{}.True(t, strings.Contains(cassette, "/users"))
+	spectest.DefaultVerifier{}.True(t, !strings.Contains(cassette, "/other"))
+	spectest.DefaultVerifier{}.True(t, http.DefaultTransport == native)
+}
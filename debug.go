@@ -81,6 +81,10 @@ func (d *debug) mock(res *http.Response, req *http.Request) {
 	} else {
 		debugLog(responseDebugPrefix(), "response from mock", "")
 	}
+
+	if fault := faultOf(req); fault != "" {
+		debugLog(responseDebugPrefix(), "fault injected into mock response", fault)
+	}
 }
 
 // debugLog is used to print debug information
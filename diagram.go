@@ -448,6 +448,10 @@ func (m *MarkdownFormatter) generateMarkdown(w io.Writer, recorder *Recorder, st
 	}
 	markdown = markdown.CodeBlocks(md.SyntaxHighlightMermaid, m.mermaidSequenceDiagram(recorder)).LF()
 
+	if recorder.Meta != nil && len(recorder.Meta.Failures) > 0 {
+		markdown = markdown.H2("Failures").CodeBlocks(md.SyntaxHighlightText, formatFailures(recorder.Meta.Failures)).LF()
+	}
+
 	markdown = markdown.H2("Event log")
 	for i, log := range logs {
 		markdown = markdown.H4(fmt.Sprintf("Event %d", i+1)).LF()
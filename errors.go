@@ -25,12 +25,25 @@ func errorOrNil(statement bool, errorMessage func() string) error {
 type unmatchedMockError struct {
 	// errors is a map of mock number to errors
 	errors map[int][]error
+	// ranks is a map of mock number to the number of matchers of the mock that passed
+	ranks map[int]mockRank
+	// closest describes the mock that is the closest to the request, and how the request differs from it
+	closest string
+}
+
+// mockRank is the number of matchers of a mock that passed for a request
+type mockRank struct {
+	// passed is the number of matchers that passed
+	passed int
+	// total is the number of matchers of the mock
+	total int
 }
 
 // newUnmatchedMockError creates a new unmatchedMockError
 func newUnmatchedMockError() *unmatchedMockError {
 	return &unmatchedMockError{
 		errors: map[int][]error{},
+		ranks:  map[int]mockRank{},
 	}
 }
 
@@ -40,12 +53,35 @@ func (u *unmatchedMockError) append(mockNumber int, errors ...error) *unmatchedM
 	return u
 }
 
+// rank records how many of the matchers of the mock passed, so that the closest mocks are listed first
+func (u *unmatchedMockError) rank(mockNumber, passed, total int) *unmatchedMockError {
+	u.ranks[mockNumber] = mockRank{passed: passed, total: total}
+	return u
+}
+
+// closestMock returns the number of the mock that is the closest to the request, or 0 if there are no mocks.
+func (u *unmatchedMockError) closestMock() int {
+	keys := u.orderedMockKeys()
+	if len(keys) == 0 {
+		return 0
+	}
+	return keys[0]
+}
+
 // Error implementation of in-built error human readable string function
 func (u *unmatchedMockError) Error() string {
 	var strBuilder strings.Builder
 	strBuilder.WriteString("received request did not match any mocks\n\n")
+	if u.closest != "" {
+		strBuilder.WriteString(u.closest)
+		strBuilder.WriteString("\n")
+	}
 	for _, mockNumber := range u.orderedMockKeys() {
-		strBuilder.WriteString(fmt.Sprintf("Mock %d mismatches:\n", mockNumber))
+		if rank, ok := u.ranks[mockNumber]; ok {
+			strBuilder.WriteString(fmt.Sprintf("Mock %d mismatches (%d of %d matchers passed):\n", mockNumber, rank.passed, rank.total))
+		} else {
+			strBuilder.WriteString(fmt.Sprintf("Mock %d mismatches:\n", mockNumber))
+		}
 		for _, err := range u.errors[mockNumber] {
 			strBuilder.WriteString("• ")
 			strBuilder.WriteString(err.Error())
@@ -56,12 +92,19 @@ func (u *unmatchedMockError) Error() string {
 	return strBuilder.String()
 }
 
-// orderedMockKeys returns the keys of the errors map in order.
+// orderedMockKeys returns the keys of the errors map, the mocks with the most passed matchers first.
+// The mocks with the same number of passed matchers are in order of their number.
 func (u *unmatchedMockError) orderedMockKeys() []int {
 	mockKeys := make([]int, 0, len(u.errors))
 	for mockKey := range u.errors {
 		mockKeys = append(mockKeys, mockKey)
 	}
-	sort.Ints(mockKeys)
+	sort.Slice(mockKeys, func(i, j int) bool {
+		pi, pj := u.ranks[mockKeys[i]].passed, u.ranks[mockKeys[j]].passed
+		if pi != pj {
+			return pi > pj
+		}
+		return mockKeys[i] < mockKeys[j]
+	})
 	return mockKeys
 }
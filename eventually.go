@@ -0,0 +1,132 @@
+package spectest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultEventuallyInterval is the interval between attempts when no positive interval is given.
+const defaultEventuallyInterval = 100 * time.Millisecond
+
+// eventually re-runs the request until all the response assertions pass or the timeout expires.
+type eventually struct {
+	// timeout is the maximum duration to wait for the assertions to pass
+	timeout time.Duration
+	// interval is the time to wait between attempts
+	interval time.Duration
+	// attempts is the number of attempts made so far
+	attempts int
+}
+
+// newEventually creates a new eventually setting
+func newEventually(timeout, interval time.Duration) *eventually {
+	if interval <= 0 {
+		interval = defaultEventuallyInterval
+	}
+	return &eventually{
+		timeout:  timeout,
+		interval: interval,
+	}
+}
+
+// ExpectEventually marks the request spec as complete like Expect, but the request is re-run
+// until all the response assertions (status, body, headers, cookies and Assert funcs) pass
+// or the timeout expires. The interval is the time to wait between attempts.
+// On timeout, the test fails with the last failure and the number of attempts.
+// Every attempt is recorded in the report.
+// The executions and the calls of the mocks are reset before every attempt, so a mock with
+// MockResponse.Times(n) is expected to be called n times by each attempt.
+func (r *Request) ExpectEventually(t TestingT, timeout, interval time.Duration) *Response {
+	r.specTest.t = t
+	r.specTest.response.eventually = newEventually(timeout, interval)
+	return r.specTest.response
+}
+
+// run runs the attempt until it passes or the timeout expires.
+func (e *eventually) run(s *SpecTest, attempt func() *http.Response) *http.Response {
+	t := s.t
+	defer func() { s.t = t }()
+
+	deadline := time.Now().Add(e.timeout)
+	var started time.Time
+	var res *http.Response
+	var failures []string
+	for {
+		e.attempts++
+		s.mocks.reset()
+		attemptT := &attemptT{}
+		s.t = attemptT
+		res = runAttempt(attemptT, attempt)
+		if started.IsZero() {
+			started = s.interval.Started
+		}
+		if len(attemptT.failures) == 0 {
+			s.interval.Started = started
+			return res
+		}
+		failures = attemptT.failures
+		if time.Now().Add(e.interval).After(deadline) {
+			break
+		}
+		time.Sleep(e.interval)
+	}
+	s.interval.Started = started
+
+	s.t = t
+	if s.verifier == nil {
+		s.verifier = DefaultVerifier{}
+	}
+	s.verifier.Fail(t,
+		fmt.Sprintf("expectation not met after %d attempts within %s. Last failure:\n%s",
+			e.attempts, e.timeout, strings.Join(failures, "\n")),
+		failureMessageArgs{Name: s.name})
+	return res
+}
+
+// runAttempt runs the attempt. If the attempt calls Fatal, the attempt is stopped
+// and the failure is recorded instead of stopping the test.
+func runAttempt(t *attemptT, attempt func() *http.Response) (res *http.Response) {
+	defer func() {
+		if err := recover(); err != nil {
+			if !t.fatal {
+				panic(err)
+			}
+			res = nil
+		}
+	}()
+	return attempt()
+}
+
+// attemptFatal is the value used to stop an attempt on Fatal.
+type attemptFatal struct{}
+
+// attemptT is a TestingT that records the failures of an attempt instead of reporting them.
+type attemptT struct {
+	// failures is the list of failure messages
+	failures []string
+	// fatal is true if the attempt was stopped by Fatal
+	fatal bool
+}
+
+var _ TestingT = &attemptT{}
+
+// Errorf records the failure
+func (a *attemptT) Errorf(format string, args ...interface{}) {
+	a.failures = append(a.failures, fmt.Sprintf(format, args...))
+}
+
+// Fatal records the failure and stops the attempt
+func (a *attemptT) Fatal(args ...interface{}) {
+	a.failures = append(a.failures, fmt.Sprint(args...))
+	a.fatal = true
+	panic(attemptFatal{})
+}
+
+// Fatalf records the failure and stops the attempt
+func (a *attemptT) Fatalf(format string, args ...interface{}) {
+	a.failures = append(a.failures, fmt.Sprintf(format, args...))
+	a.fatal = true
+	panic(attemptFatal{})
+}
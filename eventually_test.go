@@ -0,0 +1,134 @@
+package spectest_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nao1215/spectest"
+	"github.com/nao1215/spectest/mocks"
+)
+
+// eventuallyHandler returns a handler that responds "pending" until it is called readyAfter times.
+func eventuallyHandler(readyAfter int32) (http.HandlerFunc, *int32) {
+	var calls int32
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(&calls, 1) < readyAfter {
+			_, _ = w.Write([]byte(`{"status": "pending"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status": "done"}`))
+	}, &calls
+}
+
+func TestExpectEventuallyRetriesUntilAssertionsPass(t *testing.T) {
+	handler, calls := eventuallyHandler(3)
+
+	spectest.New().
+		HandlerFunc(handler).
+		Get("/jobs/1").
+		ExpectEventually(t, time.Second, time.Millisecond).
+		Status(http.StatusOK).
+		Body(`{"status": "done"}`).
+		End()
+
+	spectest.DefaultVerifier{}.Equal(t, int32(3), atomic.LoadInt32(calls))
+}
+
+func TestExpectEventuallyRetriesUntilAssertFuncPasses(t *testing.T) {
+	handler, calls := eventuallyHandler(2)
+
+	spectest.New().
+		HandlerFunc(handler).
+		Get("/jobs/1").
+		ExpectEventually(t, time.Second, time.Millisecond).
+		Assert(func(res *http.Response, _ *http.Request) error {
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				return err
+			}
+			if !strings.Contains(string(body), "done") {
+				return fmt.Errorf("job is not done: %s", body)
+			}
+			return nil
+		}).
+		End()
+
+	spectest.DefaultVerifier{}.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
+
+func TestExpectEventuallyFailsWithLastFailureOnTimeout(t *testing.T) {
+	handler, _ := eventuallyHandler(1000)
+	var failureMessage string
+	verifier := mocks.NewVerifier()
+	verifier.FailFn = func(t spectest.TestingT, msg string, msgAndArgs ...interface{}) bool {
+		failureMessage = msg
+		return false
+	}
+	verifier.JSONEqFn = spectest.DefaultVerifier{}.JSONEq
+
+	spectest.New().
+		Verifier(verifier).
+		HandlerFunc(handler).
+		Get("/jobs/1").
+		ExpectEventually(t, 50*time.Millisecond, 10*time.Millisecond).
+		Body(`{"status": "done"}`).
+		End()
+
+	spectest.DefaultVerifier{}.Equal(t, true, strings.HasPrefix(failureMessage, "expectation not met after "))
+	spectest.DefaultVerifier{}.Equal(t, true, strings.Contains(failureMessage, "attempts within 50ms"))
+}
+
+func TestExpectEventuallyRecordsEveryAttempt(t *testing.T) {
+	handler, _ := eventuallyHandler(3)
+	reporter := &RecorderCaptor{}
+
+	spectest.New("eventually").
+		Report(reporter).
+		HandlerFunc(handler).
+		Get("/jobs/1").
+		ExpectEventually(t, time.Second, time.Millisecond).
+		Body(`{"status": "done"}`).
+		End()
+
+	r := reporter.capturedRecorder
+	spectest.DefaultVerifier{}.Equal(t, 6, len(r.Events))
+	spectest.DefaultVerifier{}.Equal(t, http.StatusOK, r.Meta.StatusCode)
+	spectest.DefaultVerifier{}.Equal(t, "GET /jobs/1", r.Title)
+}
+
+func TestExpectEventuallyCountsMockCallsPerAttempt(t *testing.T) {
+	handler, calls := eventuallyHandler(3)
+	quotaMock := spectest.NewMock().
+		Get("http://localhost:8080/quota").
+		RespondWith().
+		Status(http.StatusOK).
+		Times(2).
+		End()
+
+	spectest.New().
+		Mocks(quotaMock).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for i := 0; i < 2; i++ {
+				res, err := http.Get("http://localhost:8080/quota")
+				if err != nil {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				res.Body.Close() //nolint
+			}
+			handler(w, r)
+		}).
+		Get("/jobs/1").
+		ExpectEventually(t, time.Second, time.Millisecond).
+		Status(http.StatusOK).
+		Body(`{"status": "done"}`).
+		End()
+
+	spectest.DefaultVerifier{}.Equal(t, int32(3), atomic.LoadInt32(calls))
+}
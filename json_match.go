@@ -0,0 +1,470 @@
+package spectest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The placeholders that can be used as values in the expected JSON bodies of the responses and the mock requests
+const (
+	// JSONAny matches any value, e.g. {"id": "<<any>>"}
+	JSONAny = "<<any>>"
+	// JSONUUID matches a string that is a UUID
+	JSONUUID = "<<uuid>>"
+	// JSONRFC3339 matches a string that is a RFC 3339 timestamp, e.g. 2024-01-02T15:04:05Z
+	JSONRFC3339 = "<<rfc3339>>"
+	// JSONNumber matches any number
+	JSONNumber = "<<number>>"
+)
+
+// jsonPathIdentifier matches a field name that can be written with a dot in a JSONPath
+var jsonPathIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$-]*$`)
+
+// uuidPattern matches a UUID
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// jsonMatch configures how an expected JSON body is compared to an actual JSON body.
+// The zero value requires the bodies to be equal, except for the placeholders.
+type jsonMatch struct {
+	// subset allows the actual body to have fields and array elements that are not in the expected body
+	subset bool
+	// unordered compares the arrays without regard to the order of their elements
+	unordered bool
+	// ignored is the list of JSONPaths of the values that are not compared, e.g. $.id or $.items[*].createdAt
+	ignored []string
+}
+
+// JSONSubset matches a JSON body of the request that contains the expected JSON body, i.e. the request may have
+// other fields, and other array elements after the expected elements.
+func (r *MockRequest) JSONSubset() *MockRequest {
+	r.jsonMatch.subset = true
+	return r
+}
+
+// IgnoreJSONPaths does not compare the values of the JSON body of the request at the given JSONPaths,
+// e.g. IgnoreJSONPaths("$.id", "$.items[*].createdAt", "$..updatedAt").
+func (r *MockRequest) IgnoreJSONPaths(paths ...string) *MockRequest {
+	r.jsonMatch.ignored = append(r.jsonMatch.ignored, paths...)
+	return r
+}
+
+// UnorderedJSONArrays compares the arrays of the JSON body of the request without regard to the order of their elements.
+func (r *MockRequest) UnorderedJSONArrays() *MockRequest {
+	r.jsonMatch.unordered = true
+	return r
+}
+
+// JSONSubset asserts that the JSON body of the response contains the expected JSON body, i.e. the response may have
+// other fields, and other array elements after the expected elements.
+func (r *Response) JSONSubset() *Response {
+	r.jsonMatch.subset = true
+	return r
+}
+
+// IgnoreJSONPaths does not assert the values of the JSON body of the response at the given JSONPaths,
+// e.g. IgnoreJSONPaths("$.id", "$.items[*].createdAt", "$..updatedAt").
+func (r *Response) IgnoreJSONPaths(paths ...string) *Response {
+	r.jsonMatch.ignored = append(r.jsonMatch.ignored, paths...)
+	return r
+}
+
+// UnorderedJSONArrays compares the arrays of the JSON body of the response without regard to the order of their elements.
+func (r *Response) UnorderedJSONArrays() *Response {
+	r.jsonMatch.unordered = true
+	return r
+}
+
+// isEnabled returns true if the bodies are compared with the json matcher instead of a plain JSON equality,
+// i.e. if an option is set or if the expected body contains a placeholder.
+func (m jsonMatch) isEnabled(expected string) bool {
+	if m.subset || m.unordered || len(m.ignored) > 0 {
+		return true
+	}
+	// encoding/json escapes < and > by default, e.g. in the bodies of MockRequest.JSON
+	escaper := strings.NewReplacer("<", `\u003c`, ">", `\u003e`)
+	for _, placeholder := range []string{JSONAny, JSONUUID, JSONRFC3339, JSONNumber} {
+		if strings.Contains(expected, placeholder) || strings.Contains(expected, escaper.Replace(placeholder)) {
+			return true
+		}
+	}
+	return false
+}
+
+// match compares the actual JSON body to the expected JSON body.
+// It returns the list of the differences.
+func (m jsonMatch) match(expected, actual []byte) ([]jsonDiff, error) {
+	ignored := make([]jsonPathPattern, 0, len(m.ignored))
+	for _, path := range m.ignored {
+		pattern, err := parseJSONPathPattern(path)
+		if err != nil {
+			return nil, err
+		}
+		ignored = append(ignored, pattern)
+	}
+
+	var expectedJSON, actualJSON interface{}
+	if err := json.Unmarshal(expected, &expectedJSON); err != nil {
+		return nil, fmt.Errorf("expected body is not JSON: %w", err)
+	}
+	if err := json.Unmarshal(actual, &actualJSON); err != nil {
+		return []jsonDiff{{actual: string(actual), message: fmt.Sprintf("expected JSON, received %q", string(actual))}}, nil
+	}
+	c := &jsonComparator{jsonMatch: m, ignored: ignored}
+	return c.compare(nil, expectedJSON, actualJSON), nil
+}
+
+// jsonComparator compares JSON values decoded by encoding/json
+type jsonComparator struct {
+	jsonMatch
+	// ignored is the list of the parsed ignored JSONPaths
+	ignored []jsonPathPattern
+}
+
+// compare returns the differences between the values at the given path.
+func (c *jsonComparator) compare(path jsonPath, expected, actual interface{}) []jsonDiff {
+	if c.isIgnored(path) {
+		return nil
+	}
+	if placeholder, ok := expected.(string); ok {
+		if matched, isPlaceholder := matchPlaceholder(placeholder, actual); isPlaceholder {
+			if matched {
+				return nil
+			}
+			return []jsonDiff{{path, expected, actual, fmt.Sprintf("expected %s, received %s", placeholder, formatJSON(actual))}}
+		}
+	}
+
+	switch expected := expected.(type) {
+	case map[string]interface{}:
+		object, ok := actual.(map[string]interface{})
+		if !ok {
+			return []jsonDiff{{path, expected, actual, fmt.Sprintf("expected an object, received %s", formatJSON(actual))}}
+		}
+		return c.compareObjects(path, expected, object)
+	case []interface{}:
+		array, ok := actual.([]interface{})
+		if !ok {
+			return []jsonDiff{{path, expected, actual, fmt.Sprintf("expected an array, received %s", formatJSON(actual))}}
+		}
+		if c.unordered {
+			return c.compareUnorderedArrays(path, expected, array)
+		}
+		return c.compareArrays(path, expected, array)
+	default:
+		if reflect.DeepEqual(expected, actual) {
+			return nil
+		}
+		return []jsonDiff{{path, expected, actual, fmt.Sprintf("expected %s, received %s", formatJSON(expected), formatJSON(actual))}}
+	}
+}
+
+// compareObjects compares the fields of the objects. The actual object may have other fields in subset mode.
+func (c *jsonComparator) compareObjects(path jsonPath, expected, actual map[string]interface{}) []jsonDiff {
+	var diffs []jsonDiff
+	for _, key := range sortedKeys(expected) {
+		fieldPath := path.key(key)
+		value, ok := actual[key]
+		if !ok {
+			if !c.isIgnored(fieldPath) {
+				diffs = append(diffs, jsonDiff{fieldPath, expected[key], nil, fmt.Sprintf("expected %s, but the field is missing", formatJSON(expected[key]))})
+			}
+			continue
+		}
+		diffs = append(diffs, c.compare(fieldPath, expected[key], value)...)
+	}
+	if c.subset {
+		return diffs
+	}
+	for _, key := range sortedKeys(actual) {
+		if _, ok := expected[key]; !ok && !c.isIgnored(path.key(key)) {
+			diffs = append(diffs, jsonDiff{path.key(key), nil, actual[key], fmt.Sprintf("unexpected field with value %s", formatJSON(actual[key]))})
+		}
+	}
+	return diffs
+}
+
+// compareArrays compares the elements of the arrays in order. In subset mode, the expected elements
+// must match actual elements in the same order, and the actual array may have other elements.
+func (c *jsonComparator) compareArrays(path jsonPath, expected, actual []interface{}) []jsonDiff {
+	if !c.subset {
+		if len(expected) != len(actual) {
+			return []jsonDiff{{path, expected, actual, fmt.Sprintf("expected %d elements, received %d", len(expected), len(actual))}}
+		}
+		var diffs []jsonDiff
+		for i := range expected {
+			diffs = append(diffs, c.compare(path.index(i), expected[i], actual[i])...)
+		}
+		return diffs
+	}
+
+	next := 0
+	for i, element := range expected {
+		found := false
+		for ; next < len(actual) && !found; next++ {
+			found = len(c.compare(path.index(next), element, actual[next])) == 0
+		}
+		if !found {
+			return []jsonDiff{{path, element, actual, fmt.Sprintf("no element matches expected element %d %s in order", i, formatJSON(element))}}
+		}
+	}
+	return nil
+}
+
+// compareUnorderedArrays compares the elements of the arrays without regard to their order.
+// Each expected element must match a different actual element.
+func (c *jsonComparator) compareUnorderedArrays(path jsonPath, expected, actual []interface{}) []jsonDiff {
+	if !c.subset && len(expected) != len(actual) {
+		return []jsonDiff{{path, expected, actual, fmt.Sprintf("expected %d elements, received %d", len(expected), len(actual))}}
+	}
+
+	matches := make([][]int, len(expected))
+	for i := range expected {
+		for j := range actual {
+			if len(c.compare(path.index(j), expected[i], actual[j])) == 0 {
+				matches[i] = append(matches[i], j)
+			}
+		}
+	}
+
+	// match the expected elements to the actual elements with augmenting paths
+	owner := make([]int, len(actual))
+	for j := range owner {
+		owner[j] = -1
+	}
+	var assign func(i int, seen []bool) bool
+	assign = func(i int, seen []bool) bool {
+		for _, j := range matches[i] {
+			if seen[j] {
+				continue
+			}
+			seen[j] = true
+			if owner[j] == -1 || assign(owner[j], seen) {
+				owner[j] = i
+				return true
+			}
+		}
+		return false
+	}
+	var diffs []jsonDiff
+	for i := range expected {
+		if !assign(i, make([]bool, len(actual))) {
+			diffs = append(diffs, jsonDiff{path, expected[i], actual, fmt.Sprintf("no element matches expected element %d %s", i, formatJSON(expected[i]))})
+		}
+	}
+	return diffs
+}
+
+// isIgnored returns true if the path matches one of the ignored JSONPaths.
+func (c *jsonComparator) isIgnored(path jsonPath) bool {
+	for _, pattern := range c.ignored {
+		if pattern.matches(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonDiff is a difference between the expected and the actual JSON bodies
+type jsonDiff struct {
+	// path is the location of the difference
+	path jsonPath
+	// expected is the expected value at the path, nil if the actual value is unexpected
+	expected interface{}
+	// actual is the actual value at the path, nil if the expected value is missing
+	actual interface{}
+	// message describes the difference
+	message string
+}
+
+// String formats the difference, e.g. `$.name: expected "Alice", received "Bob"`
+func (d jsonDiff) String() string {
+	return d.path.String() + ": " + d.message
+}
+
+// joinJSONDiffs formats the differences, one per line
+func joinJSONDiffs(diffs []jsonDiff) string {
+	lines := make([]string, 0, len(diffs))
+	for _, d := range diffs {
+		lines = append(lines, d.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// matchPlaceholder matches the actual value to the placeholder. isPlaceholder is false if the value is not a placeholder.
+func matchPlaceholder(placeholder string, actual interface{}) (matched bool, isPlaceholder bool) {
+	switch placeholder {
+	case JSONAny:
+		return true, true
+	case JSONNumber:
+		_, ok := actual.(float64)
+		return ok, true
+	case JSONUUID:
+		s, ok := actual.(string)
+		return ok && uuidPattern.MatchString(s), true
+	case JSONRFC3339:
+		s, ok := actual.(string)
+		if !ok {
+			return false, true
+		}
+		_, err := time.Parse(time.RFC3339Nano, s)
+		return err == nil, true
+	default:
+		return false, false
+	}
+}
+
+// formatJSON formats the value as JSON for the error messages
+func formatJSON(v interface{}) string {
+	var b strings.Builder
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// sortedKeys returns the keys of the object in order, so that the differences are reported in a stable order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// jsonPath is the location of a value in a JSON document. A step is a field name or an array index.
+type jsonPath []jsonPathStep
+
+// jsonPathStep is a step of a jsonPath
+type jsonPathStep struct {
+	// key is the name of the field. It is empty for an array index.
+	key string
+	// index is the array index
+	index int
+	// isIndex is true if the step is an array index
+	isIndex bool
+}
+
+// key returns the path of the field of the object at the path
+func (p jsonPath) key(key string) jsonPath {
+	return append(append(jsonPath{}, p...), jsonPathStep{key: key})
+}
+
+// index returns the path of the element of the array at the path
+func (p jsonPath) index(i int) jsonPath {
+	return append(append(jsonPath{}, p...), jsonPathStep{index: i, isIndex: true})
+}
+
+// String formats the path as a JSONPath, e.g. $.items[0].name
+func (p jsonPath) String() string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, step := range p {
+		if step.isIndex {
+			b.WriteString("[" + strconv.Itoa(step.index) + "]")
+		} else if jsonPathIdentifier.MatchString(step.key) {
+			b.WriteString("." + step.key)
+		} else {
+			b.WriteString("['" + strings.ReplaceAll(step.key, "'", `\'`) + "']")
+		}
+	}
+	return b.String()
+}
+
+// jsonPathPattern is a JSONPath that selects the values that are ignored,
+// e.g. $.id, $.items[*].createdAt, $..updatedAt or $['a key']
+type jsonPathPattern []jsonPathSegment
+
+// jsonPathSegment is a segment of a jsonPathPattern
+type jsonPathSegment struct {
+	// key is the name of the field, or * for any field or element
+	key string
+	// index is the array index
+	index int
+	// isIndex is true if the segment is an array index
+	isIndex bool
+	// recursive is true if the segment matches at any depth, e.g. ..updatedAt
+	recursive bool
+}
+
+// parseJSONPathPattern parses a JSONPath made of fields, indexes, wildcards and recursive descents.
+func parseJSONPathPattern(path string) (jsonPathPattern, error) {
+	rest, ok := strings.CutPrefix(path, "$")
+	if !ok {
+		return nil, fmt.Errorf("invalid JSONPath %s: it must start with $", path)
+	}
+	var pattern jsonPathPattern
+	for rest != "" {
+		var segment jsonPathSegment
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			segment.recursive = true
+			rest = rest[1:]
+			fallthrough
+		case strings.HasPrefix(rest, "."):
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			segment.key, rest = rest[1:end+1], rest[end+1:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSONPath %s: missing ]", path)
+			}
+			selector := rest[1:end]
+			rest = rest[end+1:]
+			if quoted, err := strconv.Unquote(strings.ReplaceAll(selector, "'", `"`)); err == nil {
+				segment.key = quoted
+			} else if selector == "*" {
+				segment.key = "*"
+			} else if i, err := strconv.Atoi(selector); err == nil {
+				segment.index, segment.isIndex = i, true
+			} else {
+				return nil, fmt.Errorf("invalid JSONPath %s: unsupported selector [%s]", path, selector)
+			}
+		default:
+			return nil, fmt.Errorf("invalid JSONPath %s: unexpected %s", path, rest)
+		}
+		if segment.key == "" && !segment.isIndex {
+			return nil, fmt.Errorf("invalid JSONPath %s: empty field name", path)
+		}
+		pattern = append(pattern, segment)
+	}
+	return pattern, nil
+}
+
+// matches returns true if the pattern selects the value at the path.
+func (p jsonPathPattern) matches(path jsonPath) bool {
+	if len(p) == 0 {
+		return len(path) == 0
+	}
+	if len(path) == 0 {
+		return false
+	}
+	segment, step := p[0], path[0]
+	if segment.matchesStep(step) && p[1:].matches(path[1:]) {
+		return true
+	}
+	return segment.recursive && p.matches(path[1:])
+}
+
+// matchesStep returns true if the segment selects the step
+func (s jsonPathSegment) matchesStep(step jsonPathStep) bool {
+	switch {
+	case s.key == "*":
+		return true
+	case s.isIndex:
+		return step.isIndex && step.index == s.index
+	default:
+		return !step.isIndex && step.key == s.key
+	}
+}
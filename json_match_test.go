@@ -0,0 +1,168 @@
+package spectest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJSONMatch(t *testing.T) {
+	tests := map[string]struct {
+		match    jsonMatch
+		expected string
+		actual   string
+		diffs    []string
+	}{
+		"equal": {
+			expected: `{"a": 1, "b": [1, 2]}`,
+			actual:   `{"b": [1, 2], "a": 1}`,
+		},
+		"different value": {
+			expected: `{"a": {"b": "x"}}`,
+			actual:   `{"a": {"b": "y"}}`,
+			diffs:    []string{`$.a.b: expected "x", received "y"`},
+		},
+		"missing and unexpected fields": {
+			expected: `{"a": 1, "b": 2}`,
+			actual:   `{"a": 1, "c": 3}`,
+			diffs:    []string{`$.b: expected 2, but the field is missing`, `$.c: unexpected field with value 3`},
+		},
+		"subset allows other fields": {
+			match:    jsonMatch{subset: true},
+			expected: `{"a": {"b": 1}}`,
+			actual:   `{"a": {"b": 1, "c": 2}, "d": 3}`,
+		},
+		"subset still requires expected fields": {
+			match:    jsonMatch{subset: true},
+			expected: `{"a": 1, "b": 2}`,
+			actual:   `{"a": 1}`,
+			diffs:    []string{`$.b: expected 2, but the field is missing`},
+		},
+		"subset array elements in order": {
+			match:    jsonMatch{subset: true},
+			expected: `[1, 3]`,
+			actual:   `[1, 2, 3]`,
+		},
+		"subset array elements out of order": {
+			match:    jsonMatch{subset: true},
+			expected: `[3, 1]`,
+			actual:   `[1, 2, 3]`,
+			diffs:    []string{`$: no element matches expected element 1 1 in order`},
+		},
+		"array length": {
+			expected: `[1, 2]`,
+			actual:   `[1, 2, 3]`,
+			diffs:    []string{`$: expected 2 elements, received 3`},
+		},
+		"ordered array": {
+			expected: `[1, 2]`,
+			actual:   `[2, 1]`,
+			diffs:    []string{`$[0]: expected 1, received 2`, `$[1]: expected 2, received 1`},
+		},
+		"unordered array": {
+			match:    jsonMatch{unordered: true},
+			expected: `{"tags": ["a", "b", {"c": 1}]}`,
+			actual:   `{"tags": [{"c": 1}, "b", "a"]}`,
+		},
+		"unordered array needs a distinct element for each expected element": {
+			match:    jsonMatch{unordered: true},
+			expected: `["<<any>>", "a"]`,
+			actual:   `["a", "b"]`,
+		},
+		"unordered array mismatch": {
+			match:    jsonMatch{unordered: true},
+			expected: `["a", "a"]`,
+			actual:   `["a", "b"]`,
+			diffs:    []string{`$: no element matches expected element 1 "a"`},
+		},
+		"unordered subset array": {
+			match:    jsonMatch{unordered: true, subset: true},
+			expected: `[{"id": 3}]`,
+			actual:   `[{"id": 1, "name": "a"}, {"id": 3, "name": "c"}]`,
+		},
+		"ignored paths": {
+			match:    jsonMatch{ignored: []string{"$.id", "$.items[*].createdAt", "$..updatedAt", "$['a key']"}},
+			expected: `{"id": 1, "items": [{"name": "a", "createdAt": "x"}], "meta": {"updatedAt": "y"}}`,
+			actual:   `{"id": 2, "items": [{"name": "a", "createdAt": "z"}], "meta": {"updatedAt": "w"}, "a key": 1}`,
+		},
+		"ignored index": {
+			match:    jsonMatch{ignored: []string{"$.items[1]"}},
+			expected: `{"items": [1, 2]}`,
+			actual:   `{"items": [1, 3]}`,
+		},
+		"placeholders": {
+			expected: `{"id": "<<uuid>>", "createdAt": "<<rfc3339>>", "total": "<<number>>", "extra": "<<any>>"}`,
+			actual:   `{"id": "5b2f5d5e-0a8a-4a0b-9a3e-2f6e7c1d9b10", "createdAt": "2024-01-02T15:04:05.123+09:00", "total": 12.5, "extra": null}`,
+		},
+		"placeholder mismatches": {
+			expected: `{"id": "<<uuid>>", "createdAt": "<<rfc3339>>", "total": "<<number>>"}`,
+			actual:   `{"id": "42", "createdAt": "yesterday", "total": "12"}`,
+			diffs: []string{
+				`$.createdAt: expected <<rfc3339>>, received "yesterday"`,
+				`$.id: expected <<uuid>>, received "42"`,
+				`$.total: expected <<number>>, received "12"`,
+			},
+		},
+		"any requires the field": {
+			expected: `{"id": "<<any>>"}`,
+			actual:   `{}`,
+			diffs:    []string{`$.id: expected "<<any>>", but the field is missing`},
+		},
+		"type mismatch": {
+			expected: `{"a": [1]}`,
+			actual:   `{"a": {"b": 1}}`,
+			diffs:    []string{`$.a: expected an array, received {"b":1}`},
+		},
+		"actual is not JSON": {
+			expected: `{"a": 1}`,
+			actual:   `a=1`,
+			diffs:    []string{`$: expected JSON, received "a=1"`},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			diffs, err := test.match.match([]byte(test.expected), []byte(test.actual))
+
+			assert.NoError(t, err)
+			var lines []string
+			for _, d := range diffs {
+				lines = append(lines, d.String())
+			}
+			assert.Equal(t, test.diffs, lines)
+		})
+	}
+}
+
+func TestJSONMatchInvalidIgnoredPath(t *testing.T) {
+	for _, path := range []string{"id", "$.items[", "$.items[?(@.id)]", "$..", "$x"} {
+		_, err := jsonMatch{ignored: []string{path}}.match([]byte(`{}`), []byte(`{}`))
+
+		assert.True(t, err != nil)
+	}
+}
+
+func TestJSONMatchIsEnabled(t *testing.T) {
+	assert.True(t, !jsonMatch{}.isEnabled(`{"a": 1}`))
+	assert.True(t, jsonMatch{}.isEnabled(`{"a": "<<number>>"}`))
+	assert.True(t, jsonMatch{}.isEnabled(`{"a":"<<uuid>>"}`))
+	assert.True(t, jsonMatch{subset: true}.isEnabled(`{"a": 1}`))
+}
+
+func TestMockRequestJSONSubset(t *testing.T) {
+	mock := NewMock().
+		Post("http://example.com/orders").
+		JSON(map[string]interface{}{"item": "book", "id": JSONUUID}).
+		JSONSubset().
+		IgnoreJSONPaths("$.tags").
+		UnorderedJSONArrays()
+
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/orders",
+		strings.NewReader(`{"id": "5b2f5d5e-0a8a-4a0b-9a3e-2f6e7c1d9b10", "item": "book", "quantity": 2, "tags": "x"}`))
+	assert.NoError(t, bodyMatcher(req, mock))
+
+	req = httptest.NewRequest(http.MethodPost, "http://example.com/orders", strings.NewReader(`{"id": "1", "item": "pen"}`))
+	err := bodyMatcher(req, mock)
+	assert.Equal(t, "received body did not match expected mock body\n$.id: expected <<uuid>>, received \"1\"\n$.item: expected \"book\", received \"pen\"", err.Error())
+}
@@ -39,6 +39,10 @@ func CopyResponse(response *http.Response) *http.Response {
 
 // CopyRequest copy request
 func CopyRequest(request *http.Request) *http.Request {
+	if request == nil {
+		return nil
+	}
+
 	resCopy := &http.Request{
 		Method:        request.Method,
 		Host:          request.Host,
@@ -2,106 +2,83 @@
 package jsonpath
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"reflect"
 	regex "regexp"
 
+	"github.com/nao1215/spectest"
 	httputil "github.com/nao1215/spectest/jsonpath/http"
 	"github.com/nao1215/spectest/jsonpath/jsonpath"
 )
 
 // Contains is a convenience function to assert that a jsonpath expression extracts a value in an array
 func Contains(expression string, expected interface{}) func(*http.Response, *http.Request) error {
-	return func(res *http.Response, req *http.Request) error {
-		return jsonpath.Contains(expression, expected, res.Body)
-	}
+	return assertion(expression, expected, func(body io.Reader) error {
+		return jsonpath.Contains(expression, expected, body)
+	})
 }
 
 // Equal is a convenience function to assert that a jsonpath expression extracts a value
 func Equal(expression string, expected interface{}) func(*http.Response, *http.Request) error {
-	return func(res *http.Response, req *http.Request) error {
-		return jsonpath.Equal(expression, expected, res.Body)
-	}
+	return assertion(expression, expected, func(body io.Reader) error {
+		return jsonpath.Equal(expression, expected, body)
+	})
 }
 
 // NotEqual is a function to check json path expression value is not equal to given value
 func NotEqual(expression string, expected interface{}) func(*http.Response, *http.Request) error {
-	return func(res *http.Response, req *http.Request) error {
-		return jsonpath.NotEqual(expression, expected, res.Body)
-	}
+	return assertion(expression, expected, func(body io.Reader) error {
+		return jsonpath.NotEqual(expression, expected, body)
+	})
 }
 
 // Len asserts that value is the expected length, determined by reflect.Len
 func Len(expression string, expectedLength int) func(*http.Response, *http.Request) error {
-	return func(res *http.Response, req *http.Request) error {
-		return jsonpath.Length(expression, expectedLength, res.Body)
-	}
+	return assertion(expression, expectedLength, func(body io.Reader) error {
+		return jsonpath.Length(expression, expectedLength, body)
+	})
 }
 
 // GreaterThan asserts that value is greater than the given length, determined by reflect.Len
 func GreaterThan(expression string, minimumLength int) func(*http.Response, *http.Request) error {
-	return func(res *http.Response, req *http.Request) error {
-		return jsonpath.GreaterThan(expression, minimumLength, res.Body)
-	}
+	return assertion(expression, minimumLength, func(body io.Reader) error {
+		return jsonpath.GreaterThan(expression, minimumLength, body)
+	})
 }
 
 // LessThan asserts that value is less than the given length, determined by reflect.Len
 func LessThan(expression string, maximumLength int) func(*http.Response, *http.Request) error {
-	return func(res *http.Response, req *http.Request) error {
-		return jsonpath.LessThan(expression, maximumLength, res.Body)
-	}
+	return assertion(expression, maximumLength, func(body io.Reader) error {
+		return jsonpath.LessThan(expression, maximumLength, body)
+	})
 }
 
 // Present asserts that value returned by the expression is present
 func Present(expression string) func(*http.Response, *http.Request) error {
-	return func(res *http.Response, req *http.Request) error {
-		return jsonpath.Present(expression, res.Body)
-	}
+	return assertion(expression, nil, func(body io.Reader) error {
+		return jsonpath.Present(expression, body)
+	})
 }
 
 // NotPresent asserts that value returned by the expression is not present
 func NotPresent(expression string) func(*http.Response, *http.Request) error {
-	return func(res *http.Response, req *http.Request) error {
-		return jsonpath.NotPresent(expression, res.Body)
-	}
+	return assertion(expression, nil, func(body io.Reader) error {
+		return jsonpath.NotPresent(expression, body)
+	})
 }
 
 // Matches asserts that the value matches the given regular expression
 func Matches(expression string, regexp string) func(*http.Response, *http.Request) error {
 	return func(res *http.Response, req *http.Request) error {
-		pattern, err := regex.Compile(regexp)
-		if err != nil {
+		if _, err := regex.Compile(regexp); err != nil {
 			return fmt.Errorf("invalid pattern: '%s'", regexp)
 		}
-		value, _ := jsonpath.JSONPath(res.Body, expression)
-		if value == nil {
-			return fmt.Errorf("no match for pattern: '%s'", expression)
-		}
-		kind := reflect.ValueOf(value).Kind()
-		switch kind { //nolint:exhaustive
-		case reflect.Bool,
-			reflect.Int,
-			reflect.Int8,
-			reflect.Int16,
-			reflect.Int32,
-			reflect.Int64,
-			reflect.Uint,
-			reflect.Uint8,
-			reflect.Uint16,
-			reflect.Uint32,
-			reflect.Uint64,
-			reflect.Uintptr,
-			reflect.Float32,
-			reflect.Float64,
-			reflect.String:
-			if !pattern.Match([]byte(fmt.Sprintf("%v", value))) {
-				return fmt.Errorf("value '%v' does not match pattern '%v'", value, regexp)
-			}
-			return nil
-		default:
-			return fmt.Errorf("unable to match using type: %s", kind.String())
-		}
+		return assertion(expression, regexp, func(body io.Reader) error {
+			return jsonpath.Matches(expression, regexp, body)
+		})(res, req)
 	}
 }
 
@@ -157,12 +134,36 @@ func (r *AssertionChain) Matches(expression, regexp string) *AssertionChain {
 	return r
 }
 
-// End returns an func(*http.Response, *http.Request) error which is a combination of the registered assertions
+// End returns an func(*http.Response, *http.Request) error which is a combination of the registered assertions.
+// Every assertion runs, and the errors of the failed assertions are joined with errors.Join.
 func (r *AssertionChain) End() func(*http.Response, *http.Request) error {
 	return func(res *http.Response, req *http.Request) error {
+		var errs []error
 		for _, assertion := range r.assertions {
 			if err := assertion(httputil.CopyResponse(res), httputil.CopyRequest(req)); err != nil {
-				return err
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// assertion runs the check on the body of the response. Its error is a spectest.AssertionFailure
+// with the expression, the expected value and the value of the expression.
+func assertion(expression string, expected interface{}, check func(body io.Reader) error) func(*http.Response, *http.Request) error {
+	return func(res *http.Response, _ *http.Request) error {
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+		if err := check(bytes.NewReader(body)); err != nil {
+			actual, _ := jsonpath.JSONPath(bytes.NewReader(body), expression)
+			return &spectest.AssertionFailure{
+				Category: spectest.FailureBody,
+				Path:     expression,
+				Expected: expected,
+				Actual:   actual,
+				Message:  err.Error(),
 			}
 		}
 		return nil
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"regexp"
 	"strings"
 
 	"github.com/PaesslerAG/jsonpath"
@@ -126,6 +127,43 @@ func NotPresent(expression string, data io.Reader) error {
 	return nil
 }
 
+// Matches asserts that the value extracted by a jsonpath expression, a string, a number or a boolean,
+// matches the regular expression
+func Matches(expression string, pattern string, data io.Reader) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern: '%s'", pattern)
+	}
+	value, _ := JSONPath(data, expression)
+	if value == nil {
+		return fmt.Errorf("no match for pattern: '%s'", expression)
+	}
+	kind := reflect.ValueOf(value).Kind()
+	switch kind { //nolint:exhaustive
+	case reflect.Bool,
+		reflect.Int,
+		reflect.Int8,
+		reflect.Int16,
+		reflect.Int32,
+		reflect.Int64,
+		reflect.Uint,
+		reflect.Uint8,
+		reflect.Uint16,
+		reflect.Uint32,
+		reflect.Uint64,
+		reflect.Uintptr,
+		reflect.Float32,
+		reflect.Float64,
+		reflect.String:
+		if !re.MatchString(fmt.Sprintf("%v", value)) {
+			return fmt.Errorf("value '%v' does not match pattern '%v'", value, pattern)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unable to match using type: %s", kind.String())
+	}
+}
+
 // JSONPath evaluates the given expression against the given JSON document
 func JSONPath(reader io.Reader, expression string) (interface{}, error) {
 	v := interface{}(nil)
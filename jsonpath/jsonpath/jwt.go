@@ -0,0 +1,59 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// JWTPart is a JSON part of a JWT: the header or the payload
+type JWTPart int
+
+const (
+	// JWTHeader is the header of a JWT
+	JWTHeader JWTPart = 0
+	// JWTPayload is the payload of a JWT
+	JWTPayload JWTPart = 1
+)
+
+// JWTEqual asserts that a jsonpath expression extracts the expected value from the header or the payload of the JWT.
+// The part is JWTHeader or JWTPayload. The signature of the JWT is not verified.
+func JWTEqual(token string, part JWTPart, expression string, expected interface{}) error {
+	if part != JWTHeader && part != JWTPayload {
+		return fmt.Errorf("invalid jwt part: %d, expected the header or the payload", part)
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return errors.New("invalid token: token should contain header, payload and secret")
+	}
+
+	decoded, err := base64Decode(parts[part])
+	if err != nil {
+		return fmt.Errorf("invalid jwt: %s", err.Error())
+	}
+
+	value, err := JSONPath(bytes.NewReader(decoded), expression)
+	if err != nil {
+		return err
+	}
+
+	if !ObjectsAreEqual(value, expected) {
+		return fmt.Errorf("\"%s\" not equal to \"%s\"", value, expected)
+	}
+	return nil
+}
+
+func base64Decode(src string) ([]byte, error) {
+	if l := len(src) % 4; l > 0 {
+		src += strings.Repeat("=", 4-l)
+	}
+
+	decoded, err := base64.URLEncoding.DecodeString(src)
+	if err != nil {
+		errMsg := fmt.Errorf("decoding Error %w", err)
+		return nil, errMsg
+	}
+	return decoded, nil
+}
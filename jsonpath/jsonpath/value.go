@@ -0,0 +1,113 @@
+package jsonpath
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// ValueGreaterThan asserts that the value extracted by a jsonpath expression is a number greater than the minimum
+func ValueGreaterThan(expression string, minimum float64, data io.Reader) error {
+	value, err := number(expression, data)
+	if err != nil {
+		return err
+	}
+	if value <= minimum {
+		return fmt.Errorf("\"%v\" is not greater than \"%v\"", value, minimum)
+	}
+	return nil
+}
+
+// ValueLessThan asserts that the value extracted by a jsonpath expression is a number less than the maximum
+func ValueLessThan(expression string, maximum float64, data io.Reader) error {
+	value, err := number(expression, data)
+	if err != nil {
+		return err
+	}
+	if value >= maximum {
+		return fmt.Errorf("\"%v\" is not less than \"%v\"", value, maximum)
+	}
+	return nil
+}
+
+// Between asserts that the value extracted by a jsonpath expression is a number between the minimum and the maximum, inclusive
+func Between(expression string, minimum, maximum float64, data io.Reader) error {
+	value, err := number(expression, data)
+	if err != nil {
+		return err
+	}
+	if value < minimum || value > maximum {
+		return fmt.Errorf("\"%v\" is not between \"%v\" and \"%v\"", value, minimum, maximum)
+	}
+	return nil
+}
+
+// Approximately asserts that the value extracted by a jsonpath expression is a number within the tolerance of the expected number
+func Approximately(expression string, expected, tolerance float64, data io.Reader) error {
+	value, err := number(expression, data)
+	if err != nil {
+		return err
+	}
+	if math.Abs(value-expected) > tolerance {
+		return fmt.Errorf("\"%v\" is not within \"%v\" of \"%v\"", value, tolerance, expected)
+	}
+	return nil
+}
+
+// IsType asserts that the value extracted by a jsonpath expression is of the JSON type:
+// string, number, boolean, array, object or null
+func IsType(expression string, expectedType string, data io.Reader) error {
+	value, err := JSONPath(data, expression)
+	if err != nil {
+		return err
+	}
+	if actualType := TypeOf(value); actualType != expectedType {
+		return fmt.Errorf("\"%s\" is %s, not %s", expression, withArticle(actualType), withArticle(expectedType))
+	}
+	return nil
+}
+
+// TypeOf returns the JSON type of a value decoded by encoding/json
+func TypeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// withArticle returns the JSON type with its indefinite article, e.g. an array
+func withArticle(jsonType string) string {
+	switch jsonType {
+	case "null":
+		return jsonType
+	case "array", "object":
+		return "an " + jsonType
+	default:
+		return "a " + jsonType
+	}
+}
+
+// number returns the value extracted by a jsonpath expression, which must be a number
+func number(expression string, data io.Reader) (float64, error) {
+	value, err := JSONPath(data, expression)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := value.(float64)
+	if !ok {
+		return 0, fmt.Errorf("\"%s\" is %s, not a number", expression, withArticle(TypeOf(value)))
+	}
+	return n, nil
+}
@@ -326,6 +326,43 @@ func TestApiTestChain(t *testing.T) {
 		End()
 }
 
+func TestApiTestChainRunsEveryAssertion(t *testing.T) {
+	chain := jsonpath.Chain().
+		Equal("$.a", float64(2)).
+		Present("$.b").
+		Equal("$.c", "x").
+		End()
+
+	err := chain(&http.Response{Body: io.NopCloser(bytes.NewBufferString(`{"a": 1, "c": "y"}`))}, &http.Request{})
+
+	assert.EqualError(t, err, "\"%!s(float64=1)\" not equal to \"%!s(float64=2)\"\nvalue not present for expression: '$.b'\n\"y\" not equal to \"x\"")
+	var failure *spectest.AssertionFailure
+	assert.True(t, errors.As(err, &failure))
+	assert.Equal(t, spectest.FailureBody, failure.Category)
+	assert.Equal(t, "$.a", failure.Path)
+	assert.Equal(t, float64(2), failure.Expected)
+	assert.Equal(t, float64(1), failure.Actual)
+}
+
+func TestApiTestChainWithSoftAssertions(t *testing.T) {
+	result := spectest.New().
+		SoftAssertions().
+		Verifier(spectest.NoopVerifier{}).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"a": 1, "c": "y"}`))
+		}).
+		Get("/").
+		Expect(t).
+		Assert(jsonpath.Chain().Equal("$.a", float64(2)).Equal("$.c", "x").End()).
+		End()
+
+	failures := result.Failures()
+	assert.Len(t, failures, 2)
+	assert.Equal(t, "$.a", failures[0].Path)
+	assert.Equal(t, "$.c", failures[1].Path)
+	assert.Equal(t, "y", failures[1].Actual)
+}
+
 func TestApiTestMatchesFailCompile(t *testing.T) {
 	willFailToCompile := jsonpath.Matches(`$.b[? @.key=="c"].value`, `\`)
 	err := willFailToCompile(nil, nil)
@@ -2,7 +2,13 @@
 package mocks
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/nao1215/spectest"
 	httputil "github.com/nao1215/spectest/jsonpath/http"
@@ -11,35 +17,417 @@ import (
 
 // Contains is a convenience function to assert that a jsonpath expression extracts a value in an array
 func Contains(expression string, expected interface{}) spectest.Matcher {
-	return func(req *http.Request, mockReq *spectest.MockRequest) error {
-		return jsonpath.Contains(expression, expected, httputil.CopyRequest(req).Body)
-	}
+	return Chain().Contains(expression, expected).End()
 }
 
 // Equal is a convenience function to assert that a jsonpath expression matches the given value
 func Equal(expression string, expected interface{}) spectest.Matcher {
-	return func(req *http.Request, mockReq *spectest.MockRequest) error {
-		return jsonpath.Equal(expression, expected, httputil.CopyRequest(req).Body)
-	}
+	return Chain().Equal(expression, expected).End()
 }
 
 // NotEqual is a function to check json path expression value is not equal to given value
 func NotEqual(expression string, expected interface{}) spectest.Matcher {
-	return func(req *http.Request, mockReq *spectest.MockRequest) error {
-		return jsonpath.NotEqual(expression, expected, httputil.CopyRequest(req).Body)
-	}
+	return Chain().NotEqual(expression, expected).End()
 }
 
 // Len asserts that value is the expected length, determined by reflect.Len
 func Len(expression string, expectedLength int) spectest.Matcher {
-	return func(req *http.Request, mockReq *spectest.MockRequest) error {
-		return jsonpath.Length(expression, expectedLength, httputil.CopyRequest(req).Body)
-	}
+	return Chain().Len(expression, expectedLength).End()
 }
 
 // GreaterThan asserts that value is greater than the given length, determined by reflect.Len
 func GreaterThan(expression string, minimumLength int) spectest.Matcher {
+	return Chain().GreaterThan(expression, minimumLength).End()
+}
+
+// LessThan asserts that value is less than the given length, determined by reflect.Len
+func LessThan(expression string, maximumLength int) spectest.Matcher {
+	return Chain().LessThan(expression, maximumLength).End()
+}
+
+// Present asserts that value returned by the expression is present
+func Present(expression string) spectest.Matcher {
+	return Chain().Present(expression).End()
+}
+
+// NotPresent asserts that value returned by the expression is not present
+func NotPresent(expression string) spectest.Matcher {
+	return Chain().NotPresent(expression).End()
+}
+
+// Matches asserts that the value matches the given regular expression
+func Matches(expression string, regexp string) spectest.Matcher {
+	return Chain().Matches(expression, regexp).End()
+}
+
+// ValueGreaterThan asserts that the value is a number greater than the minimum.
+// Unlike GreaterThan, it compares the value and not its length.
+func ValueGreaterThan(expression string, minimum float64) spectest.Matcher {
+	return Chain().ValueGreaterThan(expression, minimum).End()
+}
+
+// ValueLessThan asserts that the value is a number less than the maximum.
+// Unlike LessThan, it compares the value and not its length.
+func ValueLessThan(expression string, maximum float64) spectest.Matcher {
+	return Chain().ValueLessThan(expression, maximum).End()
+}
+
+// Between asserts that the value is a number between the minimum and the maximum, inclusive
+func Between(expression string, minimum, maximum float64) spectest.Matcher {
+	return Chain().Between(expression, minimum, maximum).End()
+}
+
+// Approximately asserts that the value is a number within the tolerance of the expected number
+func Approximately(expression string, expected, tolerance float64) spectest.Matcher {
+	return Chain().Approximately(expression, expected, tolerance).End()
+}
+
+// IsString asserts that the value is a string
+func IsString(expression string) spectest.Matcher {
+	return Chain().IsString(expression).End()
+}
+
+// IsNumber asserts that the value is a number
+func IsNumber(expression string) spectest.Matcher {
+	return Chain().IsNumber(expression).End()
+}
+
+// IsBool asserts that the value is a boolean
+func IsBool(expression string) spectest.Matcher {
+	return Chain().IsBool(expression).End()
+}
+
+// IsArray asserts that the value is an array
+func IsArray(expression string) spectest.Matcher {
+	return Chain().IsArray(expression).End()
+}
+
+// IsObject asserts that the value is an object
+func IsObject(expression string) spectest.Matcher {
+	return Chain().IsObject(expression).End()
+}
+
+// IsNull asserts that the value is null. A missing value is not null.
+func IsNull(expression string) spectest.Matcher {
+	return Chain().IsNull(expression).End()
+}
+
+// Each applies the matcher to every element of the array extracted by the expression. The expressions of the matcher
+// are relative to the element, e.g. Each(`$.items`, ValueGreaterThan(`$.price`, 0)), and the matcher can be a Chain.
+func Each(expression string, matcher spectest.Matcher) spectest.Matcher {
+	return Chain().Each(expression, matcher).End()
+}
+
+// JWTHeaderEqual asserts that the header of the JWT selected from the request matches the expected value
+func JWTHeaderEqual(tokenSelector func(*http.Request) (string, error), expression string, expected interface{}) spectest.Matcher {
+	return jwtEqual(tokenSelector, "JWT header", jsonpath.JWTHeader, expression, expected)
+}
+
+// JWTPayloadEqual asserts that the payload of the JWT selected from the request matches the expected value
+func JWTPayloadEqual(tokenSelector func(*http.Request) (string, error), expression string, expected interface{}) spectest.Matcher {
+	return jwtEqual(tokenSelector, "JWT payload", jsonpath.JWTPayload, expression, expected)
+}
+
+func jwtEqual(tokenSelector func(*http.Request) (string, error), name string, part jsonpath.JWTPart, expression string, expected interface{}) spectest.Matcher {
+	return func(req *http.Request, _ *spectest.MockRequest) error {
+		token, err := tokenSelector(httputil.CopyRequest(req))
+		if err != nil {
+			return err
+		}
+		if err := jsonpath.JWTEqual(token, part, expression, expected); err != nil {
+			return fmt.Errorf("received %s did not match jsonpath '%s': %w", name, expression, err)
+		}
+		return nil
+	}
+}
+
+// Chain creates a new matcher chain on the body of the request
+func Chain() *MatcherChain {
+	return &MatcherChain{source: body}
+}
+
+// Root creates a new matcher chain on the body of the request, prefixed with the given expression
+func Root(expression string) *MatcherChain {
+	return &MatcherChain{source: body, rootExpression: expression + "."}
+}
+
+// Query creates a new matcher chain on the first value of the query parameter, parsed as JSON,
+// e.g. Query("filter").Equal(`$.name`, "jon") matches /users?filter={"name":"jon"}
+func Query(key string) *MatcherChain {
+	return &MatcherChain{source: query(key)}
+}
+
+// Form creates a new matcher chain on the first value of the form field, parsed as JSON
+func Form(key string) *MatcherChain {
+	return &MatcherChain{source: form(key)}
+}
+
+// MatcherChain supports chaining matchers and root expressions. End combines the matchers of the chain
+// into a single spectest.Matcher that can be added to a mock with AddMatcher.
+type MatcherChain struct {
+	source         source
+	rootExpression string
+	matchers       []spectest.Matcher
+}
+
+// Contains adds a Contains matcher to the chain
+func (r *MatcherChain) Contains(expression string, expected interface{}) *MatcherChain {
+	return r.add(expression, func(expression string, data io.Reader) error {
+		return jsonpath.Contains(expression, expected, data)
+	})
+}
+
+// Equal adds an Equal matcher to the chain
+func (r *MatcherChain) Equal(expression string, expected interface{}) *MatcherChain {
+	return r.add(expression, func(expression string, data io.Reader) error {
+		return jsonpath.Equal(expression, expected, data)
+	})
+}
+
+// NotEqual adds a NotEqual matcher to the chain
+func (r *MatcherChain) NotEqual(expression string, expected interface{}) *MatcherChain {
+	return r.add(expression, func(expression string, data io.Reader) error {
+		return jsonpath.NotEqual(expression, expected, data)
+	})
+}
+
+// Len adds a Len matcher to the chain
+func (r *MatcherChain) Len(expression string, expectedLength int) *MatcherChain {
+	return r.add(expression, func(expression string, data io.Reader) error {
+		return jsonpath.Length(expression, expectedLength, data)
+	})
+}
+
+// GreaterThan adds a GreaterThan matcher to the chain
+func (r *MatcherChain) GreaterThan(expression string, minimumLength int) *MatcherChain {
+	return r.add(expression, func(expression string, data io.Reader) error {
+		return jsonpath.GreaterThan(expression, minimumLength, data)
+	})
+}
+
+// LessThan adds a LessThan matcher to the chain
+func (r *MatcherChain) LessThan(expression string, maximumLength int) *MatcherChain {
+	return r.add(expression, func(expression string, data io.Reader) error {
+		return jsonpath.LessThan(expression, maximumLength, data)
+	})
+}
+
+// Present adds a Present matcher to the chain
+func (r *MatcherChain) Present(expression string) *MatcherChain {
+	return r.add(expression, jsonpath.Present)
+}
+
+// NotPresent adds a NotPresent matcher to the chain
+func (r *MatcherChain) NotPresent(expression string) *MatcherChain {
+	return r.add(expression, jsonpath.NotPresent)
+}
+
+// Matches adds a Matches matcher to the chain
+func (r *MatcherChain) Matches(expression, regexp string) *MatcherChain {
+	return r.add(expression, func(expression string, data io.Reader) error {
+		return jsonpath.Matches(expression, regexp, data)
+	})
+}
+
+// ValueGreaterThan adds a ValueGreaterThan matcher to the chain
+func (r *MatcherChain) ValueGreaterThan(expression string, minimum float64) *MatcherChain {
+	return r.add(expression, func(expression string, data io.Reader) error {
+		return jsonpath.ValueGreaterThan(expression, minimum, data)
+	})
+}
+
+// ValueLessThan adds a ValueLessThan matcher to the chain
+func (r *MatcherChain) ValueLessThan(expression string, maximum float64) *MatcherChain {
+	return r.add(expression, func(expression string, data io.Reader) error {
+		return jsonpath.ValueLessThan(expression, maximum, data)
+	})
+}
+
+// Between adds a Between matcher to the chain
+func (r *MatcherChain) Between(expression string, minimum, maximum float64) *MatcherChain {
+	return r.add(expression, func(expression string, data io.Reader) error {
+		return jsonpath.Between(expression, minimum, maximum, data)
+	})
+}
+
+// Approximately adds an Approximately matcher to the chain
+func (r *MatcherChain) Approximately(expression string, expected, tolerance float64) *MatcherChain {
+	return r.add(expression, func(expression string, data io.Reader) error {
+		return jsonpath.Approximately(expression, expected, tolerance, data)
+	})
+}
+
+// IsString adds an IsString matcher to the chain
+func (r *MatcherChain) IsString(expression string) *MatcherChain {
+	return r.isType(expression, "string")
+}
+
+// IsNumber adds an IsNumber matcher to the chain
+func (r *MatcherChain) IsNumber(expression string) *MatcherChain {
+	return r.isType(expression, "number")
+}
+
+// IsBool adds an IsBool matcher to the chain
+func (r *MatcherChain) IsBool(expression string) *MatcherChain {
+	return r.isType(expression, "boolean")
+}
+
+// IsArray adds an IsArray matcher to the chain
+func (r *MatcherChain) IsArray(expression string) *MatcherChain {
+	return r.isType(expression, "array")
+}
+
+// IsObject adds an IsObject matcher to the chain
+func (r *MatcherChain) IsObject(expression string) *MatcherChain {
+	return r.isType(expression, "object")
+}
+
+// IsNull adds an IsNull matcher to the chain
+func (r *MatcherChain) IsNull(expression string) *MatcherChain {
+	return r.isType(expression, "null")
+}
+
+func (r *MatcherChain) isType(expression, expectedType string) *MatcherChain {
+	return r.add(expression, func(expression string, data io.Reader) error {
+		return jsonpath.IsType(expression, expectedType, data)
+	})
+}
+
+// Each adds an Each matcher to the chain. Every element of the array is matched as the body of a copy of the request.
+func (r *MatcherChain) Each(expression string, matcher spectest.Matcher) *MatcherChain {
+	expression = r.rootExpression + expression
+	src := r.source
+	r.matchers = append(r.matchers, func(req *http.Request, mockReq *spectest.MockRequest) error {
+		data, err := src.read(req)
+		if err != nil {
+			return err
+		}
+		document, err := io.ReadAll(data)
+		if err != nil {
+			return err
+		}
+		value, err := jsonpath.JSONPath(bytes.NewReader(document), expression)
+		if err != nil {
+			return src.mismatch(expression, err)
+		}
+		elements, ok := value.([]interface{})
+		if !ok {
+			return src.mismatch(expression, jsonpath.IsType(expression, "array", bytes.NewReader(document)))
+		}
+
+		var errs []error
+		for i, element := range elements {
+			b, err := json.Marshal(element)
+			if err != nil {
+				return err
+			}
+			elementReq := httputil.CopyRequest(req)
+			elementReq.Body = io.NopCloser(bytes.NewReader(b))
+			elementReq.ContentLength = int64(len(b))
+			if err := matcher(elementReq, mockReq); err != nil {
+				errs = append(errs, elementError(fmt.Sprintf("%s[%d]", expression, i), err))
+			}
+		}
+		return errors.Join(errs...)
+	})
+	return r
+}
+
+// End returns a spectest.Matcher which is a combination of the registered matchers.
+// Every matcher runs, and the errors of the failed matchers are joined with errors.Join.
+func (r *MatcherChain) End() spectest.Matcher {
+	matchers := r.matchers
 	return func(req *http.Request, mockReq *spectest.MockRequest) error {
-		return jsonpath.GreaterThan(expression, minimumLength, httputil.CopyRequest(req).Body)
+		var errs []error
+		for _, matcher := range matchers {
+			if err := matcher(req, mockReq); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// add adds a matcher that runs the check on the JSON document of the source of the chain
+func (r *MatcherChain) add(expression string, check func(expression string, data io.Reader) error) *MatcherChain {
+	expression = r.rootExpression + expression
+	src := r.source
+	r.matchers = append(r.matchers, func(req *http.Request, _ *spectest.MockRequest) error {
+		data, err := src.read(req)
+		if err != nil {
+			return err
+		}
+		if err := check(expression, data); err != nil {
+			return src.mismatch(expression, err)
+		}
+		return nil
+	})
+	return r
+}
+
+// elementError prefixes the error of the matcher of an element, or each of its joined errors, with the path of the element
+func elementError(path string, err error) error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var errs []error
+		for _, err := range joined.Unwrap() {
+			errs = append(errs, elementError(path, err))
+		}
+		return errors.Join(errs...)
+	}
+	return fmt.Errorf("%s: %w", path, err)
+}
+
+// source is the part of the request that holds the JSON document of a matcher: the body, a query parameter or a form field
+type source struct {
+	name string
+	read func(*http.Request) (io.Reader, error)
+}
+
+// mismatch returns the error of a failed matcher, which names the source and the expression
+func (s source) mismatch(expression string, err error) error {
+	return fmt.Errorf("received %s did not match jsonpath '%s': %w", s.name, expression, err)
+}
+
+var body = source{
+	name: "body",
+	read: func(req *http.Request) (io.Reader, error) {
+		reqCopy := httputil.CopyRequest(req)
+		if reqCopy == nil || reqCopy.Body == nil {
+			return strings.NewReader(""), nil
+		}
+		return reqCopy.Body, nil
+	},
+}
+
+func query(key string) source {
+	return source{
+		name: fmt.Sprintf("query parameter '%s'", key),
+		read: func(req *http.Request) (io.Reader, error) {
+			values := req.URL.Query()[key]
+			if len(values) == 0 {
+				return nil, fmt.Errorf("query parameter '%s' is not present", key)
+			}
+			return strings.NewReader(values[0]), nil
+		},
+	}
+}
+
+func form(key string) source {
+	return source{
+		name: fmt.Sprintf("form field '%s'", key),
+		read: func(req *http.Request) (io.Reader, error) {
+			reqCopy := httputil.CopyRequest(req)
+			if reqCopy.Body == nil {
+				reqCopy.Body = http.NoBody
+			}
+			if err := reqCopy.ParseForm(); err != nil {
+				return nil, fmt.Errorf("unable to parse form: %w", err)
+			}
+			values := reqCopy.PostForm[key]
+			if len(values) == 0 {
+				return nil, fmt.Errorf("form field '%s' is not present", key)
+			}
+			return strings.NewReader(values[0]), nil
+		},
 	}
 }
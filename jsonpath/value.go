@@ -0,0 +1,215 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/nao1215/spectest"
+	httputil "github.com/nao1215/spectest/jsonpath/http"
+	"github.com/nao1215/spectest/jsonpath/jsonpath"
+)
+
+// ValueGreaterThan asserts that the value is a number greater than the minimum, e.g. ValueGreaterThan(`$.price`, 100).
+// Unlike GreaterThan, it compares the value and not its length.
+func ValueGreaterThan(expression string, minimum float64) func(*http.Response, *http.Request) error {
+	return assertion(expression, minimum, func(body io.Reader) error {
+		return jsonpath.ValueGreaterThan(expression, minimum, body)
+	})
+}
+
+// ValueLessThan asserts that the value is a number less than the maximum.
+// Unlike LessThan, it compares the value and not its length.
+func ValueLessThan(expression string, maximum float64) func(*http.Response, *http.Request) error {
+	return assertion(expression, maximum, func(body io.Reader) error {
+		return jsonpath.ValueLessThan(expression, maximum, body)
+	})
+}
+
+// Between asserts that the value is a number between the minimum and the maximum, inclusive
+func Between(expression string, minimum, maximum float64) func(*http.Response, *http.Request) error {
+	return assertion(expression, []float64{minimum, maximum}, func(body io.Reader) error {
+		return jsonpath.Between(expression, minimum, maximum, body)
+	})
+}
+
+// Approximately asserts that the value is a number within the tolerance of the expected number,
+// e.g. Approximately(`$.total`, 10.2, 0.01)
+func Approximately(expression string, expected, tolerance float64) func(*http.Response, *http.Request) error {
+	return assertion(expression, expected, func(body io.Reader) error {
+		return jsonpath.Approximately(expression, expected, tolerance, body)
+	})
+}
+
+// IsString asserts that the value is a string
+func IsString(expression string) func(*http.Response, *http.Request) error {
+	return isType(expression, "string")
+}
+
+// IsNumber asserts that the value is a number
+func IsNumber(expression string) func(*http.Response, *http.Request) error {
+	return isType(expression, "number")
+}
+
+// IsBool asserts that the value is a boolean
+func IsBool(expression string) func(*http.Response, *http.Request) error {
+	return isType(expression, "boolean")
+}
+
+// IsArray asserts that the value is an array
+func IsArray(expression string) func(*http.Response, *http.Request) error {
+	return isType(expression, "array")
+}
+
+// IsObject asserts that the value is an object
+func IsObject(expression string) func(*http.Response, *http.Request) error {
+	return isType(expression, "object")
+}
+
+// IsNull asserts that the value is null. A missing value is not null.
+func IsNull(expression string) func(*http.Response, *http.Request) error {
+	return isType(expression, "null")
+}
+
+// isType asserts that the value is of the JSON type
+func isType(expression, expectedType string) func(*http.Response, *http.Request) error {
+	return assertion(expression, expectedType, func(body io.Reader) error {
+		return jsonpath.IsType(expression, expectedType, body)
+	})
+}
+
+// Each applies the assertion to every element of the array extracted by the expression. The expressions of the assertion
+// are relative to the element, e.g. Each(`$.items`, ValueGreaterThan(`$.price`, 0)), and the assertion can be a Chain.
+// The failures of all the elements are returned, with the path of the element, e.g. $.items[2].price.
+func Each(expression string, assertion func(*http.Response, *http.Request) error) func(*http.Response, *http.Request) error {
+	return func(res *http.Response, req *http.Request) error {
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+		value, err := jsonpath.JSONPath(bytes.NewReader(body), expression)
+		if err != nil {
+			return err
+		}
+		elements, ok := value.([]interface{})
+		if !ok {
+			return &spectest.AssertionFailure{
+				Category: spectest.FailureBody,
+				Path:     expression,
+				Expected: "array",
+				Actual:   value,
+				Message:  jsonpath.IsType(expression, "array", bytes.NewReader(body)).Error(),
+			}
+		}
+
+		var errs []error
+		for i, element := range elements {
+			b, err := json.Marshal(element)
+			if err != nil {
+				return err
+			}
+			elementResponse := httputil.CopyResponse(res)
+			elementResponse.Body = io.NopCloser(bytes.NewReader(b))
+			if err := assertion(elementResponse, req); err != nil {
+				errs = append(errs, elementError(fmt.Sprintf("%s[%d]", expression, i), err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// elementError prefixes the error of the assertion of an element, and the paths of its failures, with the path of the element
+func elementError(path string, err error) error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var errs []error
+		for _, err := range joined.Unwrap() {
+			errs = append(errs, elementError(path, err))
+		}
+		return errors.Join(errs...)
+	}
+	var failure *spectest.AssertionFailure
+	if !errors.As(err, &failure) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	prefixed := *failure
+	switch {
+	case failure.Path == "" || failure.Path == "$":
+		prefixed.Path = path
+	case strings.HasPrefix(failure.Path, "$"):
+		prefixed.Path = path + strings.TrimPrefix(failure.Path, "$")
+	default:
+		prefixed.Path = path + "." + failure.Path
+	}
+	prefixed.Message = path + ": " + failure.Message
+	return &prefixed
+}
+
+// ValueGreaterThan adds a ValueGreaterThan assertion to the chain
+func (r *AssertionChain) ValueGreaterThan(expression string, minimum float64) *AssertionChain {
+	r.assertions = append(r.assertions, ValueGreaterThan(r.rootExpression+expression, minimum))
+	return r
+}
+
+// ValueLessThan adds a ValueLessThan assertion to the chain
+func (r *AssertionChain) ValueLessThan(expression string, maximum float64) *AssertionChain {
+	r.assertions = append(r.assertions, ValueLessThan(r.rootExpression+expression, maximum))
+	return r
+}
+
+// Between adds a Between assertion to the chain
+func (r *AssertionChain) Between(expression string, minimum, maximum float64) *AssertionChain {
+	r.assertions = append(r.assertions, Between(r.rootExpression+expression, minimum, maximum))
+	return r
+}
+
+// Approximately adds an Approximately assertion to the chain
+func (r *AssertionChain) Approximately(expression string, expected, tolerance float64) *AssertionChain {
+	r.assertions = append(r.assertions, Approximately(r.rootExpression+expression, expected, tolerance))
+	return r
+}
+
+// IsString adds an IsString assertion to the chain
+func (r *AssertionChain) IsString(expression string) *AssertionChain {
+	r.assertions = append(r.assertions, IsString(r.rootExpression+expression))
+	return r
+}
+
+// IsNumber adds an IsNumber assertion to the chain
+func (r *AssertionChain) IsNumber(expression string) *AssertionChain {
+	r.assertions = append(r.assertions, IsNumber(r.rootExpression+expression))
+	return r
+}
+
+// IsBool adds an IsBool assertion to the chain
+func (r *AssertionChain) IsBool(expression string) *AssertionChain {
+	r.assertions = append(r.assertions, IsBool(r.rootExpression+expression))
+	return r
+}
+
+// IsArray adds an IsArray assertion to the chain
+func (r *AssertionChain) IsArray(expression string) *AssertionChain {
+	r.assertions = append(r.assertions, IsArray(r.rootExpression+expression))
+	return r
+}
+
+// IsObject adds an IsObject assertion to the chain
+func (r *AssertionChain) IsObject(expression string) *AssertionChain {
+	r.assertions = append(r.assertions, IsObject(r.rootExpression+expression))
+	return r
+}
+
+// IsNull adds an IsNull assertion to the chain
+func (r *AssertionChain) IsNull(expression string) *AssertionChain {
+	r.assertions = append(r.assertions, IsNull(r.rootExpression+expression))
+	return r
+}
+
+// Each adds an Each assertion to the chain
+func (r *AssertionChain) Each(expression string, assertion func(*http.Response, *http.Request) error) *AssertionChain {
+	r.assertions = append(r.assertions, Each(r.rootExpression+expression, assertion))
+	return r
+}
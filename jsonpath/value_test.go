@@ -0,0 +1,168 @@
+package jsonpath_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/nao1215/spectest"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nao1215/spectest/jsonpath"
+)
+
+const orderBody = `{
+	"id": "o-1",
+	"total": 10.2,
+	"paid": true,
+	"coupon": null,
+	"customer": {"name": "ada"},
+	"items": [
+		{"sku": "pen", "price": 2.5, "quantity": 2},
+		{"sku": "ink", "price": 5.2, "quantity": 1}
+	]
+}`
+
+func orderResponse() *http.Response {
+	return &http.Response{Body: io.NopCloser(bytes.NewBufferString(orderBody))}
+}
+
+func TestApiTestValueAssertions(t *testing.T) {
+	spectest.New().
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(orderBody))
+		}).
+		Get("/order").
+		Expect(t).
+		Assert(jsonpath.ValueGreaterThan(`$.total`, 10)).
+		Assert(jsonpath.ValueLessThan(`$.items[0].price`, 3)).
+		Assert(jsonpath.Between(`$.items[1].quantity`, 1, 10)).
+		Assert(jsonpath.Approximately(`$.total`, 10.19, 0.02)).
+		Assert(jsonpath.IsString(`$.id`)).
+		Assert(jsonpath.IsNumber(`$.total`)).
+		Assert(jsonpath.IsBool(`$.paid`)).
+		Assert(jsonpath.IsArray(`$.items`)).
+		Assert(jsonpath.IsObject(`$.customer`)).
+		Assert(jsonpath.IsNull(`$.coupon`)).
+		Assert(jsonpath.Each(`$.items`, jsonpath.Chain().
+			IsString(`$.sku`).
+			ValueGreaterThan(`$.price`, 0).
+			Between(`$.quantity`, 1, 2).
+			End())).
+		Assert(jsonpath.Root(`$`).
+			ValueGreaterThan(`total`, 0).
+			ValueLessThan(`total`, 11).
+			Between(`total`, 10.2, 10.2).
+			Approximately(`total`, 10, 0.5).
+			IsString(`id`).
+			IsNumber(`total`).
+			IsBool(`paid`).
+			IsArray(`items`).
+			IsObject(`customer`).
+			IsNull(`coupon`).
+			Each(`items`, jsonpath.IsNumber(`$.price`)).
+			End()).
+		End()
+}
+
+func TestApiTestValueAssertionFailures(t *testing.T) {
+	tests := map[string]struct {
+		assertion func(*http.Response, *http.Request) error
+		err       string
+	}{
+		"greater than": {
+			assertion: jsonpath.ValueGreaterThan(`$.total`, 10.2),
+			err:       `"10.2" is not greater than "10.2"`,
+		},
+		"less than": {
+			assertion: jsonpath.ValueLessThan(`$.total`, 5),
+			err:       `"10.2" is not less than "5"`,
+		},
+		"between": {
+			assertion: jsonpath.Between(`$.total`, 1, 10),
+			err:       `"10.2" is not between "1" and "10"`,
+		},
+		"approximately": {
+			assertion: jsonpath.Approximately(`$.total`, 10, 0.1),
+			err:       `"10.2" is not within "0.1" of "10"`,
+		},
+		"not a number": {
+			assertion: jsonpath.ValueGreaterThan(`$.id`, 0),
+			err:       `"$.id" is a string, not a number`,
+		},
+		"null is not a number": {
+			assertion: jsonpath.Between(`$.coupon`, 0, 1),
+			err:       `"$.coupon" is null, not a number`,
+		},
+		"is string": {
+			assertion: jsonpath.IsString(`$.total`),
+			err:       `"$.total" is a number, not a string`,
+		},
+		"is number": {
+			assertion: jsonpath.IsNumber(`$.items`),
+			err:       `"$.items" is an array, not a number`,
+		},
+		"is array": {
+			assertion: jsonpath.IsArray(`$.customer`),
+			err:       `"$.customer" is an object, not an array`,
+		},
+		"is object": {
+			assertion: jsonpath.IsObject(`$.paid`),
+			err:       `"$.paid" is a boolean, not an object`,
+		},
+		"is null": {
+			assertion: jsonpath.IsNull(`$.id`),
+			err:       `"$.id" is a string, not null`,
+		},
+		"each of an object": {
+			assertion: jsonpath.Each(`$.customer`, jsonpath.Present(`$.name`)),
+			err:       `"$.customer" is an object, not an array`,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.assertion(orderResponse(), nil)
+
+			assert.EqualError(t, err, test.err)
+			var failure *spectest.AssertionFailure
+			assert.True(t, errors.As(err, &failure))
+		})
+	}
+}
+
+func TestApiTestEachReportsTheFailuresOfEveryElement(t *testing.T) {
+	err := jsonpath.Each(`$.items`, jsonpath.Chain().
+		ValueGreaterThan(`$.price`, 3).
+		Equal(`$.quantity`, float64(1)).
+		End())(orderResponse(), nil)
+
+	assert.EqualError(t, err, "$.items[0]: \"2.5\" is not greater than \"3\"\n"+
+		"$.items[0]: \"%!s(float64=2)\" not equal to \"%!s(float64=1)\"")
+	var failure *spectest.AssertionFailure
+	assert.True(t, errors.As(err, &failure))
+	assert.Equal(t, "$.items[0].price", failure.Path)
+	assert.Equal(t, float64(3), failure.Expected)
+	assert.Equal(t, 2.5, failure.Actual)
+}
+
+func TestApiTestEachWithSoftAssertions(t *testing.T) {
+	result := spectest.New().
+		SoftAssertions().
+		Verifier(spectest.NoopVerifier{}).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(orderBody))
+		}).
+		Get("/order").
+		Expect(t).
+		Assert(jsonpath.Each(`$.items`, jsonpath.ValueLessThan(`$.price`, 2))).
+		End()
+
+	failures := result.Failures()
+	assert.Len(t, failures, 2)
+	assert.Equal(t, "$.items[0].price", failures[0].Path)
+	assert.Equal(t, "$.items[1].price", failures[1].Path)
+	assert.Equal(t, `$.items[1]: "5.2" is not less than "2"`, failures[1].Message)
+}
@@ -33,6 +33,10 @@ type Meta struct {
 	StatusCode int `json:"status_code,omitempty"`
 	// TestingTargetName represents the name of the system under test.
 	TestingTargetName string `json:"testing_target_name,omitempty"`
+	// Variables represents the variables captured by the steps of a scenario.
+	Variables map[string]string `json:"variables,omitempty"`
+	// Failures represents the failed checks of the test in soft assertion mode.
+	Failures []AssertionFailure `json:"failures,omitempty"`
 }
 
 // newMeta creates a new meta data object.
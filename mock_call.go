@@ -0,0 +1,145 @@
+package spectest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// mockCallSequence orders the calls of all the mocks, because the timestamps of concurrent calls can be equal
+var mockCallSequence atomic.Uint64
+
+// MockCall is a request that matched a mock
+type MockCall struct {
+	// Request is a copy of the request. Its body can be read again.
+	Request *http.Request
+	// Body is the body of the request
+	Body []byte
+	// Timestamp is the time the request matched the mock
+	Timestamp time.Time
+	// Response is a copy of the response of the real service to a spy mock. It is nil for the other mocks.
+	Response *http.Response
+	// ResponseBody is the body of the response of the real service to a spy mock
+	ResponseBody []byte
+	// sequence is the position of the call among the calls of all the mocks
+	sequence uint64
+	// origin is the request received by the transport, used to attach the response of a spy mock to the call
+	origin *http.Request
+}
+
+// JSON decodes the body of the request into v
+func (c MockCall) JSON(v interface{}) error {
+	return json.Unmarshal(c.Body, v)
+}
+
+// mockCalls is the list of calls of a mock. Each copy of a mock has its own list.
+type mockCalls struct {
+	// mu guards calls
+	mu sync.Mutex
+	// calls is the list of calls in the order they were received
+	calls []MockCall
+}
+
+// add records a call of the mock.
+func (c *mockCalls) add(req *http.Request) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	call := copyHTTPRequest(req)
+	call.Body = io.NopCloser(bytes.NewReader(body))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, MockCall{
+		Request:   call,
+		Body:      body,
+		Timestamp: time.Now().UTC(),
+		sequence:  mockCallSequence.Add(1),
+		origin:    req,
+	})
+}
+
+// respond attaches the response of the real service to the call of a spy mock.
+func (c *mockCalls) respond(req *http.Request, res *http.Response, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := len(c.calls) - 1; i >= 0; i-- {
+		if c.calls[i].origin == req {
+			c.calls[i].Response, c.calls[i].ResponseBody = res, body
+			return
+		}
+	}
+}
+
+// reset forgets the calls.
+func (c *mockCalls) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = nil
+}
+
+// list returns a copy of the calls.
+func (c *mockCalls) list() []MockCall {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]MockCall{}, c.calls...)
+}
+
+// Calls returns every request that matched the mock, in the order they were received.
+// A mock that expects multiple executions returns the calls of all of its executions.
+// A SpecTest or a MockServer records the calls in its own copy of the mock, so a mock that is
+// used by several tests returns the calls of the last test (or server) that was given the mock.
+func (m *Mock) Calls() []MockCall {
+	m.m.Lock()
+	calls := m.calls
+	m.m.Unlock()
+	return calls.list()
+}
+
+// String describes the mock by its method and url, e.g. "POST http://payments.example.com/charges"
+func (m *Mock) String() string {
+	method := m.request.method
+	if method == "" {
+		method = "ANY"
+	}
+	if m.request.url == nil {
+		return method
+	}
+	u := m.request.url.String()
+	if unescaped, err := url.PathUnescape(u); err == nil {
+		u = unescaped // show the wildcards of the path, e.g. /users/{id}
+	}
+	return fmt.Sprintf("%s %s", method, u)
+}
+
+// CalledInOrder returns an error unless the mocks were called in the given order,
+// e.g. the auth service before the payment service. Each mock must have a call that
+// was received after a call of the previous mock. Other calls between them are ignored.
+func (mocks Mocks) CalledInOrder() error {
+	var previous *MockCall
+	for i, mock := range mocks {
+		var next *MockCall
+		for _, call := range mock.Calls() {
+			if previous == nil || call.sequence > previous.sequence {
+				next = &call
+				break
+			}
+		}
+		if next == nil && previous == nil {
+			return fmt.Errorf("mock %d (%s) was not called", i+1, mock)
+		}
+		if next == nil {
+			return fmt.Errorf("mock %d (%s) was not called after mock %d (%s)", i+1, mock, i, mocks[i-1])
+		}
+		previous = next
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package spectest_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/nao1215/spectest"
+)
+
+// checkoutHandler authenticates the user, then charges the card twice
+func checkoutHandler(w http.ResponseWriter, _ *http.Request) {
+	for _, call := range []struct{ url, body string }{
+		{"http://auth.example.com/token", `{"user": "bob"}`},
+		{"http://payments.example.com/charges", `{"amount": 100}`},
+		{"http://payments.example.com/charges", `{"amount": 200}`},
+	} {
+		res, err := http.Post(call.url, "application/json", strings.NewReader(call.body))
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_ = res.Body.Close()
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestMockCallsAreCapturedAndOrdered(t *testing.T) {
+	auth := spectest.NewMock().
+		Post("http://auth.example.com/token").
+		RespondWith().
+		Status(http.StatusOK).
+		End()
+	charge := spectest.NewMock().
+		Post("http://payments.example.com/charges").
+		RespondWith().
+		Status(http.StatusCreated).
+		Times(2).
+		End()
+
+	spectest.New().
+		Mocks(auth, charge).
+		HandlerFunc(checkoutHandler).
+		Get("/checkout").
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+
+	calls := charge.Calls()
+	spectest.DefaultVerifier{}.Equal(t, 2, len(calls))
+	var body struct {
+		Amount int `json:"amount"`
+	}
+	spectest.DefaultVerifier{}.NoError(t, calls[1].JSON(&body))
+	spectest.DefaultVerifier{}.Equal(t, 200, body.Amount)
+	spectest.DefaultVerifier{}.Equal(t, `{"amount": 100}`, string(calls[0].Body))
+	requestBody, _ := io.ReadAll(calls[0].Request.Body)
+	spectest.DefaultVerifier{}.Equal(t, `{"amount": 100}`, string(requestBody))
+	spectest.DefaultVerifier{}.Equal(t, "application/json", calls[0].Request.Header.Get("Content-Type"))
+	spectest.DefaultVerifier{}.True(t, !calls[0].Timestamp.After(calls[1].Timestamp))
+	spectest.DefaultVerifier{}.True(t, !auth.Calls()[0].Timestamp.After(calls[0].Timestamp))
+
+	spectest.DefaultVerifier{}.NoError(t, spectest.Mocks{auth, charge}.CalledInOrder())
+	spectest.DefaultVerifier{}.NoError(t, spectest.Mocks{auth, charge, charge}.CalledInOrder())
+	spectest.DefaultVerifier{}.Equal(t,
+		"mock 2 (POST http://auth.example.com/token) was not called after mock 1 (POST http://payments.example.com/charges)",
+		spectest.Mocks{charge, auth}.CalledInOrder().Error())
+}
+
+func TestMockCalledInOrderFailsWhenMockIsNotCalled(t *testing.T) {
+	mock := spectest.NewMock().Get("http://example.com/users").RespondWith().Status(http.StatusOK).End()
+
+	spectest.DefaultVerifier{}.Equal(t, "mock 1 (GET http://example.com/users) was not called", spectest.Mocks{mock}.CalledInOrder().Error())
+	spectest.DefaultVerifier{}.Equal(t, 0, len(mock.Calls()))
+}
+
+func TestMockCallsAreThoseOfTheLastTest(t *testing.T) {
+	auth := spectest.NewMock().
+		Post("http://auth.example.com/token").
+		RespondWith().
+		Status(http.StatusOK).
+		End()
+	charge := spectest.NewMock().
+		Post("http://payments.example.com/charges").
+		RespondWith().
+		Status(http.StatusCreated).
+		AnyTimes().
+		End()
+
+	spectest.New().
+		Mocks(auth, charge).
+		HandlerFunc(checkoutHandler).
+		Get("/checkout").
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+	spectest.DefaultVerifier{}.NoError(t, spectest.Mocks{auth, charge}.CalledInOrder())
+
+	spectest.New().
+		Mocks(auth, charge).
+		HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			res, err := http.Post("http://payments.example.com/charges", "application/json", strings.NewReader(`{"amount": 300}`))
+			if err != nil {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			_ = res.Body.Close()
+			w.WriteHeader(http.StatusOK)
+		}).
+		Get("/charge").
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+
+	spectest.DefaultVerifier{}.Equal(t, 0, len(auth.Calls()))
+	spectest.DefaultVerifier{}.Equal(t, 1, len(charge.Calls()))
+	spectest.DefaultVerifier{}.Equal(t, `{"amount": 300}`, string(charge.Calls()[0].Body))
+	spectest.DefaultVerifier{}.Equal(t,
+		"mock 1 (POST http://auth.example.com/token) was not called",
+		spectest.Mocks{auth, charge}.CalledInOrder().Error())
+}
@@ -0,0 +1,353 @@
+package spectest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrConnectionReset is the error returned by the mocks that inject a connection reset.
+// It wraps syscall.ECONNRESET, so errors.Is(err, syscall.ECONNRESET) reports true.
+var ErrConnectionReset = fmt.Errorf("mock connection reset: %w", syscall.ECONNRESET)
+
+// defaultFaultSeed is the seed of the random faults if FaultSeed is not set, so that the runs are reproducible
+const defaultFaultSeed = 1
+
+// faultContextKey is the context key of the description of the fault injected into a mock response
+type faultContextKey struct{}
+
+// Latency returns a random latency drawn from a distribution with the given random number generator
+type Latency func(rng *rand.Rand) time.Duration
+
+// UniformLatency returns a latency that is uniformly distributed between min and max
+func UniformLatency(min, max time.Duration) Latency {
+	return func(rng *rand.Rand) time.Duration {
+		if max <= min {
+			return min
+		}
+		return min + time.Duration(rng.Int63n(int64(max-min)+1))
+	}
+}
+
+// NormalLatency returns a latency that is normally distributed with the given mean and standard deviation.
+// Negative latencies are truncated to zero.
+func NormalLatency(mean, stddev time.Duration) Latency {
+	return func(rng *rand.Rand) time.Duration {
+		return time.Duration(math.Max(0, rng.NormFloat64()*float64(stddev)+float64(mean)))
+	}
+}
+
+// ExponentialLatency returns a latency that is exponentially distributed with the given mean.
+// It models a service that usually answers quickly, with a long tail of slow responses.
+func ExponentialLatency(mean time.Duration) Latency {
+	return func(rng *rand.Rand) time.Duration {
+		return time.Duration(rng.ExpFloat64() * float64(mean))
+	}
+}
+
+// mockFaults is the faults injected into the responses of a mock.
+// The copies of a mock share its faults, so that they share the random number generator.
+type mockFaults struct {
+	// mu guards rng
+	mu sync.Mutex
+	// rng is the random number generator of the latency and the failure rate
+	rng *rand.Rand
+	// seed is the seed of rng
+	seed int64
+	// reset fails the request with ErrConnectionReset before any byte of the response is sent
+	reset bool
+	// resetAfter fails the body with ErrConnectionReset after the given number of bytes. -1 disables the fault.
+	resetAfter int
+	// truncateAfter fails the body with io.ErrUnexpectedEOF after the given number of bytes. -1 disables the fault.
+	truncateAfter int
+	// slowChunkSize is the number of bytes the body sends at a time. 0 disables the fault.
+	slowChunkSize int
+	// slowInterval is the delay before each chunk of a slow body
+	slowInterval time.Duration
+	// latency is the distribution of the random delay of the response
+	latency Latency
+	// contentLength is the value of the Content-Length header. Empty disables the fault.
+	contentLength string
+	// failureRate is the fraction of the responses the faults are injected into. 0 injects them into every response.
+	failureRate float64
+}
+
+// newMockFaults returns faults that inject nothing
+func newMockFaults() *mockFaults {
+	return &mockFaults{seed: defaultFaultSeed, resetAfter: -1, truncateAfter: -1}
+}
+
+// faults returns the faults of the mock response, creating them if needed.
+func (r *MockResponse) faults() *mockFaults {
+	if r.fault == nil {
+		r.fault = newMockFaults()
+	}
+	return r.fault
+}
+
+// ConnectionReset fails the request with ErrConnectionReset, as if the connection was reset before the response was sent
+func (r *MockResponse) ConnectionReset() *MockResponse {
+	r.faults().reset = true
+	return r
+}
+
+// ResetAfterBytes sends the given number of bytes of the body, then fails the read with ErrConnectionReset
+func (r *MockResponse) ResetAfterBytes(n int) *MockResponse {
+	r.faults().resetAfter = n
+	return r
+}
+
+// TruncateAfterBytes sends the given number of bytes of the body, then fails the read with io.ErrUnexpectedEOF,
+// as if the connection was closed before the end of the body
+func (r *MockResponse) TruncateAfterBytes(n int) *MockResponse {
+	r.faults().truncateAfter = n
+	return r
+}
+
+// SlowBody sends the body chunkSize bytes at a time, waiting for the interval before each chunk.
+// Use a chunk size of 1 to send the body byte by byte.
+func (r *MockResponse) SlowBody(chunkSize int, interval time.Duration) *MockResponse {
+	if chunkSize < 1 {
+		chunkSize = 1
+	}
+	r.faults().slowChunkSize = chunkSize
+	r.faults().slowInterval = interval
+	return r
+}
+
+// RandomDelay will return the response after a random delay drawn from the latency distribution,
+// e.g. RandomDelay(spectest.UniformLatency(10*time.Millisecond, 50*time.Millisecond)).
+// SpecTest::EnableMockResponseDelay must be set for this to take effect.
+func (r *MockResponse) RandomDelay(latency Latency) *MockResponse {
+	r.faults().latency = latency
+	return r
+}
+
+// MalformedContentLength sets the Content-Length header of the response to the given value,
+// e.g. a length that does not match the body or a value that is not a number
+func (r *MockResponse) MalformedContentLength(value string) *MockResponse {
+	r.faults().contentLength = value
+	return r
+}
+
+// FailureRate injects the faults of the mock into the given fraction of the responses only, e.g. 0.25.
+// If the mock has no other fault, the failed requests return ErrConnectionReset.
+// The failures are drawn from a random number generator seeded by FaultSeed, so the runs are reproducible.
+func (r *MockResponse) FailureRate(rate float64) *MockResponse {
+	r.faults().failureRate = rate
+	return r
+}
+
+// FaultSeed sets the seed of the random number generator of RandomDelay and FailureRate. The default seed is 1.
+func (r *MockResponse) FaultSeed(seed int64) *MockResponse {
+	r.faults().seed = seed
+	r.faults().rng = nil
+	return r
+}
+
+// injectedFault is the fault injected into a mock response
+type injectedFault struct {
+	// faults is the configuration of the faults of the mock
+	faults *mockFaults
+	// delay is the random delay of the response
+	delay time.Duration
+	// failed is true if the faults are injected into the response
+	failed bool
+}
+
+// inject decides the faults injected into the next response of the mock. The random delay is only
+// injected if the delays are enabled. It returns nil if the mock has no faults or if the failure rate spares the response.
+func (f *mockFaults) inject(delayEnabled bool) *injectedFault {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.rng == nil {
+		f.rng = rand.New(rand.NewSource(f.seed)) //nolint:gosec // faults do not need a secure random number generator
+	}
+
+	injected := &injectedFault{faults: f, failed: true}
+	if f.failureRate > 0 && f.rng.Float64() >= f.failureRate {
+		injected.failed = false
+	}
+	if f.latency != nil && delayEnabled {
+		injected.delay = f.latency(f.rng)
+	}
+	if injected.String() == "" {
+		return nil
+	}
+	return injected
+}
+
+// reset returns true if the request fails before any byte of the response is sent.
+func (i *injectedFault) reset() bool {
+	if i == nil || !i.failed {
+		return false
+	}
+	f := i.faults
+	return f.reset || (f.failureRate > 0 && f.resetAfter < 0 && f.truncateAfter < 0 &&
+		f.slowChunkSize == 0 && f.contentLength == "")
+}
+
+// String describes the injected fault, e.g. "connection reset after 10 bytes, latency 20ms"
+func (i *injectedFault) String() string {
+	if i == nil {
+		return ""
+	}
+	var faults []string
+	if i.delay > 0 {
+		faults = append(faults, fmt.Sprintf("latency %s", i.delay))
+	}
+	if i.failed {
+		f := i.faults
+		switch {
+		case i.reset():
+			faults = append(faults, "connection reset")
+		case f.resetAfter >= 0:
+			faults = append(faults, fmt.Sprintf("connection reset after %d bytes", f.resetAfter))
+		case f.truncateAfter >= 0:
+			faults = append(faults, fmt.Sprintf("body truncated after %d bytes", f.truncateAfter))
+		}
+		if f.slowChunkSize > 0 {
+			faults = append(faults, fmt.Sprintf("slow body of %d bytes every %s", f.slowChunkSize, f.slowInterval))
+		}
+		if f.contentLength != "" {
+			faults = append(faults, fmt.Sprintf("Content-Length %s", f.contentLength))
+		}
+	}
+	return strings.Join(faults, ", ")
+}
+
+// wait waits for the random delay of the response, or until the context is done.
+func (i *injectedFault) wait(ctx context.Context) error {
+	if i == nil || i.delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(i.delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// apply injects the faults into the response. It is called after the observers have seen the response,
+// so that they record the body that the mock meant to send.
+func (i *injectedFault) apply(res *http.Response) *http.Response {
+	if i == nil || !i.failed || res == nil {
+		return res
+	}
+	f := i.faults
+	if f.contentLength != "" {
+		res.Header.Set("Content-Length", f.contentLength)
+		if n, err := strconv.ParseInt(f.contentLength, 10, 64); err == nil {
+			res.ContentLength = n
+		} else {
+			res.ContentLength = -1
+		}
+	}
+
+	body := &faultBody{body: res.Body, limit: -1, chunkSize: f.slowChunkSize, interval: f.slowInterval}
+	if res.Request != nil {
+		body.ctx = res.Request.Context()
+	}
+	switch {
+	case f.resetAfter >= 0:
+		body.limit, body.err = f.resetAfter, ErrConnectionReset
+	case f.truncateAfter >= 0:
+		body.limit, body.err = f.truncateAfter, io.ErrUnexpectedEOF
+	}
+	if body.limit >= 0 || body.chunkSize > 0 {
+		res.Body = body
+	}
+	return res
+}
+
+// withFault returns the request with the description of the injected fault,
+// so that the debug output and the report show it.
+func withFault(req *http.Request, fault *injectedFault) *http.Request {
+	if fault == nil {
+		return req
+	}
+	return req.WithContext(context.WithValue(req.Context(), faultContextKey{}, fault.String()))
+}
+
+// faultOf returns the description of the fault injected into the response to the request.
+func faultOf(req *http.Request) string {
+	if req == nil {
+		return ""
+	}
+	fault, _ := req.Context().Value(faultContextKey{}).(string)
+	return fault
+}
+
+// faultBody is a response body that fails after a number of bytes, or that is sent slowly
+type faultBody struct {
+	// body is the original body
+	body io.ReadCloser
+	// ctx is the context of the request. The slow body stops when it is done.
+	ctx context.Context
+	// read is the number of bytes read
+	read int
+	// limit is the number of bytes sent before err is returned. -1 disables the limit.
+	limit int
+	// err is the error returned after limit bytes
+	err error
+	// chunkSize is the number of bytes sent at a time. 0 sends the body as fast as it is read.
+	chunkSize int
+	// interval is the delay before each chunk
+	interval time.Duration
+}
+
+// Read reads the body, injecting the faults
+func (b *faultBody) Read(p []byte) (int, error) {
+	if b.limit >= 0 && b.read >= b.limit {
+		return 0, b.err
+	}
+	if b.limit >= 0 && len(p) > b.limit-b.read {
+		p = p[:b.limit-b.read]
+	}
+	if b.chunkSize > 0 {
+		if len(p) > b.chunkSize {
+			p = p[:b.chunkSize]
+		}
+		if err := b.sleep(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := b.body.Read(p)
+	b.read += n
+	return n, err
+}
+
+// sleep waits for the interval of the slow body, or until the context is done.
+func (b *faultBody) sleep() error {
+	if b.ctx == nil {
+		time.Sleep(b.interval)
+		return nil
+	}
+	timer := time.NewTimer(b.interval)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-b.ctx.Done():
+		return b.ctx.Err()
+	}
+}
+
+// Close closes the original body
+func (b *faultBody) Close() error {
+	return b.body.Close()
+}
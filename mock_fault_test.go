@@ -0,0 +1,190 @@
+package spectest_test
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nao1215/spectest"
+)
+
+func newFaultMock() *spectest.MockResponse {
+	return spectest.NewMock().
+		Get("http://faults.example.com/data").
+		RespondWith().
+		Status(http.StatusOK).
+		Body("abcdefghij")
+}
+
+func TestMockResponseConnectionReset(t *testing.T) {
+	defer newFaultMock().ConnectionReset().EndStandalone()()
+
+	_, err := http.Get("http://faults.example.com/data")
+
+	spectest.DefaultVerifier{}.True(t, errors.Is(err, syscall.ECONNRESET))
+}
+
+func TestMockResponseBodyFaults(t *testing.T) {
+	tests := map[string]struct {
+		mock          *spectest.MockResponse
+		expectedBody  string
+		expectedError error
+	}{
+		"reset after bytes": {
+			mock:          newFaultMock().ResetAfterBytes(3),
+			expectedBody:  "abc",
+			expectedError: spectest.ErrConnectionReset,
+		},
+		"truncate after bytes": {
+			mock:          newFaultMock().TruncateAfterBytes(4),
+			expectedBody:  "abcd",
+			expectedError: io.ErrUnexpectedEOF,
+		},
+		"slow body": {
+			mock:         newFaultMock().SlowBody(3, time.Millisecond),
+			expectedBody: "abcdefghij",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer test.mock.EndStandalone()()
+
+			res, err := http.Get("http://faults.example.com/data")
+			spectest.DefaultVerifier{}.NoError(t, err)
+			body, err := io.ReadAll(res.Body)
+
+			spectest.DefaultVerifier{}.Equal(t, test.expectedBody, string(body))
+			spectest.DefaultVerifier{}.Equal(t, test.expectedError, err)
+		})
+	}
+}
+
+func TestMockResponseSlowBodyIsSentInChunks(t *testing.T) {
+	defer newFaultMock().SlowBody(1, 5*time.Millisecond).EndStandalone()()
+
+	res, err := http.Get("http://faults.example.com/data")
+	spectest.DefaultVerifier{}.NoError(t, err)
+	buf := make([]byte, 8)
+	start := time.Now()
+	n, err := res.Body.Read(buf)
+
+	spectest.DefaultVerifier{}.NoError(t, err)
+	spectest.DefaultVerifier{}.Equal(t, 1, n)
+	spectest.DefaultVerifier{}.True(t, time.Since(start) >= 5*time.Millisecond)
+}
+
+func TestMockResponseMalformedContentLength(t *testing.T) {
+	defer newFaultMock().MalformedContentLength("abc").EndStandalone()()
+
+	res, err := http.Get("http://faults.example.com/data")
+
+	spectest.DefaultVerifier{}.NoError(t, err)
+	spectest.DefaultVerifier{}.Equal(t, "abc", res.Header.Get("Content-Length"))
+	spectest.DefaultVerifier{}.Equal(t, int64(-1), res.ContentLength)
+}
+
+func TestMockResponseFailureRateIsReproducible(t *testing.T) {
+	run := func() []bool {
+		var failures []bool
+		spectest.New().
+			Mocks(newFaultMock().FailureRate(0.5).FaultSeed(42).Times(20).End()).
+			HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				for i := 0; i < 20; i++ {
+					res, err := http.Get("http://faults.example.com/data")
+					if err == nil {
+						_ = res.Body.Close()
+					}
+					failures = append(failures, errors.Is(err, syscall.ECONNRESET))
+				}
+				w.WriteHeader(http.StatusOK)
+			}).
+			Get("/").
+			Expect(t).
+			Status(http.StatusOK).
+			End()
+		return failures
+	}
+
+	first, second := run(), run()
+
+	spectest.DefaultVerifier{}.Equal(t, first, second)
+	var failed int
+	for _, f := range first {
+		if f {
+			failed++
+		}
+	}
+	spectest.DefaultVerifier{}.True(t, failed > 0 && failed < 20)
+}
+
+func TestMockResponseRandomDelay(t *testing.T) {
+	spectest.New().
+		EnableMockResponseDelay().
+		Mocks(newFaultMock().RandomDelay(spectest.UniformLatency(20*time.Millisecond, 30*time.Millisecond)).End()).
+		HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			start := time.Now()
+			res, err := http.Get("http://faults.example.com/data")
+			if err != nil {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			_ = res.Body.Close()
+			if time.Since(start) < 20*time.Millisecond {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}).
+		Get("/").
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+}
+
+func TestMockResponseLatencyDistributions(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 100; i++ {
+		uniform := spectest.UniformLatency(10*time.Millisecond, 20*time.Millisecond)(rng)
+		spectest.DefaultVerifier{}.True(t, uniform >= 10*time.Millisecond && uniform <= 20*time.Millisecond)
+		spectest.DefaultVerifier{}.True(t, spectest.NormalLatency(time.Millisecond, 10*time.Millisecond)(rng) >= 0)
+		spectest.DefaultVerifier{}.True(t, spectest.ExponentialLatency(time.Millisecond)(rng) >= 0)
+	}
+}
+
+func TestMockResponseFaultIsRecordedInReport(t *testing.T) {
+	reporter := &RecorderCaptor{}
+
+	spectest.New().
+		Report(reporter).
+		Mocks(newFaultMock().TruncateAfterBytes(2).End()).
+		HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			res, err := http.Get("http://faults.example.com/data")
+			if err != nil {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			_, err = io.ReadAll(res.Body)
+			spectest.DefaultVerifier{}.Equal(t, io.ErrUnexpectedEOF, err)
+			w.WriteHeader(http.StatusOK)
+		}).
+		Get("/").
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+
+	events := reporter.capturedRecorder.Events
+	spectest.DefaultVerifier{}.Equal(t, 5, len(events))
+	spectest.DefaultVerifier{}.Equal(t, spectest.MessageResponse{
+		Source:    "faults.example.com",
+		Target:    spectest.SystemUnderTestDefaultName,
+		Header:    "fault",
+		Body:      "body truncated after 2 bytes",
+		Timestamp: events[3].GetTime(),
+	}, events[3])
+}
@@ -0,0 +1,515 @@
+package spectest
+
+import (
+	"bytes"
+	_ "embed" // embed the schema of the mock files
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mockFileSchema is the JSON Schema of the mock files
+//
+//go:embed schema/mocks.schema.json
+var mockFileSchema []byte
+
+// MockFileSchema returns the JSON Schema of the files loaded by MocksFromFile.
+// Editors can use it to validate and complete the mock files.
+func MockFileSchema() []byte {
+	return append([]byte{}, mockFileSchema...)
+}
+
+// MockFileError is an error in a mock file
+type MockFileError struct {
+	// File is the path of the mock file
+	File string
+	// Line is the line of the error. It is zero if the line is unknown.
+	Line int
+	// Column is the column of the error. It is zero if the column is unknown.
+	Column int
+	// Message describes the error
+	Message string
+}
+
+// Error returns the error in the file:line:column: message format
+func (e *MockFileError) Error() string {
+	switch {
+	case e.Line > 0 && e.Column > 0:
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+	case e.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+	default:
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+	}
+}
+
+// mockFile is the content of a mock file
+type mockFile struct {
+	// Mocks is the list of mock definitions
+	Mocks []mockDefinition `yaml:"mocks"`
+}
+
+// mockDefinition is the definition of a mock in a mock file
+type mockDefinition struct {
+	// Request is the request the mock matches
+	Request *mockRequestDefinition `yaml:"request"`
+	// Response is the response of the mock
+	Response *mockResponseDefinition `yaml:"response"`
+}
+
+// mockRequestDefinition is the request side of a mock definition. It mirrors MockRequest.
+type mockRequestDefinition struct {
+	Method             string                `yaml:"method"`
+	URL                string                `yaml:"url"`
+	URLMatch           string                `yaml:"url_match"`
+	PathRegexp         string                `yaml:"path_regexp"`
+	HostRegexp         string                `yaml:"host_regexp"`
+	Headers            map[string]stringList `yaml:"headers"`
+	HeaderPresent      []string              `yaml:"header_present"`
+	HeaderNotPresent   []string              `yaml:"header_not_present"`
+	Query              map[string]stringList `yaml:"query"`
+	QueryPresent       []string              `yaml:"query_present"`
+	QueryNotPresent    []string              `yaml:"query_not_present"`
+	FormData           map[string]stringList `yaml:"form_data"`
+	FormDataPresent    []string              `yaml:"form_data_present"`
+	FormDataNotPresent []string              `yaml:"form_data_not_present"`
+	Cookies            map[string]string     `yaml:"cookies"`
+	CookiePresent      []string              `yaml:"cookie_present"`
+	CookieNotPresent   []string              `yaml:"cookie_not_present"`
+	Body               yaml.Node             `yaml:"body"`
+	BodyFile           string                `yaml:"body_file"`
+	BodyRegexp         string                `yaml:"body_regexp"`
+	JSONSubset         bool                  `yaml:"json_subset"`
+	IgnoreJSONPaths    []string              `yaml:"ignore_json_paths"`
+	UnorderedJSON      bool                  `yaml:"unordered_json_arrays"`
+	BasicAuth          *struct {
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+	} `yaml:"basic_auth"`
+	Scenario      string `yaml:"scenario"`
+	ScenarioState string `yaml:"scenario_state"`
+}
+
+// mockResponseDefinition is the response side of a mock definition. It mirrors MockResponse.
+type mockResponseDefinition struct {
+	Status           *int                   `yaml:"status"`
+	Headers          map[string]stringList  `yaml:"headers"`
+	Cookies          []mockCookieDefinition `yaml:"cookies"`
+	Body             yaml.Node              `yaml:"body"`
+	BodyFile         string                 `yaml:"body_file"`
+	BodyTemplate     string                 `yaml:"body_template"`
+	Delay            int64                  `yaml:"delay"`
+	Times            *int                   `yaml:"times"`
+	Timeout          bool                   `yaml:"timeout"`
+	NewScenarioState string                 `yaml:"new_scenario_state"`
+}
+
+// mockCookieDefinition is a cookie of a mock response
+type mockCookieDefinition struct {
+	Name     string     `yaml:"name"`
+	Value    string     `yaml:"value"`
+	Path     string     `yaml:"path"`
+	Domain   string     `yaml:"domain"`
+	Expires  *time.Time `yaml:"expires"`
+	MaxAge   *int       `yaml:"max_age"`
+	Secure   *bool      `yaml:"secure"`
+	HTTPOnly *bool      `yaml:"http_only"`
+}
+
+// stringList is a list of strings that can be written as a single string in the mock file
+type stringList []string
+
+// UnmarshalYAML accepts a scalar or a sequence of scalars
+func (l *stringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = stringList{node.Value}
+		return nil
+	}
+	var values []string
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+	*l = values
+	return nil
+}
+
+// yamlErrorLine extracts the line from the errors of the yaml decoder, e.g. "line 3: field foo not found"
+var yamlErrorLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// MocksFromFile builds mocks from a YAML or JSON file, so that the mocks can be kept as data.
+// The format is described by MockFileSchema. The relative paths of body_file are resolved
+// from the directory of the mock file. The returned error lists every problem of the file with its line.
+//
+//	mocks:
+//	  - request:
+//	      method: POST
+//	      url: https://payments.example.com/charges
+//	      headers:
+//	        Authorization: Bearer .+
+//	      body: {"amount": 1000}
+//	    response:
+//	      status: 201
+//	      body_file: charge.json
+func MocksFromFile(path string) (Mocks, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	return parseMockFile(path, data)
+}
+
+// parseMockFile builds mocks from the content of a mock file.
+func parseMockFile(path string, data []byte) (Mocks, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, yamlErrors(path, err)
+	}
+
+	p := &mockFileParser{path: path, dir: filepath.Dir(path)}
+	definitions := lookup(document(&root), "mocks")
+	if definitions == nil {
+		p.fail(document(&root), "mocks is required")
+		return nil, p.err()
+	}
+
+	var file mockFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, yamlErrors(path, err)
+	}
+
+	mocks := make(Mocks, 0, len(file.Mocks))
+	for i, definition := range file.Mocks {
+		if mock := p.mock(definition, definitions.Content[i]); mock != nil {
+			mocks = append(mocks, mock)
+		}
+	}
+	if err := p.err(); err != nil {
+		return nil, err
+	}
+	return mocks, nil
+}
+
+// yamlErrors converts the errors of the yaml decoder to mock file errors.
+func yamlErrors(path string, err error) error {
+	var messages []string
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	} else {
+		messages = []string{err.Error()}
+	}
+
+	errs := make([]error, 0, len(messages))
+	for _, message := range messages {
+		fileErr := &MockFileError{File: path, Message: strings.TrimPrefix(message, "yaml: ")}
+		if m := yamlErrorLine.FindStringSubmatch(message); m != nil {
+			fileErr.Line, _ = strconv.Atoi(m[1])
+			fileErr.Message = m[2]
+		}
+		errs = append(errs, fileErr)
+	}
+	return errors.Join(errs...)
+}
+
+// mockFileParser builds mocks from the definitions of a mock file and collects the errors
+type mockFileParser struct {
+	// path is the path of the mock file
+	path string
+	// dir is the directory that body files are resolved from
+	dir string
+	// errs is the list of errors
+	errs []error
+}
+
+// fail records an error at the position of the node.
+func (p *mockFileParser) fail(node *yaml.Node, format string, args ...interface{}) {
+	p.errs = append(p.errs, &MockFileError{
+		File:    p.path,
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// err returns the recorded errors or nil.
+func (p *mockFileParser) err() error {
+	return errors.Join(p.errs...)
+}
+
+// mock builds a mock from a definition. The node is the mapping node of the definition.
+func (p *mockFileParser) mock(definition mockDefinition, node *yaml.Node) *Mock {
+	if definition.Request == nil {
+		p.fail(node, "request is required")
+		return nil
+	}
+	if definition.Response == nil {
+		p.fail(node, "response is required")
+		return nil
+	}
+
+	mock := NewMock()
+	p.request(mock.request, definition.Request, child(node, "request"))
+	p.response(mock.response, definition.Response, child(node, "response"))
+	return mock
+}
+
+// request configures the mock request from its definition.
+func (p *mockFileParser) request(req *MockRequest, def *mockRequestDefinition, node *yaml.Node) {
+	if def.URL == "" {
+		p.fail(node, "request url is required")
+	} else if u, err := url.Parse(def.URL); err != nil {
+		p.fail(child(node, "url"), "invalid request url: %s", err)
+	} else {
+		req.url = u
+	}
+	if def.Method != "" {
+		if !isHTTPMethod(def.Method) {
+			p.fail(child(node, "method"), "unknown request method %s", def.Method)
+		}
+		req.method = def.Method
+	}
+	if def.URLMatch != "" {
+		mode, ok := urlMatchNames[def.URLMatch]
+		if !ok {
+			p.fail(child(node, "url_match"), "unknown url_match %s", def.URLMatch)
+		}
+		req.URLMatch(mode)
+	}
+	if _, err := regexp.Compile(def.PathRegexp); err != nil {
+		p.fail(child(node, "path_regexp"), "invalid path_regexp: %s", err)
+	}
+	if _, err := regexp.Compile(def.HostRegexp); err != nil {
+		p.fail(child(node, "host_regexp"), "invalid host_regexp: %s", err)
+	}
+	req.PathRegexp(def.PathRegexp).HostRegexp(def.HostRegexp)
+
+	for key, values := range def.Headers {
+		p.regexps(child(child(node, "headers"), key), "header "+key, values)
+		for _, value := range values {
+			req.Header(key, value)
+		}
+	}
+	req.headerPresent = append(req.headerPresent, def.HeaderPresent...)
+	req.headerNotPresent = append(req.headerNotPresent, def.HeaderNotPresent...)
+
+	for key, values := range def.Query {
+		p.regexps(child(child(node, "query"), key), "query param "+key, values)
+		req.query[key] = append(req.query[key], values...)
+	}
+	req.queryPresent = append(req.queryPresent, def.QueryPresent...)
+	req.queryNotPresent = append(req.queryNotPresent, def.QueryNotPresent...)
+
+	for key, values := range def.FormData {
+		p.regexps(child(child(node, "form_data"), key), "form data "+key, values)
+		req.formData[key] = append(req.formData[key], values...)
+	}
+	req.formDataPresent = append(req.formDataPresent, def.FormDataPresent...)
+	req.formDataNotPresent = append(req.formDataNotPresent, def.FormDataNotPresent...)
+
+	for name, value := range def.Cookies {
+		req.Cookie(name, value)
+	}
+	req.cookiePresent = append(req.cookiePresent, def.CookiePresent...)
+	req.cookieNotPresent = append(req.cookieNotPresent, def.CookieNotPresent...)
+
+	if body, ok := p.body(node, &def.Body, def.BodyFile); ok {
+		req.body = body
+	}
+	if def.BodyRegexp != "" {
+		if _, err := regexp.Compile(def.BodyRegexp); err != nil {
+			p.fail(child(node, "body_regexp"), "invalid body_regexp: %s", err)
+		}
+		req.bodyRegexp = def.BodyRegexp
+	}
+	for _, path := range def.IgnoreJSONPaths {
+		if _, err := parseJSONPathPattern(path); err != nil {
+			p.fail(child(node, "ignore_json_paths"), "invalid ignore_json_paths: %s", err)
+		}
+	}
+	req.jsonMatch = jsonMatch{subset: def.JSONSubset, unordered: def.UnorderedJSON, ignored: def.IgnoreJSONPaths}
+	if def.BasicAuth != nil {
+		if def.BasicAuth.Username == "" || def.BasicAuth.Password == "" {
+			p.fail(child(node, "basic_auth"), "basic_auth requires a username and a password")
+		}
+		req.BasicAuth(def.BasicAuth.Username, def.BasicAuth.Password)
+	}
+	if def.ScenarioState != "" && def.Scenario == "" {
+		p.fail(child(node, "scenario_state"), "scenario_state requires a scenario")
+	}
+	req.InScenario(def.Scenario).WhenScenarioStateIs(def.ScenarioState)
+}
+
+// response configures the mock response from its definition.
+func (p *mockFileParser) response(res *MockResponse, def *mockResponseDefinition, node *yaml.Node) {
+	res.statusCode = http.StatusOK
+	if def.Status != nil {
+		if *def.Status < 100 || *def.Status > 599 {
+			p.fail(child(node, "status"), "invalid response status %d", *def.Status)
+		}
+		res.statusCode = *def.Status
+	}
+
+	for key, values := range def.Headers {
+		for _, value := range values {
+			res.Header(key, value)
+		}
+	}
+
+	cookies := child(node, "cookies")
+	for i, c := range def.Cookies {
+		if c.Name == "" {
+			p.fail(cookies.Content[i], "cookie name is required")
+			continue
+		}
+		cookie := NewCookie(c.Name).Value(c.Value)
+		if c.Path != "" {
+			cookie.Path(c.Path)
+		}
+		if c.Domain != "" {
+			cookie.Domain(c.Domain)
+		}
+		if c.Expires != nil {
+			cookie.Expires(*c.Expires)
+		}
+		if c.MaxAge != nil {
+			cookie.MaxAge(*c.MaxAge)
+		}
+		if c.Secure != nil {
+			cookie.Secure(*c.Secure)
+		}
+		if c.HTTPOnly != nil {
+			cookie.HTTPOnly(*c.HTTPOnly)
+		}
+		res.Cookies(cookie)
+	}
+
+	if body, ok := p.body(node, &def.Body, def.BodyFile); ok {
+		res.body = body
+	}
+	if def.BodyTemplate != "" {
+		if def.Body.Kind != 0 || def.BodyFile != "" {
+			p.fail(child(node, "body_template"), "body_template can not be used with body or body_file")
+		}
+		tmpl, err := template.New("body").Funcs(mockTemplateFuncs).Option("missingkey=zero").Parse(def.BodyTemplate)
+		if err != nil {
+			p.fail(child(node, "body_template"), "invalid body_template: %s", err)
+		}
+		res.bodyTemplate = tmpl
+	}
+	if def.Delay < 0 {
+		p.fail(child(node, "delay"), "delay must not be negative")
+	}
+	res.FixedDelay(def.Delay)
+	if def.Times != nil {
+		if *def.Times < 1 {
+			p.fail(child(node, "times"), "times must be greater than zero")
+		}
+		res.Times(*def.Times)
+	}
+	if def.Timeout {
+		res.Timeout()
+	}
+	res.WillSetScenarioState(def.NewScenarioState)
+}
+
+// body returns the body of a request or response definition.
+// A string body is used as is, and any other value is encoded as JSON.
+func (p *mockFileParser) body(node, body *yaml.Node, bodyFile string) (string, bool) {
+	if body.Kind != 0 && bodyFile != "" {
+		p.fail(child(node, "body_file"), "body and body_file are mutually exclusive")
+		return "", false
+	}
+
+	if bodyFile != "" {
+		path := bodyFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(p.dir, path)
+		}
+		b, err := os.ReadFile(filepath.Clean(path))
+		if err != nil {
+			p.fail(child(node, "body_file"), "failed to read body_file: %s", err)
+			return "", false
+		}
+		return string(b), true
+	}
+
+	switch {
+	case body.Kind == 0:
+		return "", false
+	case body.Kind == yaml.ScalarNode && body.Tag == "!!str":
+		return body.Value, true
+	}
+	var value interface{}
+	if err := body.Decode(&value); err != nil {
+		p.fail(body, "invalid body: %s", err)
+		return "", false
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		p.fail(body, "body can not be encoded as JSON: %s", err)
+		return "", false
+	}
+	return string(b), true
+}
+
+// regexps checks that the values of a header, query param or form data are valid regular expressions.
+func (p *mockFileParser) regexps(node *yaml.Node, name string, values []string) {
+	for _, value := range values {
+		if _, err := regexp.Compile(value); err != nil {
+			p.fail(node, "invalid regexp for %s: %s", name, err)
+		}
+	}
+}
+
+// document returns the top level node of the document node.
+func document(root *yaml.Node) *yaml.Node {
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		return root.Content[0]
+	}
+	return root
+}
+
+// lookup returns the value of the key of the mapping node, or nil if the key is not found.
+func lookup(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// child returns the value of the key of the mapping node. If the key is not found, the mapping node is returned
+// so that errors are reported at the closest known position.
+func child(node *yaml.Node, key string) *yaml.Node {
+	if value := lookup(node, key); value != nil {
+		return value
+	}
+	return node
+}
+
+// isHTTPMethod returns true if the method is a known HTTP method.
+func isHTTPMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+package spectest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMocksFromFile(t *testing.T) {
+	mocks, err := MocksFromFile("testdata/mocks/payments.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(mocks))
+
+	charge := mocks[0]
+	assert.Equal(t, http.MethodPost, charge.request.method)
+	assert.Equal(t, "https://payments.example.com/charges", charge.request.url.String())
+	assert.Equal(t, map[string][]string{"X-Api-Key": {"key_.+"}}, charge.request.headers)
+	assert.Equal(t, []string{"X-Debug"}, charge.request.headerNotPresent)
+	assert.Equal(t, map[string][]string{"currency": {"jpy", "usd"}}, charge.request.query)
+	assert.Equal(t, `{"amount":1000,"created_at":"\u003c\u003crfc3339\u003e\u003e"}`, charge.request.body)
+	assert.Equal(t, jsonMatch{subset: true, ignored: []string{"$.metadata"}}, charge.request.jsonMatch)
+	assert.Equal(t, http.StatusCreated, charge.response.statusCode)
+	assert.Equal(t, "{\"id\": \"ch_1\", \"amount\": 1000}\n", charge.response.body)
+	assert.Equal(t, 2, charge.execCount.min)
+	assert.Equal(t, 2, charge.execCount.max)
+	assert.Equal(t, 1, len(charge.response.cookies))
+	assert.Equal(t, "/", *charge.response.cookies[0].path)
+
+	req := httptest.NewRequest(http.MethodPost, "https://payments.example.com/charges?currency=jpy&currency=usd", strings.NewReader(`{"amount": 1000, "created_at": "2024-01-02T15:04:05Z", "metadata": {"order": 1}, "currency": "jpy"}`))
+	req.Header.Set("X-Api-Key", "key_123")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	req.SetBasicAuth("shop", "s3cr3t")
+	assert.Equal(t, []error(nil), charge.Matches(req))
+
+	refund := mocks[1]
+	assert.Equal(t, http.MethodPost, refund.request.method)
+	assert.Equal(t, "reason=duplicate", refund.request.bodyRegexp)
+	assert.Equal(t, "accepted", refund.response.body)
+	assert.Equal(t, int64(10), refund.response.fixedDelayMillis)
+	assert.Equal(t, "refund", refund.request.scenario)
+	assert.Equal(t, MockScenarioStarted, refund.request.scenarioState)
+	assert.Equal(t, "refunded", refund.response.scenarioState)
+
+	form := url.Values{"charge": {"ch_1"}, "reason": {"duplicate"}}
+	req = httptest.NewRequest(http.MethodPost, "https://payments.example.com/refunds", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	assert.Equal(t, 0, len(refund.Matches(req)))
+
+	health := mocks[2]
+	assert.Equal(t, "", health.request.method)
+	assert.Equal(t, http.StatusOK, health.response.statusCode)
+	assert.True(t, health.response.timeout)
+
+	getCharge := mocks[3]
+	assert.Equal(t, URLMatchGlob, getCharge.request.urlMatch)
+	req = httptest.NewRequest(http.MethodGet, "https://eu.payments.example.com/charges/ch_1", nil)
+	assert.Equal(t, 0, len(getCharge.Matches(req)))
+	rendered, err := getCharge.response.render(req)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id": "ch_1"}`, rendered.body)
+}
+
+func TestMocksFromFileJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mocks.json")
+	content := `{"mocks": [
+		{"request": {"method": "GET", "url": "http://example.com/users"}, "response": {"body": [{"id": 1}]}},
+		{"request": {"method": "GET", "url": "http://example.com/users/1"}, "response": {"body_template": "{{.Path}}"}}
+	]}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	mocks, err := MocksFromFile(path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"id":1}]`, mocks[0].response.body)
+	rendered, err := mocks[1].response.render(httptest.NewRequest(http.MethodGet, "http://example.com/users/1", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "/users/1", rendered.body)
+}
+
+func TestMocksFromFileErrors(t *testing.T) {
+	tests := map[string]struct {
+		content  string
+		expected string
+	}{
+		"missing mocks": {
+			content:  "foo: bar\n",
+			expected: "mocks.yaml:1:1: mocks is required",
+		},
+		"unknown field": {
+			content: `mocks:
+  - request:
+      url: http://example.com
+      heders:
+        Accept: application/json
+    response:
+      status: 200
+`,
+			expected: "mocks.yaml:4: field heders not found in type spectest.mockRequestDefinition",
+		},
+		"invalid type": {
+			content: `mocks:
+  - request:
+      url: http://example.com
+    response:
+      status: ok
+`,
+			expected: "mocks.yaml:5: cannot unmarshal !!str `ok` into int",
+		},
+		"syntax error": {
+			content:  "mocks:\n  - request: [\n",
+			expected: "mocks.yaml:2: did not find expected node content",
+		},
+		"invalid ignored json path": {
+			content: `mocks:
+  - request:
+      url: http://example.com
+      ignore_json_paths: [id]
+    response:
+      status: 200
+`,
+			expected: "mocks.yaml:4:26: invalid ignore_json_paths: invalid JSONPath id: it must start with $",
+		},
+		"semantic errors": {
+			content: `mocks:
+  - request:
+      method: FETCH
+      url: http://example.com
+      headers:
+        Accept: "[a-"
+      body: a
+      body_file: body.json
+    response:
+      status: 1000
+      times: 0
+  - response:
+      status: 200
+`,
+			expected: strings.Join([]string{
+				"mocks.yaml:3:15: unknown request method FETCH",
+				"mocks.yaml:6:17: invalid regexp for header Accept: error parsing regexp: missing closing ]: `[a-`",
+				"mocks.yaml:8:18: body and body_file are mutually exclusive",
+				"mocks.yaml:10:15: invalid response status 1000",
+				"mocks.yaml:11:14: times must be greater than zero",
+				"mocks.yaml:12:5: request is required",
+			}, "\n"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "mocks.yaml")
+			if err := os.WriteFile(path, []byte(test.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := MocksFromFile(path)
+
+			assert.Equal(t, test.expected, strings.ReplaceAll(err.Error(), filepath.Dir(path)+string(filepath.Separator), ""))
+		})
+	}
+}
+
+func TestMockFileSchemaValidatesMockFile(t *testing.T) {
+	b, err := os.ReadFile("testdata/mocks/payments.yaml")
+	assert.NoError(t, err)
+	var document interface{}
+	assert.NoError(t, yaml.Unmarshal(b, &document))
+	content, err := json.Marshal(document)
+	assert.NoError(t, err)
+
+	result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(MockFileSchema()), gojsonschema.NewBytesLoader(content))
+	assert.NoError(t, err)
+	assert.True(t, result.Valid(), result.Errors())
+
+	result, err = gojsonschema.Validate(gojsonschema.NewBytesLoader(MockFileSchema()),
+		gojsonschema.NewStringLoader(`{"mocks": [{"request": {"url": "http://example.com", "heders": {}}, "response": {}}]}`))
+	assert.NoError(t, err)
+	assert.True(t, !result.Valid())
+}
@@ -0,0 +1,70 @@
+package spectest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// describeClosestMock describes the mock that is the closest to the request that did not match any mocks,
+// with a diff of each field of the request that did not match the mock, e.g.
+//
+//	Closest mock is mock 2 (GET http://example.com/users), 19 of 20 matchers passed
+//	path:
+//	--- Expected
+//	+++ Actual
+//	@@ -1 +1 @@
+//	-/users
+//	+/user
+func describeClosestMock(req *http.Request, mockNumber int, mock *Mock, rank mockRank) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Closest mock is mock %d (%s), %d of %d matchers passed\n",
+		mockNumber, mock, rank.passed, rank.total))
+
+	spec := mock.request
+	if methodMatcher(req, spec) != nil {
+		writeFieldDiff(&b, "method", spec.method, req.Method)
+	}
+	if hostMatcher(req, spec) != nil {
+		host := req.Host
+		if host == "" {
+			host = req.URL.Host
+		}
+		writeFieldDiff(&b, "host", spec.mockHost(), host)
+	}
+	if pathMatcher(req, spec) != nil {
+		writeFieldDiff(&b, "path", spec.mockPath(), req.URL.Path)
+	}
+	if queryParamMatcher(req, spec) != nil {
+		writeFieldDiff(&b, "query", spec.query, map[string][]string(req.URL.Query()))
+	}
+	if headerMatcher(req, spec) != nil {
+		received := map[string][]string{}
+		for key := range spec.headers {
+			if values, ok := req.Header[key]; ok {
+				received[key] = values
+			}
+		}
+		writeFieldDiff(&b, "header", spec.headers, received)
+	}
+	if bodyMatcher(req, spec) != nil && req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		if err == nil {
+			req.Body = io.NopCloser(bytes.NewReader(body))
+			b.WriteString("body:\n" + bodyDiff(spec.body, string(body)))
+		}
+	}
+	return b.String()
+}
+
+// writeFieldDiff writes the diff between the expected and the actual value of a field of the request
+func writeFieldDiff(b *strings.Builder, field string, expected, actual interface{}) {
+	d := strings.TrimPrefix(diff(expected, actual), "\n\nDiff:\n")
+	if d == "" {
+		return
+	}
+	b.WriteString(field + ":\n")
+	b.WriteString(d)
+}
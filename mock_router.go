@@ -0,0 +1,157 @@
+package spectest
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// mockRouteKey is the context key used to bind the inbound request to the mocks of a SpecTest.
+type mockRouteKey struct{}
+
+// mockRouteID is the last issued route id. A route id of 0 means that mocks are not isolated.
+var mockRouteID uint64
+
+// nextMockRouteID returns a new unique route id.
+func nextMockRouteID() uint64 {
+	return atomic.AddUint64(&mockRouteID, 1)
+}
+
+// withMockRoute returns a copy of the context that carries the given route id.
+func withMockRoute(ctx context.Context, id uint64) context.Context {
+	return context.WithValue(ctx, mockRouteKey{}, id)
+}
+
+// mockRouteFromContext returns the route id carried by the context.
+func mockRouteFromContext(ctx context.Context) (uint64, bool) {
+	id, ok := ctx.Value(mockRouteKey{}).(uint64)
+	return id, ok
+}
+
+// mockRouter is a http.RoundTripper that sends each outbound request to the mocks
+// of the SpecTest that started it. The SpecTest is identified by the route id
+// stored in the request context, so the system under test must propagate the
+// context of the inbound request to its outbound requests.
+// Requests without a known route id are sent to the native transport.
+//
+// The router replaces http.DefaultTransport (or the transport of the client given by HTTPClient)
+// only while at least one isolated SpecTest is running: it is installed when the first route is
+// registered, and the native transport is restored when the last route is unregistered.
+type mockRouter struct {
+	// mu guards routes
+	mu sync.RWMutex
+	// routes is the map of route id to transport
+	routes map[uint64]*Transport
+	// native is the http.RoundTripper that was replaced by the router. It is nil if the
+	// replaced transport of a http.Client was nil, i.e. http.DefaultTransport.
+	native http.RoundTripper
+}
+
+var (
+	// mockRoutersMu guards defaultMockRouter and clientMockRouters
+	mockRoutersMu sync.Mutex
+	// defaultMockRouter is the router installed as http.DefaultTransport, or nil if no route is registered
+	defaultMockRouter *mockRouter
+	// clientMockRouters is the list of routers installed as the transport of a http.Client
+	clientMockRouters = map[*http.Client]*mockRouter{}
+)
+
+// registerMockRoute binds the route id to the transport in the router of the given http client.
+// If client is nil, the router of http.DefaultTransport is used. The router is installed if it
+// is the first route of the client.
+func registerMockRoute(client *http.Client, id uint64, transport *Transport) *mockRouter {
+	mockRoutersMu.Lock()
+	defer mockRoutersMu.Unlock()
+
+	var router *mockRouter
+	switch {
+	case client == nil && defaultMockRouter != nil:
+		router = defaultMockRouter
+	case client == nil:
+		router = newMockRouter(http.DefaultTransport)
+		http.DefaultTransport = router
+		defaultMockRouter = router
+	default:
+		var ok bool
+		if router, ok = clientMockRouters[client]; !ok {
+			router = newMockRouter(client.Transport)
+			client.Transport = router
+			clientMockRouters[client] = router
+		}
+	}
+	router.register(id, transport)
+	return router
+}
+
+// unregisterMockRoute removes the route id from the router of the given http client.
+// The native transport is restored when the last route is removed, unless the transport
+// was replaced again in the meantime.
+func unregisterMockRoute(client *http.Client, id uint64) {
+	mockRoutersMu.Lock()
+	defer mockRoutersMu.Unlock()
+
+	router := defaultMockRouter
+	if client != nil {
+		router = clientMockRouters[client]
+	}
+	if router == nil || router.unregister(id) > 0 {
+		return
+	}
+
+	if client == nil {
+		if http.DefaultTransport == router {
+			http.DefaultTransport = router.native
+		}
+		defaultMockRouter = nil
+		return
+	}
+	if client.Transport == router {
+		client.Transport = router.native
+	}
+	delete(clientMockRouters, client)
+}
+
+// newMockRouter creates a new router that falls back to the given transport.
+func newMockRouter(native http.RoundTripper) *mockRouter {
+	return &mockRouter{
+		routes: map[uint64]*Transport{},
+		native: native,
+	}
+}
+
+// register binds the route id to the transport.
+func (m *mockRouter) register(id uint64, transport *Transport) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.routes[id] = transport
+}
+
+// unregister removes the route id and returns the number of remaining routes.
+func (m *mockRouter) unregister(id uint64) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.routes, id)
+	return len(m.routes)
+}
+
+// upstream returns the transport replaced by the router.
+func (m *mockRouter) upstream() http.RoundTripper {
+	if m.native == nil {
+		return http.DefaultTransport
+	}
+	return m.native
+}
+
+// RoundTrip sends the request to the transport bound to the request context.
+func (m *mockRouter) RoundTrip(req *http.Request) (*http.Response, error) {
+	if id, ok := mockRouteFromContext(req.Context()); ok {
+		m.mu.RLock()
+		transport, found := m.routes[id]
+		m.mu.RUnlock()
+		if found {
+			return transport.RoundTrip(req)
+		}
+	}
+	return m.upstream().RoundTrip(req)
+}
@@ -0,0 +1,146 @@
+package spectest_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/nao1215/spectest"
+)
+
+// contextPropagatingHandler calls the downstream service with the context of the inbound request.
+func contextPropagatingHandler(client *http.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, "http://localhost:8080/user", nil)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer res.Body.Close() //nolint
+		body, _ := io.ReadAll(res.Body)
+		w.WriteHeader(res.StatusCode)
+		_, _ = w.Write(body)
+	}
+}
+
+func TestIsolateMocksRunsInParallel(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		i := i
+		t.Run(fmt.Sprintf("spec %d", i), func(t *testing.T) {
+			t.Parallel()
+
+			userMock := spectest.NewMock().
+				Get("http://localhost:8080/user").
+				RespondWith().
+				Status(http.StatusOK).
+				Bodyf(`{"id": %d}`, i).
+				End()
+
+			res := spectest.New().
+				IsolateMocks().
+				Mocks(userMock).
+				Handler(contextPropagatingHandler(http.DefaultClient)).
+				Get("/").
+				Expect(t).
+				Status(http.StatusOK).
+				Bodyf(`{"id": %d}`, i).
+				End()
+
+			spectest.DefaultVerifier{}.Equal(t, 0, len(res.UnmatchedMocks()))
+		})
+	}
+}
+
+func TestIsolateMocksWithHTTPClientRunsInParallel(t *testing.T) {
+	client := &http.Client{}
+	for i := 0; i < 10; i++ {
+		i := i
+		t.Run(fmt.Sprintf("spec %d", i), func(t *testing.T) {
+			t.Parallel()
+
+			userMock := spectest.NewMock().
+				Get("http://localhost:8080/user").
+				RespondWith().
+				Status(http.StatusCreated).
+				Bodyf(`{"id": %d}`, i).
+				End()
+
+			spectest.New().
+				IsolateMocks().
+				HTTPClient(client).
+				Mocks(userMock).
+				Handler(contextPropagatingHandler(client)).
+				Get("/").
+				Expect(t).
+				Status(http.StatusCreated).
+				Bodyf(`{"id": %d}`, i).
+				End()
+		})
+	}
+}
+
+func TestIsolateMocksDoesNotServeRequestsWithoutContext(t *testing.T) {
+	userMock := spectest.NewMock().
+		Get("http://localhost:8080/user").
+		RespondWith().
+		Status(http.StatusOK).
+		End()
+
+	res := spectest.New().
+		IsolateMocks().
+		Mocks(userMock).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}).
+		Get("/").
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+
+	spectest.DefaultVerifier{}.Equal(t, 1, len(res.UnmatchedMocks()))
+}
+
+func TestIsolateMocksRestoresTheNativeTransport(t *testing.T) {
+	defaultTransport := http.DefaultTransport
+	client := &http.Client{}
+
+	userMock := spectest.NewMock().
+		Get("http://localhost:8080/user").
+		RespondWith().
+		Status(http.StatusOK).
+		End()
+
+	spectest.New().
+		IsolateMocks().
+		Mocks(userMock).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, isNative := http.DefaultTransport.(*http.Transport)
+			spectest.DefaultVerifier{}.True(t, !isNative)
+			contextPropagatingHandler(http.DefaultClient)(w, r)
+		}).
+		Get("/").
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+
+	spectest.New().
+		IsolateMocks().
+		HTTPClient(client).
+		Mocks(userMock).
+		Handler(contextPropagatingHandler(client)).
+		Get("/").
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+
+	spectest.DefaultVerifier{}.Equal(t, defaultTransport, http.DefaultTransport)
+	_, isNative := http.DefaultTransport.(*http.Transport)
+	spectest.DefaultVerifier{}.True(t, isNative)
+	spectest.DefaultVerifier{}.Equal(t, nil, client.Transport)
+}
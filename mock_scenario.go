@@ -0,0 +1,115 @@
+package spectest
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// MockScenarioStarted is the state of a mock scenario before any mock has changed it
+const MockScenarioStarted = "Started"
+
+// mockScenarios holds the current state of the mock scenarios of a transport.
+// A mock scenario is a named state machine: a mock that is in a scenario only matches
+// when the scenario is in the required state, and can move the scenario to a new state when it matches.
+type mockScenarios struct {
+	// mu guards states
+	mu sync.Mutex
+	// states is the current state by scenario name
+	states map[string]string
+}
+
+// newMockScenarios creates mock scenarios where every scenario is in the MockScenarioStarted state
+func newMockScenarios() *mockScenarios {
+	return &mockScenarios{states: map[string]string{}}
+}
+
+// state returns the current state of the scenario.
+func (s *mockScenarios) state(name string) string {
+	if s == nil {
+		return MockScenarioStarted
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state, ok := s.states[name]; ok {
+		return state
+	}
+	return MockScenarioStarted
+}
+
+// transition moves the scenario of the matched mock to the new state of its response.
+func (s *mockScenarios) transition(mock *Mock) {
+	if s == nil || mock.request.scenario == "" || mock.response.scenarioState == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[mock.request.scenario] = mock.response.scenarioState
+}
+
+// InScenario configures the mock to be part of the named scenario.
+// Mocks in the same scenario share its state, see WhenScenarioStateIs and MockResponse.WillSetScenarioState.
+func (r *MockRequest) InScenario(name string) *MockRequest {
+	r.scenario = name
+	return r
+}
+
+// WhenScenarioStateIs configures the mock to match only when its scenario is in the given state.
+// Every scenario starts in the MockScenarioStarted state.
+func (r *MockRequest) WhenScenarioStateIs(state string) *MockRequest {
+	r.scenarioState = state
+	return r
+}
+
+// WillSetScenarioState moves the scenario of the mock to the given state when the mock matches
+func (r *MockResponse) WillSetScenarioState(state string) *MockResponse {
+	r.scenarioState = state
+	return r
+}
+
+// MockScenarioState asserts the state of the mock scenario after the test has run
+func (r *Response) MockScenarioState(name, state string) *Response {
+	r.scenarioStates = append(r.scenarioStates, mockScenarioState{name: name, state: state})
+	return r
+}
+
+// mockScenarioState is the expected state of a mock scenario
+type mockScenarioState struct {
+	name  string
+	state string
+}
+
+// MockScenarioState returns the state of the mock scenario at the end of the test
+func (r Result) MockScenarioState(name string) string {
+	return r.mockScenarios.state(name)
+}
+
+// scenarioStateMatcher matches when the scenario of the mock is in the state required by the mock.
+func scenarioStateMatcher(_ *http.Request, spec *MockRequest) error {
+	if spec.scenario == "" || spec.scenarioState == "" {
+		return nil
+	}
+	state := spec.mock.scenarios.state(spec.scenario)
+	return errorOrNil(state == spec.scenarioState, func() string {
+		return fmt.Sprintf("scenario %s is in state %s but mock requires state %s", spec.scenario, state, spec.scenarioState)
+	})
+}
+
+// mockScenarios returns the scenarios of the mocks of the last run, or nil if the test has no mocks.
+func (s *SpecTest) mockScenarios() *mockScenarios {
+	if s.transport == nil {
+		return nil
+	}
+	return s.transport.scenarios
+}
+
+// assertMockScenarioStates asserts the expected states of the mock scenarios.
+func (s *SpecTest) assertMockScenarioStates() {
+	scenarios := s.mockScenarios()
+	for _, expected := range s.response.scenarioStates {
+		actual := scenarios.state(expected.name)
+		s.check(FailureScenario, expected.name).Equal(s.t, expected.state, actual,
+			fmt.Sprintf("mock scenario %s is in state %s, expected %s", expected.name, actual, expected.state),
+			failureMessageArgs{Name: s.name})
+	}
+}
@@ -0,0 +1,115 @@
+package spectest_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/nao1215/spectest"
+	"github.com/nao1215/spectest/mocks"
+)
+
+// orderHandler polls the order status, confirms the order and polls the status again
+func orderHandler(w http.ResponseWriter, _ *http.Request) {
+	var statuses []string
+	for _, call := range []struct{ method, url string }{
+		{http.MethodGet, "http://orders.example.com/orders/1"},
+		{http.MethodPost, "http://orders.example.com/orders/1/confirm"},
+		{http.MethodGet, "http://orders.example.com/orders/1"},
+	} {
+		req, _ := http.NewRequest(call.method, call.url, nil)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, _ := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		statuses = append(statuses, string(body))
+	}
+	_, _ = fmt.Fprint(w, strings.Join(statuses, ","))
+}
+
+func orderScenarioMocks() []*spectest.Mock {
+	return []*spectest.Mock{
+		spectest.NewMock().
+			Get("http://orders.example.com/orders/1").
+			InScenario("order").
+			WhenScenarioStateIs("confirmed").
+			RespondWith().
+			Status(http.StatusOK).
+			Body("done").
+			End(),
+		spectest.NewMock().
+			Get("http://orders.example.com/orders/1").
+			InScenario("order").
+			WhenScenarioStateIs(spectest.MockScenarioStarted).
+			RespondWith().
+			Status(http.StatusOK).
+			Body("pending").
+			End(),
+		spectest.NewMock().
+			Post("http://orders.example.com/orders/1/confirm").
+			InScenario("order").
+			RespondWith().
+			Status(http.StatusOK).
+			Body("confirmed").
+			WillSetScenarioState("confirmed").
+			End(),
+	}
+}
+
+func TestMockScenarioMovesBetweenStates(t *testing.T) {
+	result := spectest.New().
+		Mocks(orderScenarioMocks()...).
+		HandlerFunc(orderHandler).
+		Get("/").
+		Expect(t).
+		Status(http.StatusOK).
+		Body("pending,confirmed,done").
+		MockScenarioState("order", "confirmed").
+		End()
+
+	spectest.DefaultVerifier{}.Equal(t, "confirmed", result.MockScenarioState("order"))
+	spectest.DefaultVerifier{}.Equal(t, spectest.MockScenarioStarted, result.MockScenarioState("unknown"))
+}
+
+func TestMockScenarioStateAssertionFails(t *testing.T) {
+	var failures []string
+	verifier := mocks.NewVerifier()
+	verifier.EqualFn = func(t spectest.TestingT, expected, actual interface{}, msgAndArgs ...interface{}) bool {
+		if expected != actual {
+			failures = append(failures, msgAndArgs[0].([]interface{})[0].(string))
+		}
+		return expected == actual
+	}
+
+	spectest.New().
+		Verifier(verifier).
+		Mocks(orderScenarioMocks()...).
+		HandlerFunc(orderHandler).
+		Get("/").
+		Expect(t).
+		MockScenarioState("order", "shipped").
+		End()
+
+	spectest.DefaultVerifier{}.Equal(t, []string{"mock scenario order is in state confirmed, expected shipped"}, failures)
+}
+
+func TestMockScenarioRequiresState(t *testing.T) {
+	defer spectest.NewMock().
+		Get("http://orders.example.com/orders/1").
+		InScenario("order").
+		WhenScenarioStateIs("confirmed").
+		RespondWith().
+		Status(http.StatusOK).
+		Body("done").
+		EndStandalone()()
+
+	_, err := http.Get("http://orders.example.com/orders/1")
+
+	spectest.DefaultVerifier{}.True(t, err != nil)
+	spectest.DefaultVerifier{}.True(t, strings.Contains(err.Error(), "scenario order is in state Started but mock requires state confirmed"))
+}
@@ -0,0 +1,240 @@
+package spectest
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+)
+
+// MockServer serves mocks from a real listening http server, for code under test that can not use a hijacked
+// transport: clients with a custom transport, subprocesses, or dependency urls read from configuration.
+// The mocks are matched like the hijacked mocks, so mock urls without a host, e.g. Get("/users/1"),
+// match the requests to the server.
+type MockServer struct {
+	// server is the listening http server
+	server *httptest.Server
+	// mocks is the list of mocks served by the server
+	mocks Mocks
+	// scenarios holds the state of the mock scenarios of the server
+	scenarios *mockScenarios
+
+	// mu guards the fields below, that are updated when the server is attached to a SpecTest
+	mu sync.Mutex
+	// debug is used to log the interactions with the mocks
+	debug *debug
+	// mockResponseDelayEnabled will turn on mock response delays
+	mockResponseDelayEnabled bool
+	// specTest is the SpecTest that the server is attached to. It is nil if the server is not attached.
+	specTest *SpecTest
+	// observers is the list of mock observers of the attached SpecTest
+	observers []Observe
+}
+
+// NewMockServer starts a http server that responds with the given mocks.
+// A mock is served as many times as it expects to be called.
+// The server must be closed with Close.
+func NewMockServer(mocks ...*Mock) *MockServer {
+	s := newMockServer(mocks)
+	s.server = httptest.NewServer(s)
+	return s
+}
+
+// NewTLSMockServer starts a https server that responds with the given mocks.
+// Use Client to get a http client that trusts the certificate of the server.
+func NewTLSMockServer(mocks ...*Mock) *MockServer {
+	s := newMockServer(mocks)
+	s.server = httptest.NewTLSServer(s)
+	return s
+}
+
+// newMockServer creates a mock server that is not started.
+func newMockServer(mocks []*Mock) *MockServer {
+	s := &MockServer{
+		mocks:     copyMocks(mocks),
+		scenarios: newMockScenarios(),
+		debug:     newDebug(),
+	}
+	for _, mock := range s.mocks {
+		mock.scenarios = s.scenarios
+	}
+	return s
+}
+
+// URL returns the base url of the server, e.g. http://127.0.0.1:53412
+func (s *MockServer) URL() string {
+	return s.server.URL
+}
+
+// Client returns a http client that is configured to send requests to the server,
+// and that trusts the certificate of a TLS server
+func (s *MockServer) Client() *http.Client {
+	return s.server.Client()
+}
+
+// Close shuts down the server
+func (s *MockServer) Close() {
+	s.server.CloseClientConnections()
+	s.server.Close()
+}
+
+// Debug logs to the console the interactions with the mocks of the server
+func (s *MockServer) Debug() *MockServer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.debug.enable()
+	return s
+}
+
+// EnableMockResponseDelay turns on mock response delays (defaults to OFF).
+// The delays are also enabled while the server is attached to a SpecTest that enables them.
+func (s *MockServer) EnableMockResponseDelay() *MockServer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mockResponseDelayEnabled = true
+	return s
+}
+
+// UnmatchedMocks returns the mocks of the server that were not used
+func (s *MockServer) UnmatchedMocks() []UnmatchedMock {
+	return s.mocks.findUnmatchedMocks()
+}
+
+// attach feeds the interactions with the server to the mock observers of the SpecTest while it runs,
+// so that they appear in the report. It returns a func that detaches the server.
+func (s *MockServer) attach(specTest *SpecTest) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	debug, delay := s.debug, s.mockResponseDelayEnabled
+	s.specTest, s.observers = specTest, specTest.mocksObservers
+	if specTest.debug.isEnable() {
+		s.debug = specTest.debug
+	}
+	s.mockResponseDelayEnabled = delay || specTest.mockResponseDelayEnabled
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.specTest, s.observers = nil, nil
+		s.debug, s.mockResponseDelayEnabled = debug, delay
+	}
+}
+
+// transport returns the transport that matches the requests to the mocks of the server.
+func (s *MockServer) transport() *Transport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &Transport{
+		mocks:                    s.mocks,
+		debug:                    s.debug,
+		mockResponseDelayEnabled: s.mockResponseDelayEnabled,
+		observers:                s.observers,
+		specTest:                 s.specTest,
+		scenarios:                s.scenarios,
+	}
+}
+
+// ServeHTTP matches the request to the mocks and writes the response of the matched mock.
+// The requests that do not match any mock receive a 404 response that lists the mismatches.
+// The faults of the mocks are injected into the connection, e.g. a connection reset closes the connection.
+func (s *MockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req := r.Clone(r.Context())
+	req.URL.Host = r.Host
+	req.URL.Scheme = "http"
+	if r.TLS != nil {
+		req.URL.Scheme = "https"
+	}
+	req.RequestURI = ""
+
+	res, err := s.transport().RoundTrip(req)
+	switch {
+	case errors.Is(err, ErrTimeout):
+		// the client is expected to give up before the server is closed
+		<-r.Context().Done()
+		return
+	case errors.Is(err, ErrConnectionReset):
+		resetConnection(w)
+		return
+	case err != nil:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, err.Error())
+		return
+	}
+	defer res.Body.Close() //nolint
+
+	for k, v := range res.Header {
+		w.Header()[k] = v
+	}
+	if w.Header().Get("Content-Length") == "" && res.ContentLength >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(res.ContentLength, 10))
+	}
+	w.WriteHeader(res.StatusCode)
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := res.Body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			if flusher, ok := w.(http.Flusher); ok {
+				flusher.Flush()
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if errors.Is(err, ErrConnectionReset) {
+			resetConnection(w)
+			return
+		}
+		if err != nil {
+			closeConnection(w)
+			return
+		}
+	}
+}
+
+// resetConnection closes the connection of the response without a graceful shutdown,
+// so that the client receives a connection reset.
+func resetConnection(w http.ResponseWriter) {
+	conn := hijack(w)
+	if conn == nil {
+		return
+	}
+	if tcp, ok := netConn(conn).(*net.TCPConn); ok {
+		_ = tcp.SetLinger(0)
+	}
+	_ = conn.Close()
+}
+
+// closeConnection closes the connection of the response, so that the client receives an unexpected EOF.
+func closeConnection(w http.ResponseWriter) {
+	if conn := hijack(w); conn != nil {
+		_ = conn.Close()
+	}
+}
+
+// hijack takes over the connection of the response. It returns nil if the connection can not be hijacked.
+func hijack(w http.ResponseWriter) net.Conn {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		return nil
+	}
+	return conn
+}
+
+// netConn returns the underlying connection of a TLS connection.
+func netConn(conn net.Conn) net.Conn {
+	if c, ok := conn.(interface{ NetConn() net.Conn }); ok {
+		return c.NetConn()
+	}
+	return conn
+}
@@ -0,0 +1,178 @@
+package spectest_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/nao1215/spectest"
+	"github.com/nao1215/spectest/mocks"
+)
+
+func TestMockServerServesMocks(t *testing.T) {
+	server := spectest.NewMockServer(
+		spectest.NewMock().
+			Get("/users/1").
+			Header("Accept", "application/json").
+			RespondWith().
+			Status(http.StatusOK).
+			Body(`{"id": 1}`).
+			Times(2).
+			End(),
+	)
+	defer server.Close()
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest(http.MethodGet, server.URL()+"/users/1", nil)
+		req.Header.Set("Accept", "application/json")
+		res, err := http.DefaultClient.Do(req)
+		spectest.DefaultVerifier{}.NoError(t, err)
+		body, _ := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+
+		spectest.DefaultVerifier{}.Equal(t, http.StatusOK, res.StatusCode)
+		spectest.DefaultVerifier{}.Equal(t, "application/json", res.Header.Get("Content-Type"))
+		spectest.DefaultVerifier{}.Equal(t, `{"id": 1}`, string(body))
+	}
+
+	res, err := http.Get(server.URL() + "/users/1")
+	spectest.DefaultVerifier{}.NoError(t, err)
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	spectest.DefaultVerifier{}.Equal(t, http.StatusNotFound, res.StatusCode)
+	spectest.DefaultVerifier{}.True(t, strings.Contains(string(body), "received request did not match any mocks"))
+	spectest.DefaultVerifier{}.Equal(t, 0, len(server.UnmatchedMocks()))
+}
+
+func TestMockServerTLS(t *testing.T) {
+	server := spectest.NewTLSMockServer(
+		spectest.NewMock().
+			Post("/charges").
+			Body(`{"amount": 100}`).
+			RespondWith().
+			Status(http.StatusCreated).
+			End(),
+	)
+	defer server.Close()
+
+	spectest.DefaultVerifier{}.True(t, strings.HasPrefix(server.URL(), "https://"))
+	res, err := server.Client().Post(server.URL()+"/charges", "application/json", strings.NewReader(`{"amount": 100}`))
+	spectest.DefaultVerifier{}.NoError(t, err)
+	_ = res.Body.Close()
+	spectest.DefaultVerifier{}.Equal(t, http.StatusCreated, res.StatusCode)
+}
+
+func TestMockServerInjectsFaults(t *testing.T) {
+	server := spectest.NewMockServer(
+		spectest.NewMock().
+			Get("/reset").
+			RespondWith().
+			Status(http.StatusOK).
+			ConnectionReset().
+			End(),
+		spectest.NewMock().
+			Get("/truncated").
+			RespondWith().
+			Status(http.StatusOK).
+			Body("abcdefghij").
+			TruncateAfterBytes(3).
+			End(),
+	)
+	defer server.Close()
+
+	_, err := http.Get(server.URL() + "/reset")
+	spectest.DefaultVerifier{}.True(t, err != nil)
+
+	res, err := http.Get(server.URL() + "/truncated")
+	spectest.DefaultVerifier{}.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	spectest.DefaultVerifier{}.Equal(t, "abc", string(body))
+	spectest.DefaultVerifier{}.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+}
+
+func TestMockServerInteractionsAppearInReport(t *testing.T) {
+	server := spectest.NewMockServer(
+		spectest.NewMock().
+			Get("/users/1").
+			RespondWith().
+			Status(http.StatusOK).
+			Body(`{"name": "Bob"}`).
+			End(),
+	)
+	defer server.Close()
+	reporter := &RecorderCaptor{}
+
+	spectest.New().
+		Report(reporter).
+		MockServers(server).
+		HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			// the client has its own transport, so a hijacked transport would not see the request
+			client := &http.Client{Transport: &http.Transport{}}
+			res, err := client.Get(server.URL() + "/users/1")
+			if err != nil {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			defer res.Body.Close()
+			w.WriteHeader(res.StatusCode)
+			_, _ = io.Copy(w, res.Body)
+		}).
+		Get("/user").
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"name": "Bob"}`).
+		End()
+
+	events := reporter.capturedRecorder.Events
+	spectest.DefaultVerifier{}.Equal(t, 4, len(events))
+	mockRequest, ok := events[1].(spectest.HTTPRequest)
+	spectest.DefaultVerifier{}.True(t, ok)
+	spectest.DefaultVerifier{}.Equal(t, "/users/1", mockRequest.Value.URL.Path)
+	spectest.DefaultVerifier{}.Equal(t, strings.TrimPrefix(server.URL(), "http://"), mockRequest.Target)
+	mockResponse, ok := events[2].(spectest.HTTPResponse)
+	spectest.DefaultVerifier{}.True(t, ok)
+	spectest.DefaultVerifier{}.Equal(t, http.StatusOK, mockResponse.Value.StatusCode)
+}
+
+func TestMockServerFailsWhenMocksAreNotCalledExpectedTimes(t *testing.T) {
+	server := spectest.NewMockServer(
+		spectest.NewMock().
+			Get("/users/1").
+			RespondWith().
+			Status(http.StatusOK).
+			Times(2).
+			End(),
+	)
+	defer server.Close()
+
+	var failures []string
+	verifier := mocks.NewVerifier()
+	verifier.FailFn = func(t spectest.TestingT, failureMessage string, msgAndArgs ...interface{}) bool {
+		failures = append(failures, failureMessage)
+		return false
+	}
+
+	spectest.New().
+		Verifier(verifier).
+		MockServers(server).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res, err := http.Get(server.URL() + "/users/1")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			_ = res.Body.Close()
+			w.WriteHeader(res.StatusCode)
+		}).
+		Get("/").
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+
+	spectest.DefaultVerifier{}.Equal(t, []string{
+		"mock was not invoked expected times: GET /users/1 was called 1 time, expected 2 times",
+	}, failures)
+}
@@ -0,0 +1,152 @@
+package spectest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// mockSpy forwards the requests that match a mock to the real service.
+// The copies of a mock share its spy.
+type mockSpy struct {
+	// asserts is the list of assertions on the responses of the real service
+	asserts []Assert
+	// rewrites is the list of functions that rewrite the responses of the real service, in order
+	rewrites []func(*http.Response) error
+}
+
+// Spy forwards the requests that match the mock to the real service instead of responding with a mock response.
+// The calls of the mock record the responses of the real service, see Mock.Calls. The responses can be asserted
+// with AssertUpstream, and rewritten with RewriteStatus, PatchJSON and Rewrite before they are returned.
+func (r *MockRequest) Spy() *MockResponse {
+	r.mock.response.spies()
+	return r.mock.response
+}
+
+// spies returns the spy of the mock response, creating it if needed.
+func (r *MockResponse) spies() *mockSpy {
+	if r.spy == nil {
+		r.spy = &mockSpy{}
+	}
+	return r.spy
+}
+
+// AssertUpstream asserts the response of the real service to a spy mock, before it is rewritten.
+// The test fails if the assertion returns an error. It makes the mock a spy.
+func (r *MockResponse) AssertUpstream(fn Assert) *MockResponse {
+	r.spies().asserts = append(r.spies().asserts, fn)
+	return r
+}
+
+// RewriteStatus replaces the status code of the response of the real service to a spy mock. It makes the mock a spy.
+func (r *MockResponse) RewriteStatus(statusCode int) *MockResponse {
+	return r.Rewrite(func(res *http.Response) error {
+		res.StatusCode = statusCode
+		res.Status = fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode))
+		return nil
+	})
+}
+
+// PatchJSON applies a JSON merge patch (RFC 7386) to the JSON body of the response of the real service
+// to a spy mock, e.g. PatchJSON(`{"status": "suspended", "email": null}`) replaces status and removes email.
+// It makes the mock a spy.
+func (r *MockResponse) PatchJSON(patch string) *MockResponse {
+	return r.Rewrite(func(res *http.Response) error {
+		var patchJSON interface{}
+		if err := json.Unmarshal([]byte(patch), &patchJSON); err != nil {
+			return fmt.Errorf("invalid JSON merge patch: %w", err)
+		}
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+		var bodyJSON interface{}
+		if err := json.Unmarshal(body, &bodyJSON); err != nil {
+			return fmt.Errorf("response body is not JSON: %w", err)
+		}
+		patched, err := json.Marshal(mergePatch(bodyJSON, patchJSON))
+		if err != nil {
+			return err
+		}
+		res.Body = io.NopCloser(bytes.NewReader(patched))
+		res.ContentLength = int64(len(patched))
+		res.Header.Set("Content-Length", strconv.Itoa(len(patched)))
+		return nil
+	})
+}
+
+// Rewrite rewrites the response of the real service to a spy mock with the given function,
+// after the rewrites that were added before it. An error fails the request. It makes the mock a spy.
+func (r *MockResponse) Rewrite(fn func(*http.Response) error) *MockResponse {
+	r.spies().rewrites = append(r.spies().rewrites, fn)
+	return r
+}
+
+// forward sends the request to the real service with the upstream transport, records the response
+// in the calls of the mock, asserts it and rewrites it.
+func (r *MockResponse) forward(upstream http.RoundTripper, req *http.Request) (*http.Response, error) {
+	res, err := upstream.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	if res.Header == nil {
+		res.Header = http.Header{}
+	}
+
+	r.mock.calls.respond(req, copyHTTPResponse(res), body)
+	for _, assert := range r.spy.asserts {
+		if err := assert(copyHTTPResponse(res), copyHTTPRequest(req)); err != nil {
+			r.mock.m.Lock()
+			r.mock.spyErrors = append(r.mock.spyErrors, fmt.Errorf("spy mock %s: %w", r.mock, err))
+			r.mock.m.Unlock()
+		}
+	}
+	for _, rewrite := range r.spy.rewrites {
+		if err := rewrite(res); err != nil {
+			return nil, fmt.Errorf("failed to rewrite the response of spy mock %s: %w", r.mock, err)
+		}
+	}
+	return res, nil
+}
+
+// mergePatch applies the JSON merge patch to the document, see RFC 7386.
+func mergePatch(doc, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	docObject, ok := doc.(map[string]interface{})
+	if !ok {
+		docObject = map[string]interface{}{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(docObject, key)
+			continue
+		}
+		docObject[key] = mergePatch(docObject[key], value)
+	}
+	return docObject
+}
+
+// assertMockSpies will assert the responses of the real services to the spy mocks.
+// If an assertion failed, the test will fail.
+func (s *SpecTest) assertMockSpies() {
+	for _, mock := range s.mocks {
+		mock.m.Lock()
+		errs := mock.spyErrors
+		mock.m.Unlock()
+		for _, err := range errs {
+			s.check(FailureMock, "").NoError(s.t, err, failureMessageArgs{Name: s.name})
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package spectest_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nao1215/spectest"
+	"github.com/nao1215/spectest/mocks"
+)
+
+func newUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/1":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"name": "alice", "status": "active"}`)
+		default:
+			w.WriteHeader(http.StatusTeapot)
+		}
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+// forwardingHandler responds with the status and the body of the response to a GET request to the url
+func forwardingHandler(url string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, url, nil)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = io.WriteString(w, err.Error())
+			return
+		}
+		defer res.Body.Close() //nolint
+		body, _ := io.ReadAll(res.Body)
+		w.WriteHeader(res.StatusCode)
+		_, _ = w.Write(body)
+	}
+}
+
+func TestPassthroughSendsUnmatchedRequestsUpstream(t *testing.T) {
+	upstream := newUpstream(t)
+
+	spectest.New().
+		Passthrough().
+		Mocks(spectest.NewMock().Get(upstream.URL + "/orders").RespondWith().Status(http.StatusOK).End()).
+		HandlerFunc(forwardingHandler(upstream.URL + "/users/1")).
+		Get("/").
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"name": "alice", "status": "active"}`).
+		End()
+}
+
+func TestPassthroughWithIsolatedMocks(t *testing.T) {
+	upstream := newUpstream(t)
+
+	spectest.New().
+		IsolateMocks().
+		Passthrough().
+		Mocks(spectest.NewMock().Get(upstream.URL + "/orders").RespondWith().Status(http.StatusOK).End()).
+		HandlerFunc(forwardingHandler(upstream.URL + "/missing")).
+		Get("/").
+		Expect(t).
+		Status(http.StatusTeapot).
+		End()
+}
+
+func TestWithoutPassthroughUnmatchedRequestsFail(t *testing.T) {
+	upstream := newUpstream(t)
+
+	spectest.New().
+		Mocks(spectest.NewMock().Get(upstream.URL + "/orders").RespondWith().Status(http.StatusOK).End()).
+		HandlerFunc(forwardingHandler(upstream.URL + "/users/1")).
+		Get("/").
+		Expect(t).
+		Status(http.StatusBadGateway).
+		End()
+}
+
+func TestSpyMockRecordsAndRewritesTheUpstreamResponse(t *testing.T) {
+	upstream := newUpstream(t)
+	spy := spectest.NewMock().
+		Get(upstream.URL + "/users/1").
+		Spy().
+		AssertUpstream(func(res *http.Response, _ *http.Request) error {
+			if res.StatusCode != http.StatusOK {
+				return errors.New("expected the upstream to respond 200")
+			}
+			return nil
+		}).
+		RewriteStatus(http.StatusAccepted).
+		PatchJSON(`{"status": "suspended", "name": null}`).
+		End()
+
+	spectest.New().
+		Mocks(spy).
+		HandlerFunc(forwardingHandler(upstream.URL + "/users/1")).
+		Get("/").
+		Expect(t).
+		Status(http.StatusAccepted).
+		Body(`{"status": "suspended"}`).
+		End()
+
+	calls := spy.Calls()
+	spectest.DefaultVerifier{}.Equal(t, 1, len(calls))
+	spectest.DefaultVerifier{}.Equal(t, http.StatusOK, calls[0].Response.StatusCode)
+	spectest.DefaultVerifier{}.Equal(t, `{"name": "alice", "status": "active"}`, string(calls[0].ResponseBody))
+}
+
+func TestSpyMockFailsWhenUpstreamAssertionFails(t *testing.T) {
+	upstream := newUpstream(t)
+	var failures []error
+	verifier := mocks.NewVerifier()
+	verifier.NoErrorFn = func(t spectest.TestingT, err error, msgAndArgs ...interface{}) bool {
+		failures = append(failures, err)
+		return err == nil
+	}
+
+	spectest.New().
+		Verifier(verifier).
+		Mocks(spectest.NewMock().
+			Get(upstream.URL + "/missing").
+			Spy().
+			AssertUpstream(func(res *http.Response, _ *http.Request) error {
+				if res.StatusCode != http.StatusOK {
+					return errors.New("unexpected status")
+				}
+				return nil
+			}).
+			End()).
+		HandlerFunc(forwardingHandler(upstream.URL + "/missing")).
+		Get("/").
+		Expect(t).
+		Status(http.StatusTeapot).
+		End()
+
+	spectest.DefaultVerifier{}.Equal(t, 1, len(failures))
+	spectest.DefaultVerifier{}.Equal(t, "spy mock GET "+upstream.URL+"/missing: unexpected status", failures[0].Error())
+}
@@ -0,0 +1,144 @@
+package spectest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"text/template"
+)
+
+// MockTemplateData is the data of the body templates of the mock responses.
+// It describes the request that matched the mock, e.g.
+//
+//	{"id": "{{index .PathSegments 1}}", "q": "{{.Query.Get "q"}}", "name": {{json .JSON.name}}}
+type MockTemplateData struct {
+	// Method is the method of the request
+	Method string
+	// URL is the url of the request
+	URL *url.URL
+	// Path is the path of the request
+	Path string
+	// PathSegments is the list of the segments of the path, e.g. [users 1] for /users/1
+	PathSegments []string
+	// PathParams is the path parameters captured by the path of the mock, e.g. map[id:1] for /users/{id}
+	PathParams map[string]string
+	// Query is the query of the request
+	Query url.Values
+	// Header is the header of the request
+	Header http.Header
+	// Cookies is the value of the cookies of the request by name
+	Cookies map[string]string
+	// Body is the body of the request
+	Body string
+	// JSON is the body of the request decoded as JSON. It is nil if the body is not JSON.
+	JSON interface{}
+}
+
+// mockTemplateFuncs is the functions that are available in the body templates of the mock responses
+var mockTemplateFuncs = template.FuncMap{
+	// json encodes the value as JSON, e.g. {{json .JSON.tags}}
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// BodyTemplate sets the mock response body to the result of a text/template executed
+// with the MockTemplateData of the matched request. It panics if the template is invalid.
+func (r *MockResponse) BodyTemplate(text string) *MockResponse {
+	r.bodyTemplate = template.Must(template.New("body").Funcs(mockTemplateFuncs).Option("missingkey=zero").Parse(text))
+	return r
+}
+
+// RespondWithFunc finalizes the mock request phase of set up, and responds with the response returned by fn
+// for the request that matched the mock. The status code, headers and body of the returned response replace
+// the ones defined with the MockResponse builder, so the MockResponse can still set cookies, a delay or times.
+func (r *MockRequest) RespondWithFunc(fn func(*http.Request) *http.Response) *MockResponse {
+	r.mock.response.responseFunc = fn
+	return r.mock.response
+}
+
+// render returns the mock response for the request. If the mock response is dynamic,
+// the body template or the response func is applied to a copy of the mock response.
+func (r *MockResponse) render(req *http.Request) (*MockResponse, error) {
+	if r.bodyTemplate == nil && r.responseFunc == nil {
+		return r, nil
+	}
+
+	rendered := *r
+	rendered.headers = map[string][]string{}
+	for k, v := range r.headers {
+		rendered.headers[k] = append([]string{}, v...)
+	}
+
+	if r.bodyTemplate != nil {
+		var body bytes.Buffer
+		if err := r.bodyTemplate.Execute(&body, newMockTemplateData(req, r.mock.request)); err != nil {
+			return nil, fmt.Errorf("failed to render the body template of the mock response: %w", err)
+		}
+		rendered.body = body.String()
+	}
+
+	if r.responseFunc != nil {
+		reqCopy := copyHTTPRequest(req)
+		for name, value := range r.mock.request.pathParams(req) {
+			reqCopy.SetPathValue(name, value)
+		}
+		res := r.responseFunc(reqCopy)
+		if res == nil {
+			return nil, errors.New("the response func of the mock returned a nil response")
+		}
+		if res.StatusCode != 0 {
+			rendered.statusCode = res.StatusCode
+		}
+		for k, v := range res.Header {
+			rendered.headers[textproto.CanonicalMIMEHeaderKey(k)] = append([]string{}, v...)
+		}
+		if res.Body != nil {
+			body, err := io.ReadAll(res.Body)
+			_ = res.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the body of the mock response: %w", err)
+			}
+			rendered.body = string(body)
+		}
+	}
+	return &rendered, nil
+}
+
+// newMockTemplateData returns the template data of the request that matched the mock request.
+func newMockTemplateData(req *http.Request, spec *MockRequest) MockTemplateData {
+	data := MockTemplateData{
+		Method:     req.Method,
+		URL:        req.URL,
+		Path:       req.URL.Path,
+		PathParams: spec.pathParams(req),
+		Query:      req.URL.Query(),
+		Header:     req.Header,
+		Cookies:    map[string]string{},
+	}
+	for _, segment := range strings.Split(strings.Trim(req.URL.Path, "/"), "/") {
+		if segment != "" {
+			data.PathSegments = append(data.PathSegments, segment)
+		}
+	}
+	for _, cookie := range req.Cookies() {
+		data.Cookies[cookie.Name] = cookie.Value
+	}
+	if req.Body != nil {
+		body, _ := io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		data.Body = string(body)
+		var v interface{}
+		if json.Unmarshal(body, &v) == nil {
+			data.JSON = v
+		}
+	}
+	return data
+}
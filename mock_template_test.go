@@ -0,0 +1,94 @@
+package spectest_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/nao1215/spectest"
+)
+
+// proxyHandler sends the request to the mocked user service and copies the response
+func proxyHandler(w http.ResponseWriter, r *http.Request) {
+	req, _ := http.NewRequest(http.MethodPost, "http://users.example.com/users/42?verbose=true", strings.NewReader(`{"name": "Bob", "tags": ["a", "b"]}`))
+	req.Header.Set("X-Request-Id", "abc")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "s1"})
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = io.WriteString(w, err.Error())
+		return
+	}
+	defer res.Body.Close()
+	for k, v := range res.Header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(res.StatusCode)
+	_, _ = io.Copy(w, res.Body)
+}
+
+func TestMockResponseBodyTemplate(t *testing.T) {
+	spectest.New().
+		Mocks(spectest.NewMock().
+			Post("http://users.example.com/users/{id}").
+			RespondWith().
+			Status(http.StatusCreated).
+			Cookie("user", "42").
+			BodyTemplate(`{"id": "{{.PathParams.id}}", "segment": "{{index .PathSegments 0}}", "verbose": "{{.Query.Get "verbose"}}", "request_id": "{{.Header.Get "X-Request-Id"}}", "session": "{{.Cookies.session}}", "name": {{json .JSON.name}}, "tags": {{json .JSON.tags}}, "method": "{{.Method}}"}`).
+			End()).
+		HandlerFunc(proxyHandler).
+		Get("/").
+		Expect(t).
+		Status(http.StatusCreated).
+		Header("Content-Type", "application/json").
+		Cookie("user", "42").
+		Body(`{"id": "42", "segment": "users", "verbose": "true", "request_id": "abc", "session": "s1", "name": "Bob", "tags": ["a", "b"], "method": "POST"}`).
+		End()
+}
+
+func TestMockResponseBodyTemplateExecutionError(t *testing.T) {
+	spectest.New().
+		Mocks(spectest.NewMock().
+			Post("http://users.example.com/users/{id}").
+			RespondWith().
+			Status(http.StatusOK).
+			BodyTemplate(`{{index .PathSegments 5}}`).
+			End()).
+		HandlerFunc(proxyHandler).
+		Get("/").
+		Expect(t).
+		Status(http.StatusBadGateway).
+		Assert(func(res *http.Response, _ *http.Request) error {
+			body, _ := io.ReadAll(res.Body)
+			spectest.DefaultVerifier{}.True(t, strings.Contains(string(body), "failed to render the body template of the mock response"))
+			return nil
+		}).
+		End()
+}
+
+func TestMockRequestRespondWithFunc(t *testing.T) {
+	spectest.New().
+		Mocks(spectest.NewMock().
+			Post("http://users.example.com/users/{id}").
+			RespondWithFunc(func(r *http.Request) *http.Response {
+				body, _ := io.ReadAll(r.Body)
+				return &http.Response{
+					StatusCode: http.StatusAccepted,
+					Header:     http.Header{"X-Echo-Path": {r.URL.Path}, "X-User-Id": {r.PathValue("id")}},
+					Body:       io.NopCloser(strings.NewReader(string(body))),
+				}
+			}).
+			Cookie("user", "42").
+			End()).
+		HandlerFunc(proxyHandler).
+		Get("/").
+		Expect(t).
+		Status(http.StatusAccepted).
+		Header("X-Echo-Path", "/users/42").
+		Header("X-User-Id", "42").
+		Header("Content-Type", "application/json").
+		Cookie("user", "42").
+		Body(`{"name": "Bob", "tags": ["a", "b"]}`).
+		End()
+}
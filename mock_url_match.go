@@ -0,0 +1,211 @@
+package spectest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// URLMatch is the way the host and the path of the url of a mock request are matched
+type URLMatch int
+
+const (
+	// URLMatchPattern matches the host exactly and the path as a net/http pattern (the default).
+	// A path without wildcards is matched exactly, e.g. /users/1 does not match /users/10.
+	// {name} matches a segment of the path, {name...} matches the rest of the path,
+	// {$} matches the end of a path that ends with a slash, and a path that ends with a slash
+	// matches every path that starts with it, e.g. /users/{id}/posts/{post...}
+	URLMatchPattern URLMatch = iota
+	// URLMatchExact matches the host and the path exactly. Braces are not wildcards.
+	URLMatchExact
+	// URLMatchGlob matches the host and the path as a glob of path.Match,
+	// e.g. *.example.com and /users/*/posts. A * does not match a slash.
+	URLMatchGlob
+)
+
+// urlMatchNames is the name of the url match modes in the mock files
+var urlMatchNames = map[string]URLMatch{
+	"pattern": URLMatchPattern,
+	"exact":   URLMatchExact,
+	"glob":    URLMatchGlob,
+}
+
+// URLMatch sets the way the host and the path of the url of the mock are matched. The default is URLMatchPattern.
+// The path parameters captured by the path are available from request.PathValue in the Matcher funcs
+// and the response func, and from .PathParams in the body templates.
+func (r *MockRequest) URLMatch(mode URLMatch) *MockRequest {
+	r.urlMatch = mode
+	return r
+}
+
+// PathRegexp matches the path of the request with a regular expression that must match the entire path,
+// instead of the path of the mock url, e.g. PathRegexp(`/users/(?P<id>[0-9]+)`).
+// The named groups are path parameters, like the wildcards of a pattern.
+func (r *MockRequest) PathRegexp(expr string) *MockRequest {
+	r.pathRegexp = expr
+	return r
+}
+
+// HostRegexp matches the host of the request with a regular expression that must match the entire host,
+// instead of the host of the mock url, e.g. HostRegexp(`api[0-9]+\.example\.com`).
+func (r *MockRequest) HostRegexp(expr string) *MockRequest {
+	r.hostRegexp = expr
+	return r
+}
+
+// matchPath matches the path of the request to the path of the mock. It returns the path parameters
+// captured by the path of the mock, and false if the path does not match.
+func (r *MockRequest) matchPath(req *http.Request) (map[string]string, bool, error) {
+	if r.pathRegexp != "" {
+		return matchRegexp(r.pathRegexp, req.URL.Path)
+	}
+	mockPath := r.url.Path
+	if mockPath == "" {
+		return nil, true, nil
+	}
+
+	switch r.urlMatch {
+	case URLMatchExact:
+		return nil, req.URL.Path == mockPath, nil
+	case URLMatchGlob:
+		matched, err := path.Match(mockPath, req.URL.Path)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid mock path glob %s: %w", mockPath, err)
+		}
+		return nil, matched, nil
+	default:
+		params, matched := matchPathPattern(mockPath, req.URL.EscapedPath())
+		return params, matched, nil
+	}
+}
+
+// matchHost matches the host of the request to the host of the mock.
+func (r *MockRequest) matchHost(host string) (bool, error) {
+	if r.hostRegexp != "" {
+		_, matched, err := matchRegexp(r.hostRegexp, host)
+		return matched, err
+	}
+	mockHost := r.url.Host
+	switch r.urlMatch {
+	case URLMatchGlob:
+		matched, err := path.Match(mockHost, host)
+		if err != nil {
+			return false, fmt.Errorf("invalid mock host glob %s: %w", mockHost, err)
+		}
+		return matched, nil
+	default:
+		return host == mockHost, nil
+	}
+}
+
+// pathParams returns the path parameters of the request that are captured by the path of the mock.
+func (r *MockRequest) pathParams(req *http.Request) map[string]string {
+	params, _, _ := r.matchPath(req)
+	if params == nil {
+		params = map[string]string{}
+	}
+	return params
+}
+
+// withPathValues returns a copy of the request that holds the path parameters captured by the path of the mock,
+// so that the Matcher funcs can read them with PathValue. The request given to a RoundTripper must not be
+// modified, so the request is only returned as is if the path captures no parameter.
+func (r *MockRequest) withPathValues(req *http.Request) *http.Request {
+	params, _, _ := r.matchPath(req)
+	if len(params) == 0 {
+		return req
+	}
+	reqCopy := copyHTTPRequest(req)
+	for name, value := range params {
+		reqCopy.SetPathValue(name, value)
+	}
+	return reqCopy
+}
+
+// mockPath describes the path the mock matches, for the error messages
+func (r *MockRequest) mockPath() string {
+	if r.pathRegexp != "" {
+		return r.pathRegexp
+	}
+	return r.url.Path
+}
+
+// mockHost describes the host the mock matches, for the error messages
+func (r *MockRequest) mockHost() string {
+	if r.hostRegexp != "" {
+		return r.hostRegexp
+	}
+	return r.url.Host
+}
+
+// matchRegexp matches the value to a regular expression that must match it entirely.
+// It returns the named groups of the regular expression.
+func matchRegexp(expr, value string) (map[string]string, bool, error) {
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid mock regexp %s: %w", expr, err)
+	}
+	submatches := re.FindStringSubmatch(value)
+	if submatches == nil {
+		return nil, false, nil
+	}
+	params := map[string]string{}
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			params[name] = submatches[i]
+		}
+	}
+	return params, true, nil
+}
+
+// matchPathPattern matches the escaped path to a net/http pattern, e.g. /users/{id}.
+// It returns the values of the wildcards of the pattern.
+func matchPathPattern(pattern, escapedPath string) (map[string]string, bool) {
+	patternSegments := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.TrimPrefix(escapedPath, "/"), "/")
+	params := map[string]string{}
+
+	for i, segment := range patternSegments {
+		last := i == len(patternSegments)-1
+		isWildcard := len(segment) > 2 && strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+		name := strings.Trim(segment, "{}")
+
+		switch {
+		case last && segment == "{$}":
+			return params, len(pathSegments) == i+1 && pathSegments[i] == ""
+		case last && segment == "":
+			return params, len(pathSegments) > i
+		case last && isWildcard && strings.HasSuffix(name, "..."):
+			if len(pathSegments) <= i {
+				return nil, false
+			}
+			rest, err := url.PathUnescape(strings.Join(pathSegments[i:], "/"))
+			if err != nil {
+				return nil, false
+			}
+			params[strings.TrimSuffix(name, "...")] = rest
+			return params, true
+		case isWildcard:
+			if len(pathSegments) <= i || pathSegments[i] == "" {
+				return nil, false
+			}
+			value, err := url.PathUnescape(pathSegments[i])
+			if err != nil {
+				return nil, false
+			}
+			params[name] = value
+		default:
+			if len(pathSegments) <= i {
+				return nil, false
+			}
+			value, err := url.PathUnescape(pathSegments[i])
+			if err != nil || value != segment {
+				return nil, false
+			}
+		}
+	}
+	return params, len(pathSegments) == len(patternSegments)
+}
@@ -0,0 +1,157 @@
+package spectest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMockURLMatchPath(t *testing.T) {
+	tests := map[string]struct {
+		mode           URLMatch
+		mockPath       string
+		requestPath    string
+		matched        bool
+		expectedParams map[string]string
+	}{
+		"pattern exact":                 {URLMatchPattern, "/users/1", "/users/1", true, map[string]string{}},
+		"pattern is not a prefix":       {URLMatchPattern, "/users/1", "/users/10", false, nil},
+		"pattern wildcard":              {URLMatchPattern, "/users/{id}/posts", "/users/42/posts", true, map[string]string{"id": "42"}},
+		"pattern wildcard is unescaped": {URLMatchPattern, "/files/{name}", "/files/a%20b", true, map[string]string{"name": "a b"}},
+		"pattern wildcard is a segment": {URLMatchPattern, "/users/{id}", "/users/42/posts", false, nil},
+		"pattern empty wildcard":        {URLMatchPattern, "/users/{id}", "/users/", false, nil},
+		"pattern rest wildcard":         {URLMatchPattern, "/files/{path...}", "/files/a/b.txt", true, map[string]string{"path": "a/b.txt"}},
+		"pattern trailing slash":        {URLMatchPattern, "/static/", "/static/css/app.css", true, map[string]string{}},
+		"pattern end of path":           {URLMatchPattern, "/static/{$}", "/static/", true, map[string]string{}},
+		"pattern end of path mismatch":  {URLMatchPattern, "/static/{$}", "/static/app.css", false, nil},
+		"exact braces are literal":      {URLMatchExact, "/users/{id}", "/users/42", false, nil},
+		"exact":                         {URLMatchExact, "/users/{id}", "/users/{id}", true, nil},
+		"glob":                          {URLMatchGlob, "/users/*/posts", "/users/42/posts", true, nil},
+		"glob star does not match /":    {URLMatchGlob, "/users/*", "/users/42/posts", false, nil},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com"+test.requestPath, nil)
+			mock := NewMock().Get("http://example.com" + test.mockPath).URLMatch(test.mode)
+
+			params, matched, err := mock.matchPath(req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.matched, matched)
+			if test.matched {
+				assert.Equal(t, test.expectedParams, params)
+			}
+		})
+	}
+}
+
+func TestMockURLMatchHost(t *testing.T) {
+	tests := map[string]struct {
+		mode     URLMatch
+		mockHost string
+		host     string
+		matched  bool
+	}{
+		"dots are not wildcards": {URLMatchPattern, "api.example.com", "apixexample.com", false},
+		"exact":                  {URLMatchExact, "api.example.com", "api.example.com", true},
+		"glob":                   {URLMatchGlob, "*.example.com", "api.example.com", true},
+		"glob mismatch":          {URLMatchGlob, "*.example.com", "example.com", false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://"+test.host+"/", nil)
+			mock := NewMock().Get("http://" + test.mockHost + "/").URLMatch(test.mode)
+
+			assert.Equal(t, test.matched, hostMatcher(req, mock) == nil)
+		})
+	}
+}
+
+func TestMockURLMatchRegexp(t *testing.T) {
+	tests := map[string]struct {
+		pathRegexp     string
+		hostRegexp     string
+		url            string
+		matched        bool
+		expectedParams map[string]string
+	}{
+		"path named groups":   {`/users/(?P<id>[0-9]+)`, "", "http://example.com/users/10", true, map[string]string{"id": "10"}},
+		"path is anchored":    {`/users/[0-9]`, "", "http://example.com/users/10", false, nil},
+		"host":                {"", `api[0-9]\.example\.com`, "http://api1.example.com/", true, map[string]string{}},
+		"host is anchored":    {"", `api[0-9]\.example\.com`, "http://api1.example.com.evil/", false, nil},
+		"host dot is escaped": {"", `api[0-9]\.example\.com`, "http://api1xexample.com/", false, nil},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, test.url, nil)
+			mock := NewMock().Get("").PathRegexp(test.pathRegexp).HostRegexp(test.hostRegexp)
+
+			errs := mock.mock.Matches(req)
+
+			assert.Equal(t, test.matched, len(errs) == 0)
+			if test.matched {
+				assert.Equal(t, test.expectedParams, mock.pathParams(req))
+			}
+		})
+	}
+}
+
+func TestMockURLMatchInvalidRegexp(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/users", nil)
+
+	err := pathMatcher(req, NewMock().Get("").PathRegexp("/users/("))
+
+	assert.True(t, err != nil)
+	assert.True(t, errors.Unwrap(err) != nil)
+}
+
+func TestMockURLMatchPathValuesInMatchers(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/users/42", nil)
+	mock := NewMock().
+		Get("http://example.com/users/{id}").
+		AddMatcher(func(r *http.Request, _ *MockRequest) error {
+			if r.PathValue("id") != "42" {
+				return errors.New("unexpected user")
+			}
+			return nil
+		}).
+		RespondWith().
+		Status(http.StatusOK).
+		End()
+
+	res, err := matches(req, Mocks{mock})
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.statusCode)
+}
+
+func TestMockURLMatchDoesNotModifyTheRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/users/42", nil)
+	var seen string
+	postMock := NewMock().
+		Post("http://example.com/users/{id}").
+		RespondWith().
+		Status(http.StatusCreated).
+		End()
+	getMock := NewMock().
+		Get("http://example.com/users/{user}").
+		AddMatcher(func(r *http.Request, _ *MockRequest) error {
+			seen = r.PathValue("id")
+			return nil
+		}).
+		RespondWith().
+		Status(http.StatusOK).
+		End()
+
+	res, err := matches(req, Mocks{postMock, getMock})
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.statusCode)
+	assert.Equal(t, "", seen)
+	assert.Equal(t, "", req.PathValue("id"))
+	assert.Equal(t, "", req.PathValue("user"))
+}
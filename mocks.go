@@ -15,6 +15,7 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
@@ -37,6 +38,17 @@ type Transport struct {
 	nativeTransport http.RoundTripper
 	// specTest is the spectest instance
 	specTest *SpecTest
+	// routeID is the id used to route outbound requests to this transport.
+	// If routeID is 0, the transport replaces the http client transport instead.
+	routeID uint64
+	// router is the mock router the transport is registered in while the mocks are isolated
+	router *mockRouter
+	// passthrough sends the requests that do not match any mock to the native transport
+	passthrough bool
+	// recorder records the requests sent to the native transport while a cassette records through the mock router
+	recorder *cassetteRecorder
+	// scenarios holds the state of the mock scenarios
+	scenarios *mockScenarios
 }
 
 // newTransport creates a new transport
@@ -56,6 +68,10 @@ func newTransport(
 		mockResponseDelayEnabled: mockResponseDelayEnabled,
 		observers:                observers,
 		specTest:                 specTest,
+		scenarios:                newMockScenarios(),
+	}
+	for _, mock := range mocks {
+		mock.scenarios = t.scenarios
 	}
 
 	if httpClient != nil {
@@ -69,6 +85,12 @@ func newTransport(
 // RoundTrip implementation intended to match a given expected mock request
 // or throw an error with a list of reasons why no match was found.
 func (r *Transport) RoundTrip(req *http.Request) (mockResponse *http.Response, err error) {
+	// the faults are applied after the debug output and the observers have read the response body
+	var fault *injectedFault
+	defer func() {
+		mockResponse = fault.apply(mockResponse)
+	}()
+
 	defer func() {
 		r.debug.mock(mockResponse, req)
 	}()
@@ -83,12 +105,28 @@ func (r *Transport) RoundTrip(req *http.Request) (mockResponse *http.Response, e
 
 	matchedResponse, err := matches(req, r.mocks)
 	if err != nil {
+		if r.specTest != nil && r.specTest.cassette != nil {
+			r.specTest.cassette.recordUnmatched(req)
+		}
+		if r.passthrough {
+			return r.upstream().RoundTrip(req)
+		}
 		if r.debug.isEnable() {
 			fmt.Printf("failed to match mocks. Errors: %s\n", err)
 		}
 		return nil, err
 	}
 
+	if matchedResponse.spy != nil {
+		return matchedResponse.forward(r.upstream(), req)
+	}
+
+	matchedResponse, err = matchedResponse.render(req)
+	if err != nil {
+		return nil, err
+	}
+	fault = matchedResponse.fault.inject(r.mockResponseDelayEnabled)
+	req = withFault(req, fault)
 	res := buildResponseFromMock(matchedResponse)
 	res.Request = req
 
@@ -98,11 +136,40 @@ func (r *Transport) RoundTrip(req *http.Request) (mockResponse *http.Response, e
 	if r.mockResponseDelayEnabled && matchedResponse.fixedDelayMillis > 0 {
 		time.Sleep(time.Duration(matchedResponse.fixedDelayMillis) * time.Millisecond)
 	}
+	if err := fault.wait(req.Context()); err != nil {
+		return nil, err
+	}
+	if fault.reset() {
+		return nil, ErrConnectionReset
+	}
 	return res, nil
 }
 
+// upstream returns the transport that sends the requests to the real services,
+// i.e. the transport that was replaced by the mocks, wrapped by the cassette recorder if any.
+func (r *Transport) upstream() http.RoundTripper {
+	var native http.RoundTripper
+	switch {
+	case r.router != nil:
+		native = r.router.upstream()
+	case r.nativeTransport == nil || r.nativeTransport == r:
+		native = http.DefaultTransport
+	default:
+		native = r.nativeTransport
+	}
+	if r.recorder != nil {
+		return &cassetteRoundTripper{recorder: r.recorder, native: native, observed: true}
+	}
+	return native
+}
+
 // Hijack replace the transport implementation of the interaction under test in order to observe, mock and inject expectations
+// If the mocks are isolated, the transport is registered in the mock router instead.
 func (r *Transport) Hijack() {
+	if r.routeID != 0 {
+		r.router = registerMockRoute(r.httpClient, r.routeID, r)
+		return
+	}
 	if r.httpClient != nil {
 		r.httpClient.Transport = r
 		return
@@ -112,6 +179,10 @@ func (r *Transport) Hijack() {
 
 // Reset replace the hijacked transport implementation of the interaction under test to the original implementation
 func (r *Transport) Reset() {
+	if r.routeID != 0 {
+		unregisterMockRoute(r.httpClient, r.routeID)
+		return
+	}
 	if r.httpClient != nil {
 		r.httpClient.Transport = r.nativeTransport
 		return
@@ -162,8 +233,6 @@ func buildResponseFromMock(mockResponse *MockResponse) *http.Response {
 // Mock represents the entire interaction for a mock to be used for testing
 type Mock struct {
 	m *sync.Mutex
-	// state is mock runnig state
-	state *state
 	// request is used to configure the request of the mock
 	request *MockRequest
 	// resopnse is used to configure the response of the mock
@@ -172,8 +241,15 @@ type Mock struct {
 	httpClient *http.Client
 	// debugStandalone is used to enable/disable debug logging for standalone mocks
 	debugStandalone *debug
-	// execCount is used to track the number of times the mock has been executed
+	// execCount is used to track the number of times the mock has been executed against the expected number
 	execCount *execCount
+	// scenarios holds the state of the mock scenarios of the transport that serves the mock
+	scenarios *mockScenarios
+	// calls is the list of requests that matched the mock. A copy of the mock records its calls in a new
+	// list, that becomes the list of the copied mock too, so that the mock exposes the calls of its last copy.
+	calls *mockCalls
+	// spyErrors is the list of errors returned by the assertions of a spy mock on the upstream responses
+	spyErrors []error
 }
 
 // Mocks is a slice of Mock
@@ -185,15 +261,15 @@ func (mocks Mocks) len() int {
 }
 
 // findUnmatchedMocks returns a list of unmatched mocks.
-// An unmatched mock is a mock that was not used, e.g. there was not a matching http Request for the mock
+// An unmatched mock is a mock that was not used, e.g. there was not a matching http Request for the mock,
+// or that was used fewer times than expected
 func (mocks Mocks) findUnmatchedMocks() []UnmatchedMock {
 	var unmatchedMocks []UnmatchedMock
 	for _, m := range mocks {
-		if !m.state.isRunning() {
+		if m.execCount.isPending() || m.execCount.actual == 0 && m.execCount.max != 0 {
 			unmatchedMocks = append(unmatchedMocks, UnmatchedMock{
 				URL: *m.request.url,
 			})
-			break
 		}
 	}
 	return unmatchedMocks
@@ -201,6 +277,7 @@ func (mocks Mocks) findUnmatchedMocks() []UnmatchedMock {
 
 // Matches checks whether the given request matches the mock
 func (m *Mock) Matches(req *http.Request) []error {
+	req = m.request.withPathValues(req)
 	var errs []error
 	for _, matcher := range m.request.matchers {
 		if matcherError := matcher(req, m.request); matcherError != nil {
@@ -212,22 +289,49 @@ func (m *Mock) Matches(req *http.Request) []error {
 
 // deepCopy deepCopy Mock.
 func (m *Mock) deepCopy() *Mock {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.calls = &mockCalls{}
 	newMock := *m
 
 	newMock.m = &sync.Mutex{}
 
 	req := *m.request
+	req.mock = &newMock
 	newMock.request = &req
 
 	res := *m.response
+	res.mock = &newMock
 	newMock.response = &res
 
-	state := *m.state
-	newMock.state = &state
+	execCount := *m.execCount
+	newMock.execCount = &execCount
 
 	return &newMock
 }
 
+// reset forgets the executions, the calls and the spy errors of the mocks, so that they are
+// counted again from zero, e.g. by each attempt of ExpectEventually.
+func (mocks Mocks) reset() {
+	for _, mock := range mocks {
+		mock.m.Lock()
+		mock.execCount.actual = 0
+		mock.spyErrors = nil
+		mock.m.Unlock()
+		mock.calls.reset()
+	}
+}
+
+// copyMocks returns a copy of each mock, so that the executions of a mock are counted
+// separately by each test and mock server that uses it.
+func copyMocks(mocks []*Mock) Mocks {
+	m := make(Mocks, 0, len(mocks))
+	for _, mock := range mocks {
+		m = append(m, mock.deepCopy())
+	}
+	return m
+}
+
 // MockRequest represents the http request side of a mock interaction
 type MockRequest struct {
 	mock               *Mock
@@ -249,6 +353,12 @@ type MockRequest struct {
 	body               string
 	bodyRegexp         string
 	matchers           []Matcher
+	scenario           string
+	scenarioState      string
+	urlMatch           URLMatch
+	pathRegexp         string
+	hostRegexp         string
+	jsonMatch          jsonMatch
 }
 
 // newMockRequest return new MockRequest
@@ -285,6 +395,11 @@ type MockResponse struct {
 	body             string
 	statusCode       int
 	fixedDelayMillis int64
+	scenarioState    string
+	bodyTemplate     *template.Template
+	responseFunc     func(*http.Request) *http.Response
+	fault            *mockFaults
+	spy              *mockSpy
 }
 
 // newMockResponse return new MockResponse
@@ -343,8 +458,8 @@ func NewMock() *Mock {
 	mock := &Mock{
 		debugStandalone: newDebug(),
 		m:               &sync.Mutex{},
-		state:           newState(),
-		execCount:       newExecCount(1),
+		execCount:       newExecCount(),
+		calls:           &mockCalls{},
 	}
 	mock.request = newMockRequest(mock)
 	mock.response = newMockResponse(mock)
@@ -487,27 +602,49 @@ func (m *Mock) parseURL(u string) {
 	m.request.url = parsed
 }
 
-// matches checks whether the given request matches any of the given mocks
+// matches checks whether the given request matches any of the given mocks.
+// A mock that was executed the maximum number of times is skipped. If the request matches none of the other mocks
+// but matches such a mock, the execution is counted as an unexpected call of the mock, so that assertMocks reports it.
 func matches(req *http.Request, mocks Mocks) (*MockResponse, error) {
 	mockError := newUnmatchedMockError()
 	for mockNumber, mock := range mocks {
-		mock.m.Lock() // lock is for isUsed when matches is called concurrently by RoundTripper
-		if mock.state.isRunning() {
+		mock.m.Lock() // lock is for execCount when matches is called concurrently by RoundTripper
+		if mock.execCount.isExhausted() {
 			mock.m.Unlock()
 			continue
 		}
 
 		errs := mock.Matches(req)
 		if len(errs) == 0 {
-			mock.state.Start()
+			mock.execCount.actual++
+			mock.scenarios.transition(mock)
+			mock.calls.add(req)
 			mock.m.Unlock()
 			return mock.response, nil
 		}
 
-		mockError = mockError.append(mockNumber+1, errs...)
+		total := len(mock.request.matchers)
+		mockError = mockError.append(mockNumber+1, errs...).rank(mockNumber+1, total-len(errs), total)
+		mock.m.Unlock()
+	}
+
+	for mockNumber, mock := range mocks {
+		mock.m.Lock()
+		if mock.execCount.isExhausted() && len(mock.Matches(req)) == 0 {
+			total := len(mock.request.matchers)
+			mockError = mockError.append(mockNumber+1, fmt.Errorf("mock %s was already called %s, expected %s",
+				mock, formatTimes(mock.execCount.actual), mock.execCount)).rank(mockNumber+1, total, total)
+			mock.execCount.actual++
+			mock.calls.add(req)
+			mock.m.Unlock()
+			break
+		}
 		mock.m.Unlock()
 	}
 
+	if closest := mockError.closestMock(); closest > 0 {
+		mockError.closest = describeClosestMock(req, closest, mocks[closest-1], mockError.ranks[closest])
+	}
 	return nil, mockError
 }
 
@@ -519,7 +656,7 @@ func (r *MockRequest) Body(b string) *MockRequest {
 
 // BodyRegexp configures the mock request to match the given body using the regexp matcher
 func (r *MockRequest) BodyRegexp(b string) *MockRequest {
-	r.body = b
+	r.bodyRegexp = b
 	return r
 }
 
@@ -750,9 +887,35 @@ func (r *MockResponse) FixedDelay(delay int64) *MockResponse {
 	return r
 }
 
-// Times respond the given number of times
+// Times respond the given number of times. The test fails if the mock is called fewer or more times.
 func (r *MockResponse) Times(times int) *MockResponse {
-	r.mock.execCount.updateExpectCount(times)
+	r.mock.execCount.expect(times, times)
+	return r
+}
+
+// AtLeast expects the mock to be called at least the given number of times, and responds to every call.
+// It replaces the call count set by Times, AtMost, Never or AnyTimes.
+func (r *MockResponse) AtLeast(times int) *MockResponse {
+	r.mock.execCount.expect(times, unlimitedExecCount)
+	return r
+}
+
+// AtMost expects the mock to be called at most the given number of times, including not at all.
+// It replaces the call count set by Times, AtLeast, Never or AnyTimes.
+func (r *MockResponse) AtMost(times int) *MockResponse {
+	r.mock.execCount.expect(0, times)
+	return r
+}
+
+// Never expects the mock not to be called. A call of the mock does not match, and fails the test.
+func (r *MockResponse) Never() *MockResponse {
+	r.mock.execCount.expect(0, 0)
+	return r
+}
+
+// AnyTimes expects the mock to be called any number of times, including not at all
+func (r *MockResponse) AnyTimes() *MockResponse {
+	r.mock.execCount.expect(0, unlimitedExecCount)
 	return r
 }
 
@@ -802,45 +965,44 @@ func defaultMatchers() []Matcher {
 		cookieMatcher,
 		cookiePresentMatcher,
 		cookieNotPresentMatcher,
+		scenarioStateMatcher,
 	}
 }
 
 // pathMatcher compares the path of the received HTTP request with the path specified in the mock request.
-// If the paths match, it returns nil, indicating a successful match. If the paths do not match,
-// it attempts to match using regular expressions. If a match is found, it returns an error describing
-// the mismatch. If no match is found, it returns nil. The error message includes details about
-// the received path and the expected mock path that did not match.
+// The path is matched according to the URLMatch of the mock request, as a net/http pattern by default.
+// If the paths match, it returns nil. The path parameters captured by the mock path are set on the copy of
+// the request given to the matchers by Mock.Matches, not by pathMatcher.
+// The error message includes details about the received path and the expected mock path that did not match.
 func pathMatcher(r *http.Request, spec *MockRequest) error {
-	receivedPath := r.URL.Path
-	mockPath := spec.url.Path
-	if receivedPath == mockPath {
-		return nil
+	_, matched, err := spec.matchPath(r)
+	if err != nil {
+		return err
 	}
-	matched, err := regexp.MatchString(mockPath, receivedPath)
-	return errorOrNil(matched && err == nil, func() string {
-		return fmt.Sprintf("received path %s did not match mock path %s", receivedPath, mockPath)
-	})
+	if !matched {
+		return fmt.Errorf("received path %s did not match mock path %s", r.URL.Path, spec.mockPath())
+	}
+	return nil
 }
 
 // hostMatcher compares the host of the received HTTP request with the host specified in the mock request.
-// If the hosts match, it returns nil, indicating a successful match. If the hosts do not match,
-// it attempts to match using regular expressions. If a match is found, it returns an error describing
-// the mismatch. If no match is found, it returns nil. The error message includes details about
+// The host is matched according to the URLMatch of the mock request, exactly by default.
+// If the mock request has no host, it matches any host. The error message includes details about
 // the received host and the expected mock host that did not match.
 func hostMatcher(r *http.Request, spec *MockRequest) error {
 	receivedHost := r.Host
 	if receivedHost == "" {
 		receivedHost = r.URL.Host
 	}
-	mockHost := spec.url.Host
+	mockHost := spec.mockHost()
 	if mockHost == "" {
 		return nil
 	}
-	if receivedHost == mockHost {
-		return nil
+	matched, err := spec.matchHost(receivedHost)
+	if err != nil {
+		return err
 	}
-	matched, err := regexp.MatchString(mockHost, r.URL.Path)
-	return errorOrNil(matched && err != nil, func() string {
+	return errorOrNil(matched, func() string {
 		return fmt.Sprintf("received host %s did not match mock host %s", receivedHost, mockHost)
 	})
 }
@@ -1164,6 +1326,17 @@ func bodyMatcher(req *http.Request, spec *MockRequest) error {
 		return nil
 	}
 
+	if spec.jsonMatch.isEnabled(mockBody) && json.Valid([]byte(mockBody)) {
+		diffs, err := spec.jsonMatch.match([]byte(mockBody), body)
+		if err != nil {
+			return err
+		}
+		if len(diffs) > 0 {
+			return fmt.Errorf("received body did not match expected mock body\n%s", joinJSONDiffs(diffs))
+		}
+		return nil
+	}
+
 	// Perform JSON match
 	var reqJSON interface{}
 	reqJSONErr := json.Unmarshal(body, &reqJSON)
@@ -1176,11 +1349,7 @@ func bodyMatcher(req *http.Request, spec *MockRequest) error {
 		return nil
 	}
 
-	if isJSON {
-		return fmt.Errorf("received body did not match expected mock body\n%s", diff(matchJSON, reqJSON))
-	}
-
-	return fmt.Errorf("received body did not match expected mock body\n%s", diff(mockBody, bodyStr))
+	return fmt.Errorf("received body did not match expected mock body\n%s", bodyDiff(mockBody, bodyStr))
 }
 
 // bodyRegexpMatcher checks if the body of the received HTTP request matches the regular expression specified in the mock request.
@@ -1290,49 +1459,66 @@ func (r *mockInteraction) GetRequestHost() string {
 	return host
 }
 
+// unlimitedExecCount is the maximum number of executions of a mock that can be called any number of times
+const unlimitedExecCount = -1
+
 // execCount is used to track the number of times a mock has been executed.
 type execCount struct {
-	// expect is the expected number of times the mock will be executed.
-	expect int
-	// actual is the actual number of times the mock has been executed.
+	// min is the minimum expected number of times the mock will be executed.
+	min int
+	// max is the maximum expected number of times the mock will be executed. unlimitedExecCount disables the maximum.
+	max int
+	// actual is the actual number of times the mock has been executed, including the unexpected calls.
 	actual int
 }
 
-// newExecCount creates a new execCount with the given expected number of executions.
-func newExecCount(expect int) *execCount {
-	return &execCount{expect: expect}
+// newExecCount creates a new execCount of a mock that responds once, and that is not required to be executed.
+func newExecCount() *execCount {
+	return &execCount{min: 0, max: 1}
 }
 
-// updateExpectCount updates the expected number of executions.
-func (e *execCount) updateExpectCount(expect int) {
-	e.expect = expect
+// expect updates the expected number of executions.
+func (e *execCount) expect(min, max int) {
+	e.min, e.max = min, max
 }
 
-// isComplete returns true if the actual number of executions matches the expected number of executions.
-func (e *execCount) isComplete() bool {
-	return e.actual == e.expect
+// isExhausted returns true if the mock was executed the maximum number of times.
+func (e *execCount) isExhausted() bool {
+	return e.max != unlimitedExecCount && e.actual >= e.max
 }
 
-// state is used to track the state of a mock. It's very simple state machine
-type state struct {
-	running bool
+// isPending returns true if the mock was executed fewer times than the minimum.
+func (e *execCount) isPending() bool {
+	return e.actual < e.min
 }
 
-func newState() *state {
-	return &state{}
+// isSatisfied returns true if the actual number of executions is within the expected number of executions.
+func (e *execCount) isSatisfied() bool {
+	return !e.isPending() && (e.max == unlimitedExecCount || e.actual <= e.max)
 }
 
-// Start sets the state to running.
-func (s *state) Start() {
-	s.running = true
-}
-
-// Stop sets the state to not running.
-func (s *state) Stop() {
-	s.running = false
+// String describes the expected number of executions, e.g. "at least 2 times"
+func (e *execCount) String() string {
+	switch {
+	case e.max == 0:
+		return "never"
+	case e.min == 0 && e.max == unlimitedExecCount:
+		return "any number of times"
+	case e.max == unlimitedExecCount:
+		return "at least " + formatTimes(e.min)
+	case e.min == 0:
+		return "at most " + formatTimes(e.max)
+	case e.min == e.max:
+		return formatTimes(e.min)
+	default:
+		return fmt.Sprintf("between %d and %s", e.min, formatTimes(e.max))
+	}
 }
 
-// isRunning returns true if the state is running.
-func (s *state) isRunning() bool {
-	return s.running
+// formatTimes formats a number of executions, e.g. "1 time" or "3 times"
+func formatTimes(n int) string {
+	if n == 1 {
+		return "1 time"
+	}
+	return fmt.Sprintf("%d times", n)
 }
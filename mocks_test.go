@@ -644,7 +644,7 @@ func TestMocksBodyMatcherRegexp(t *testing.T) {
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("body=%v", test.matchBody), func(t *testing.T) {
 			req := httptest.NewRequest(http.MethodGet, "/path", strings.NewReader(test.requestBody))
-			matchError := bodyRegexpMatcher(req, NewMock().Get("/path").Body(test.matchBody))
+			matchError := bodyRegexpMatcher(req, NewMock().Get("/path").BodyRegexp(test.matchBody))
 			assert.Equal(t, test.expectedError, matchError)
 		})
 	}
@@ -688,7 +688,7 @@ func TestMocksPathMatcher(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.pathToMatch, func(t *testing.T) {
 			req := httptest.NewRequest(http.MethodGet, test.requestURL, nil)
-			matchError := pathMatcher(req, NewMock().Get(test.pathToMatch))
+			matchError := pathMatcher(req, NewMock().Get("").PathRegexp(test.pathToMatch))
 			if matchError != nil && !reflect.DeepEqual(matchError, test.expectedError) {
 				t.Fatalf("methodToMatch='%s' requestUrl='%s' shouldMatch=%v",
 					test.pathToMatch, test.requestURL, matchError)
@@ -714,9 +714,11 @@ func TestMocksAddMatcher(t *testing.T) {
 		"no match": {
 			matcherResponse: errors.New("nope"),
 			mockResponse:    nil,
-			matchErrors: &unmatchedMockError{errors: map[int][]error{
-				1: {errors.New("nope")},
-			}},
+			matchErrors: &unmatchedMockError{
+				errors:  map[int][]error{1: {errors.New("nope")}},
+				ranks:   map[int]mockRank{1: {passed: len(defaultMatchers()), total: len(defaultMatchers()) + 1}},
+				closest: fmt.Sprintf("Closest mock is mock 1 (GET /test/mock), %d of %d matchers passed\n", len(defaultMatchers()), len(defaultMatchers())+1),
+			},
 		},
 	}
 	for name, test := range tests {
@@ -819,7 +821,7 @@ func TestMocksMatchesErrors(t *testing.T) {
 	mockResponse, matchErrors := matches(req, Mocks{testMock})
 
 	assert.Equal(t, true, mockResponse == nil)
-	assert.Equal(t, &unmatchedMockError{errors: map[int][]error{
+	assert.Equal(t, map[int][]error{
 		1: {
 			errors.New("received method GET did not match mock method POST"),
 			errors.New("not all of received headers map[] matched expected mock headers map[Headerkey:[headerVal headerVal]]"),
@@ -827,7 +829,9 @@ func TestMocksMatchesErrors(t *testing.T) {
 			errors.New("expected query param queryKey2 not received"),
 			errors.New("expected a body but received none"),
 		},
-	}}, matchErrors)
+	}, matchErrors.(*unmatchedMockError).errors)
+	assert.Equal(t, map[int]mockRank{1: {passed: len(defaultMatchers()) - 5, total: len(defaultMatchers())}},
+		matchErrors.(*unmatchedMockError).ranks)
 }
 
 func TestMocksMatchesNilIfNoMatch(t *testing.T) {
@@ -869,10 +873,31 @@ func TestMocksMatchesErrorsMatchUnmatchedMocks(t *testing.T) {
 	}
 
 	assert.Equal(t, true, matchErrors != nil)
-	assert.Equal(t, "received request did not match any mocks\n\nMock 1 mismatches:\n• received path /preferences/12345 did not match mock path /preferences/123456\n\n",
+	assert.Equal(t, "received request did not match any mocks\n\n"+
+		"Closest mock is mock 1 (GET /preferences/123456), 19 of 20 matchers passed\n"+
+		"path:\n--- Expected\n+++ Actual\n@@ -1 +1 @@\n-/preferences/123456\n+/preferences/12345\n\n"+
+		"Mock 1 mismatches (19 of 20 matchers passed):\n• received path /preferences/12345 did not match mock path /preferences/123456\n\n",
 		matchErrors.Error())
 }
 
+func TestMocksMatchesErrorsRankClosestMockFirst(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/users?page=2", nil)
+
+	_, matchErrors := matches(req, Mocks{
+		NewMock().Post("http://example.com/orders").Header("X-Api-Key", "secret").RespondWith().End(),
+		NewMock().Get("http://example.com/users").Query("page", "1").RespondWith().End(),
+		NewMock().Post("http://example.com/users").Query("page", "1").RespondWith().End(),
+	})
+
+	assert.Equal(t, []int{2, 3, 1}, matchErrors.(*unmatchedMockError).orderedMockKeys())
+	message := matchErrors.Error()
+	assert.True(t, strings.HasPrefix(message, "received request did not match any mocks\n\n"+
+		"Closest mock is mock 2 (GET http://example.com/users), 19 of 20 matchers passed\nquery:\n"))
+	assert.True(t, strings.Contains(message, "-  (string) (len=1) \"1\"\n+  (string) (len=1) \"2\"\n"))
+	assert.True(t, strings.Index(message, "Mock 2 mismatches") < strings.Index(message, "Mock 3 mismatches"))
+	assert.True(t, strings.Index(message, "Mock 3 mismatches") < strings.Index(message, "Mock 1 mismatches"))
+}
+
 func TestMocksMethodMatcher(t *testing.T) {
 	tests := []struct {
 		requestMethod string
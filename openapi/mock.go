@@ -0,0 +1,342 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nao1215/spectest"
+)
+
+// maxExampleDepth is the maximum depth of the nested schemas when a response is synthesized,
+// so that recursive schemas terminate.
+const maxExampleDepth = 8
+
+// MockOverride replaces the generated response of an operation
+type MockOverride struct {
+	// Operation is the operationId or the "METHOD /path" of the operation, e.g. "GET /users/{id}"
+	Operation string
+	// Respond configures the response of the mock with the MockResponse builder
+	Respond func(*spectest.MockResponse)
+}
+
+// Override returns a MockOverride that replaces the generated response of the operation.
+// The operation is the operationId or the "METHOD /path" of the operation.
+func Override(operation string, respond func(*spectest.MockResponse)) MockOverride {
+	return MockOverride{Operation: operation, Respond: respond}
+}
+
+// Mocks generates one mock per operation of the document. The response of each mock is taken from
+// the examples of the first successful response, or synthesized from its schema.
+// The mocks answer any number of calls, unless an override sets MockResponse.Times.
+// The baseURL is the scheme, host and optional base path of the mocked service, e.g. https://users.example.com/v1.
+// If baseURL is empty, the first url of the servers section is used.
+// Path templates such as /users/{id} match any value of the path parameter.
+// Mocks panics if an override does not match any operation of the document.
+func (d *Document) Mocks(baseURL string, overrides ...MockOverride) spectest.Mocks {
+	if baseURL == "" {
+		baseURL = d.serverURL()
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		panic(err)
+	}
+	basePath := strings.TrimSuffix(base.Path, "/")
+	base.Path, base.RawPath, base.RawQuery = "", "", ""
+
+	respond := map[string]func(*spectest.MockResponse){}
+	unused := map[string]bool{}
+	for _, o := range overrides {
+		respond[o.Operation] = o.Respond
+		unused[o.Operation] = true
+	}
+
+	// the most specific templates come first, e.g. /users/me before /users/{id}
+	operations := append([]*Operation{}, d.operations...)
+	sort.SliceStable(operations, func(i, j int) bool {
+		return len(operations[i].pathParameters) < len(operations[j].pathParameters)
+	})
+
+	mocks := make(spectest.Mocks, 0, len(operations))
+	for _, op := range operations {
+		template := basePath + op.Path
+		pattern, _ := compilePathTemplate(template)
+		// the url holds the path template, so that the mock is described by its operation
+		res := newMockRequest(op.Method, base.String()+template).
+			PathRegexp(pattern.String()).
+			RespondWith().
+			AnyTimes()
+
+		if fn, ok := respond[op.ID]; ok && op.ID != "" {
+			delete(unused, op.ID)
+			fn(res)
+		} else if fn, ok := respond[op.Method+" "+op.Path]; ok {
+			delete(unused, op.Method+" "+op.Path)
+			fn(res)
+		} else {
+			d.respondWithExample(op, res)
+		}
+		mocks = append(mocks, res.End())
+	}
+	for _, o := range overrides {
+		if unused[o.Operation] {
+			panic(fmt.Errorf("openapi override %q does not match any operationId or \"METHOD /path\" of the document", o.Operation))
+		}
+	}
+	return mocks
+}
+
+// newMockRequest creates a new mock request for the method and url.
+func newMockRequest(method, u string) *spectest.MockRequest {
+	mock := spectest.NewMock()
+	switch method {
+	case http.MethodGet:
+		return mock.Get(u)
+	case http.MethodPut:
+		return mock.Put(u)
+	case http.MethodPost:
+		return mock.Post(u)
+	case http.MethodDelete:
+		return mock.Delete(u)
+	case http.MethodOptions:
+		return mock.Options(u)
+	case http.MethodHead:
+		return mock.Head(u)
+	case http.MethodPatch:
+		return mock.Patch(u)
+	default:
+		return mock.Trace(u)
+	}
+}
+
+// serverURL returns the first url of the servers section.
+func (d *Document) serverURL() string {
+	servers, _ := d.raw["servers"].([]interface{})
+	for _, server := range servers {
+		s, _ := server.(map[string]interface{})
+		if u, ok := s["url"].(string); ok {
+			return u
+		}
+	}
+	return ""
+}
+
+// respondWithExample configures the response from the first successful response of the operation.
+func (d *Document) respondWithExample(op *Operation, res *spectest.MockResponse) {
+	responses, _ := op.raw["responses"].(map[string]interface{})
+	status, key := successResponse(responses)
+	res.Status(status)
+	if key == "" {
+		return
+	}
+	response, _, err := d.resolve(responses[key], "")
+	if err != nil {
+		return
+	}
+
+	headers, _ := response["headers"].(map[string]interface{})
+	for _, name := range sortedKeys(headers) {
+		header, _, err := d.resolve(headers[name], "")
+		if err != nil || strings.EqualFold(name, "Content-Type") {
+			continue
+		}
+		if value, ok := d.example(header, 0); ok {
+			res.Header(name, fmt.Sprint(value))
+		}
+	}
+
+	content, _ := response["content"].(map[string]interface{})
+	contentTypes := sortedKeys(content)
+	if len(contentTypes) == 0 {
+		return
+	}
+	contentType := contentTypes[0]
+	for _, ct := range contentTypes {
+		if isJSON(ct, "") {
+			contentType = ct
+			break
+		}
+	}
+	mediaType, _ := content[contentType].(map[string]interface{})
+	res.Header("Content-Type", contentType)
+	value, ok := d.example(mediaType, 0)
+	if !ok {
+		return
+	}
+	if s, isString := value.(string); isString && !isJSON(contentType, "") {
+		res.Body(s)
+		return
+	}
+	body, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	res.Body(string(body))
+}
+
+// successResponse returns the status code and the key of the first successful response.
+// If there is none, the default response is used with 200.
+func successResponse(responses map[string]interface{}) (int, string) {
+	for _, key := range sortedKeys(responses) {
+		if code, err := strconv.Atoi(key); err == nil && code >= 200 && code < 300 {
+			return code, key
+		}
+	}
+	for _, key := range []string{"2XX", "2xx", "default"} {
+		if _, ok := responses[key]; ok {
+			return http.StatusOK, key
+		}
+	}
+	return http.StatusOK, ""
+}
+
+// example returns the example of the media type, parameter or header object,
+// or the value synthesized from its schema.
+func (d *Document) example(object map[string]interface{}, depth int) (interface{}, bool) {
+	if value, ok := object["example"]; ok {
+		return value, true
+	}
+	if examples, ok := object["examples"].(map[string]interface{}); ok {
+		for _, name := range sortedKeys(examples) {
+			example, _, err := d.resolve(examples[name], "")
+			if err != nil {
+				continue
+			}
+			if value, ok := example["value"]; ok {
+				return value, true
+			}
+		}
+	}
+	if _, ok := object["schema"]; !ok {
+		return nil, false
+	}
+	schema, _, err := d.resolve(object["schema"], "")
+	if err != nil {
+		return nil, false
+	}
+	return d.synthesize(schema, depth), true
+}
+
+// synthesize returns a value that conforms to the schema.
+func (d *Document) synthesize(schema map[string]interface{}, depth int) interface{} {
+	if value, ok := schema["example"]; ok {
+		return value
+	}
+	if examples, ok := schema["examples"].([]interface{}); ok && len(examples) > 0 {
+		return examples[0]
+	}
+	if value, ok := schema["default"]; ok {
+		return value
+	}
+	if values, ok := schema["enum"].([]interface{}); ok && len(values) > 0 {
+		return values[0]
+	}
+	if value, ok := schema["const"]; ok {
+		return value
+	}
+	if depth > maxExampleDepth {
+		return nil
+	}
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		merged := map[string]interface{}{}
+		for _, s := range allOf {
+			resolved, _, err := d.resolve(s, "")
+			if err != nil {
+				continue
+			}
+			if object, ok := d.synthesize(resolved, depth+1).(map[string]interface{}); ok {
+				for k, v := range object {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+	for _, keyword := range []string{"oneOf", "anyOf"} {
+		if schemas, ok := schema[keyword].([]interface{}); ok && len(schemas) > 0 {
+			if resolved, _, err := d.resolve(schemas[0], ""); err == nil {
+				return d.synthesize(resolved, depth+1)
+			}
+		}
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		return d.synthesizeObject(schema, depth)
+	case "array":
+		items, _, err := d.resolve(schema["items"], "")
+		if err != nil {
+			return []interface{}{}
+		}
+		return []interface{}{d.synthesize(items, depth+1)}
+	case "integer":
+		if minimum, ok := schema["minimum"].(float64); ok {
+			return int64(minimum)
+		}
+		if minimum, ok := schema["minimum"].(int); ok {
+			return minimum
+		}
+		return 0
+	case "number":
+		if minimum, ok := schema["minimum"]; ok {
+			return minimum
+		}
+		return 0.0
+	case "boolean":
+		return true
+	case "string":
+		return synthesizeString(schema)
+	}
+	if _, ok := schema["properties"]; ok {
+		return d.synthesizeObject(schema, depth)
+	}
+	return nil
+}
+
+// synthesizeObject returns an object with all the properties of the schema.
+func (d *Document) synthesizeObject(schema map[string]interface{}, depth int) map[string]interface{} {
+	object := map[string]interface{}{}
+	properties, _ := schema["properties"].(map[string]interface{})
+	for _, name := range sortedKeys(properties) {
+		property, _, err := d.resolve(properties[name], "")
+		if err != nil {
+			continue
+		}
+		object[name] = d.synthesize(property, depth+1)
+	}
+	return object
+}
+
+// synthesizeString returns a string that conforms to the format of the schema.
+func synthesizeString(schema map[string]interface{}) string {
+	format, _ := schema["format"].(string)
+	switch format {
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "date":
+		return "2024-01-01"
+	case "time":
+		return "00:00:00"
+	case "email":
+		return "user@example.com"
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000000"
+	case "uri", "url":
+		return "https://example.com"
+	case "hostname":
+		return "example.com"
+	case "ipv4":
+		return "127.0.0.1"
+	case "ipv6":
+		return "::1"
+	}
+	value := "string"
+	if minLength, ok := schema["minLength"].(int); ok && minLength > len(value) {
+		value += strings.Repeat("x", minLength-len(value))
+	}
+	return value
+}
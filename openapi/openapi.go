@@ -0,0 +1,381 @@
+// Package openapi provides spectest.Assert functions to validate the http request and response
+// against an OpenAPI 3 document.
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+// documentURL is the url of the document in the schema pool. Schemas are compiled as
+// references into the document, so that $ref in the document are resolved.
+const documentURL = "file:///openapi.json"
+
+// Document is an OpenAPI 3 document
+type Document struct {
+	// raw is the decoded document
+	raw map[string]interface{}
+	// basePaths is the list of path prefixes from the servers section
+	basePaths []string
+	// operations is the list of operations of the document
+	operations []*Operation
+
+	// json is the document encoded as JSON
+	json []byte
+
+	// mu guards schemas
+	mu sync.Mutex
+	// schemas is the cache of compiled schemas by json pointer
+	schemas map[string]*gojsonschema.Schema
+}
+
+// Operation is an operation of an OpenAPI document
+type Operation struct {
+	// Method is the http method in upper case, e.g. GET
+	Method string
+	// Path is the path template, e.g. /pets/{id}
+	Path string
+	// ID is the operationId
+	ID string
+
+	// raw is the operation object
+	raw map[string]interface{}
+	// pointer is the json pointer of the operation object
+	pointer string
+	// pattern is the regular expression matching the path template
+	pattern *regexp.Regexp
+	// pathParameters is the list of path parameter names in the order of the path template
+	pathParameters []string
+	// parameters is the list of parameters of the path item and the operation
+	parameters []*parameter
+}
+
+// parameter is a parameter of an operation
+type parameter struct {
+	// name is the name of the parameter
+	name string
+	// in is the location of the parameter: path, query, header or cookie
+	in string
+	// required is true if the parameter is required
+	required bool
+	// explode is true if the array values are given as separate values
+	explode bool
+	// schema is the resolved schema of the parameter
+	schema map[string]interface{}
+	// schemaPointer is the json pointer of the schema
+	schemaPointer string
+}
+
+// methods is the list of operation keys of a path item
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Load reads the OpenAPI document from the given YAML or JSON file
+func Load(path string) (*Document, error) {
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
+
+// Parse parses the OpenAPI document from YAML or JSON
+func Parse(data []byte) (*Document, error) {
+	var decoded interface{}
+	if err := yaml.Unmarshal(data, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to parse openapi document: %w", err)
+	}
+	raw, ok := normalize(decoded).(map[string]interface{})
+	if !ok {
+		return nil, errors.New("failed to parse openapi document: document is not an object")
+	}
+	if _, ok := raw["openapi"]; !ok {
+		return nil, errors.New("failed to parse openapi document: openapi version is not defined")
+	}
+	convertNullable(raw)
+
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{
+		raw:     raw,
+		json:    b,
+		schemas: map[string]*gojsonschema.Schema{},
+	}
+	doc.basePaths = doc.parseBasePaths()
+	if err := doc.parseOperations(); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Operations returns the list of operations of the document sorted by path and method
+func (d *Document) Operations() []*Operation {
+	return d.operations
+}
+
+// parseBasePaths returns the path prefixes of the servers section.
+func (d *Document) parseBasePaths() []string {
+	servers, _ := d.raw["servers"].([]interface{})
+	basePaths := []string{}
+	for _, server := range servers {
+		s, _ := server.(map[string]interface{})
+		rawURL, _ := s["url"].(string)
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			continue
+		}
+		if basePath := strings.TrimSuffix(u.Path, "/"); basePath != "" {
+			basePaths = append(basePaths, basePath)
+		}
+	}
+	return basePaths
+}
+
+// parseOperations collects the operations of the paths section.
+func (d *Document) parseOperations() error {
+	paths, _ := d.raw["paths"].(map[string]interface{})
+	for path, rawItem := range paths {
+		pathPointer := "#/paths/" + escapePointer(path)
+		item, itemPointer, err := d.resolve(rawItem, pathPointer)
+		if err != nil {
+			return err
+		}
+		itemParameters, err := d.parseParameters(item["parameters"], itemPointer+"/parameters")
+		if err != nil {
+			return err
+		}
+		for _, method := range methods {
+			rawOp, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			opPointer := itemPointer + "/" + method
+			opParameters, err := d.parseParameters(rawOp["parameters"], opPointer+"/parameters")
+			if err != nil {
+				return err
+			}
+			id, _ := rawOp["operationId"].(string)
+			pattern, names := compilePathTemplate(path)
+			d.operations = append(d.operations, &Operation{
+				Method:         strings.ToUpper(method),
+				Path:           path,
+				ID:             id,
+				raw:            rawOp,
+				pointer:        opPointer,
+				pattern:        pattern,
+				pathParameters: names,
+				parameters:     mergeParameters(itemParameters, opParameters),
+			})
+		}
+	}
+	sort.Slice(d.operations, func(i, j int) bool {
+		if d.operations[i].Path != d.operations[j].Path {
+			return d.operations[i].Path < d.operations[j].Path
+		}
+		return d.operations[i].Method < d.operations[j].Method
+	})
+	return nil
+}
+
+// parseParameters parses the parameters list at the given pointer.
+func (d *Document) parseParameters(rawParameters interface{}, pointer string) ([]*parameter, error) {
+	list, _ := rawParameters.([]interface{})
+	parameters := make([]*parameter, 0, len(list))
+	for i, rawParameter := range list {
+		p, paramPointer, err := d.resolve(rawParameter, fmt.Sprintf("%s/%d", pointer, i))
+		if err != nil {
+			return nil, err
+		}
+		name, _ := p["name"].(string)
+		in, _ := p["in"].(string)
+		required, _ := p["required"].(bool)
+		explode, ok := p["explode"].(bool)
+		if !ok {
+			style, _ := p["style"].(string)
+			explode = style == "form" || (style == "" && (in == "query" || in == "cookie"))
+		}
+		param := &parameter{
+			name:     name,
+			in:       in,
+			required: required || in == "path",
+			explode:  explode,
+		}
+		if _, ok := p["schema"]; ok {
+			schema, schemaPointer, err := d.resolve(p["schema"], paramPointer+"/schema")
+			if err != nil {
+				return nil, err
+			}
+			param.schema = schema
+			param.schemaPointer = schemaPointer
+		}
+		parameters = append(parameters, param)
+	}
+	return parameters, nil
+}
+
+// mergeParameters returns the path item parameters overridden by the operation parameters.
+func mergeParameters(itemParameters, opParameters []*parameter) []*parameter {
+	merged := []*parameter{}
+	for _, p := range itemParameters {
+		overridden := false
+		for _, o := range opParameters {
+			if o.name == p.name && o.in == p.in {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			merged = append(merged, p)
+		}
+	}
+	return append(merged, opParameters...)
+}
+
+// resolve follows the $ref of the given node. It returns the resolved object and its json pointer.
+func (d *Document) resolve(node interface{}, pointer string) (map[string]interface{}, string, error) {
+	for depth := 0; depth < 32; depth++ {
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, pointer, fmt.Errorf("%s is not an object", pointer)
+		}
+		ref, ok := object["$ref"].(string)
+		if !ok {
+			return object, pointer, nil
+		}
+		if !strings.HasPrefix(ref, "#/") {
+			return nil, pointer, fmt.Errorf("%s: only local references are supported: %s", pointer, ref)
+		}
+		node, pointer = d.lookup(ref), ref
+		if node == nil {
+			return nil, pointer, fmt.Errorf("reference %s not found", ref)
+		}
+	}
+	return nil, pointer, fmt.Errorf("%s: too many nested references", pointer)
+}
+
+// lookup returns the node at the given local json pointer, e.g. #/components/schemas/Pet
+func (d *Document) lookup(pointer string) interface{} {
+	var node interface{} = d.raw
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "#/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch n := node.(type) {
+		case map[string]interface{}:
+			node = n[token]
+		case []interface{}:
+			var i int
+			if _, err := fmt.Sscanf(token, "%d", &i); err != nil || i < 0 || i >= len(n) {
+				return nil
+			}
+			node = n[i]
+		default:
+			return nil
+		}
+	}
+	return node
+}
+
+// schema returns the compiled schema at the given local json pointer.
+func (d *Document) schema(pointer string) (*gojsonschema.Schema, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if schema, ok := d.schemas[pointer]; ok {
+		return schema, nil
+	}
+	ref, err := json.Marshal(map[string]string{"$ref": documentURL + pointer})
+	if err != nil {
+		return nil, err
+	}
+	// a schema loader can compile only one schema without id, so each schema gets its own loader
+	loader := gojsonschema.NewSchemaLoader()
+	if err := loader.AddSchema(documentURL, gojsonschema.NewBytesLoader(d.json)); err != nil {
+		return nil, fmt.Errorf("failed to load openapi document: %w", err)
+	}
+	schema, err := loader.Compile(gojsonschema.NewBytesLoader(ref))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile schema %s: %w", pointer, err)
+	}
+	d.schemas[pointer] = schema
+	return schema, nil
+}
+
+// normalize converts the YAML maps to map[string]interface{}. YAML allows non string keys,
+// e.g. status codes, that are not supported by JSON.
+func normalize(node interface{}) interface{} {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for k, v := range n {
+			n[k] = normalize(v)
+		}
+		return n
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(n))
+		for k, v := range n {
+			m[fmt.Sprint(k)] = normalize(v)
+		}
+		return m
+	case []interface{}:
+		for i, v := range n {
+			n[i] = normalize(v)
+		}
+		return n
+	}
+	return node
+}
+
+// convertNullable converts the OpenAPI 3.0 nullable keyword to a JSON schema type list.
+func convertNullable(node interface{}) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if nullable, _ := n["nullable"].(bool); nullable {
+			if typ, ok := n["type"].(string); ok {
+				n["type"] = []interface{}{typ, "null"}
+			}
+		}
+		for _, v := range n {
+			convertNullable(v)
+		}
+	case []interface{}:
+		for _, v := range n {
+			convertNullable(v)
+		}
+	}
+}
+
+// escapePointer escapes the json pointer token.
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// pathTemplateParameter matches a parameter of a path template, e.g. {id}
+var pathTemplateParameter = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// compilePathTemplate returns the regular expression matching the path template
+// and the names of the path parameters.
+func compilePathTemplate(path string) (*regexp.Regexp, []string) {
+	names := []string{}
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	last := 0
+	for _, loc := range pathTemplateParameter.FindAllStringSubmatchIndex(path, -1) {
+		pattern.WriteString(regexp.QuoteMeta(path[last:loc[0]]))
+		pattern.WriteString("([^/]+)")
+		names = append(names, path[loc[2]:loc[3]])
+		last = loc[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(path[last:]))
+	pattern.WriteString("$")
+	return regexp.MustCompile(pattern.String()), names
+}
@@ -0,0 +1,353 @@
+package openapi_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/nao1215/spectest"
+	"github.com/nao1215/spectest/mocks"
+	"github.com/nao1215/spectest/openapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func loadPetstore(t *testing.T) *openapi.Document {
+	t.Helper()
+	doc, err := openapi.Load("testdata/petstore.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func petstoreHandler() http.Handler {
+	handler := http.NewServeMux()
+	handler.HandleFunc("GET /v1/pets", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Total-Count", "1")
+		_, _ = w.Write([]byte(`[{"id": 1, "name": "Tama", "tag": null}]`))
+	})
+	handler.HandleFunc("POST /v1/pets", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": 2, "name": "Pochi"}`))
+	})
+	handler.HandleFunc("GET /v1/pets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.PathValue("id") == "9" {
+			_, _ = w.Write([]byte(`{"id": "9"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message": "not found"}`))
+	})
+	return handler
+}
+
+// violationsVerifier returns a verifier that captures the violations of the contract
+func violationsVerifier(violations *[]string) *mocks.MockVerifier {
+	verifier := mocks.NewVerifier()
+	verifier.NoErrorFn = func(t spectest.TestingT, err error, msgAndArgs ...interface{}) bool {
+		var verr *openapi.ValidationError
+		if errors.As(err, &verr) {
+			for _, v := range verr.Violations {
+				*violations = append(*violations, v.String())
+			}
+		}
+		return err == nil
+	}
+	return verifier
+}
+
+func TestValidateConformingRequestAndResponse(t *testing.T) {
+	doc := loadPetstore(t)
+
+	spectest.New().
+		Handler(petstoreHandler()).
+		Contract(doc.Validate()).
+		Get("/v1/pets").
+		Query("limit", "10").
+		Query("tags", "cat").
+		Query("tags", "dog").
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+
+	spectest.New().
+		Handler(petstoreHandler()).
+		Contract(doc.Validate()).
+		Post("/v1/pets").
+		Header("X-Request-Id", "abc").
+		JSON(`{"name": "Pochi"}`).
+		Expect(t).
+		Status(http.StatusCreated).
+		End()
+
+	spectest.New().
+		Handler(petstoreHandler()).
+		Get("/v1/pets/1").
+		Expect(t).
+		Status(http.StatusNotFound).
+		Assert(doc.ValidateResponse()).
+		End()
+}
+
+func TestValidateReportsRequestViolations(t *testing.T) {
+	var violations []string
+	doc := loadPetstore(t)
+
+	spectest.New().
+		Handler(petstoreHandler()).
+		Verifier(violationsVerifier(&violations)).
+		Contract(doc.ValidateRequest()).
+		Post("/v1/pets").
+		JSON(`{"name": 1}`).
+		Expect(t).
+		End()
+
+	assert.Equal(t, []string{
+		"header parameter 'X-Request-Id': is required",
+		"request body: name: Invalid type. Expected: string, given: integer",
+	}, violations)
+}
+
+func TestValidateReportsParameterViolations(t *testing.T) {
+	var violations []string
+	doc := loadPetstore(t)
+
+	spectest.New().
+		Handler(petstoreHandler()).
+		Verifier(violationsVerifier(&violations)).
+		Contract(doc.ValidateRequest()).
+		Get("/v1/pets").
+		Query("limit", "1000").
+		Expect(t).
+		End()
+
+	spectest.New().
+		Handler(petstoreHandler()).
+		Verifier(violationsVerifier(&violations)).
+		Contract(doc.ValidateRequest()).
+		Get("/v1/pets/abc").
+		Expect(t).
+		End()
+
+	assert.Equal(t, []string{
+		"query parameter 'limit': Must be less than or equal to 100",
+		"path parameter 'id': Invalid type. Expected: integer, given: string",
+	}, violations)
+}
+
+func TestValidateReportsResponseViolations(t *testing.T) {
+	var violations []string
+	doc := loadPetstore(t)
+
+	spectest.New().
+		Handler(petstoreHandler()).
+		Verifier(violationsVerifier(&violations)).
+		Contract(doc.ValidateResponse()).
+		Get("/v1/pets/9").
+		Expect(t).
+		End()
+
+	assert.Equal(t, []string{
+		"response body: name is required",
+		"response body: id: Invalid type. Expected: integer, given: string",
+		"response body: Must validate all the schemas (allOf)",
+	}, violations)
+}
+
+func TestValidateReportsUndocumentedOperation(t *testing.T) {
+	doc := loadPetstore(t)
+
+	cases := []struct {
+		method string
+		path   string
+		status int
+		want   string
+	}{
+		{http.MethodGet, "/v1/owners", http.StatusOK, "operation: path /v1/owners is not documented"},
+		{http.MethodDelete, "/v1/pets", http.StatusOK, "operation: method DELETE is not documented for path /v1/pets"},
+		{http.MethodGet, "/v1/pets/mine", http.StatusTeapot, "status: status code 418 is not documented"},
+	}
+	for _, c := range cases {
+		t.Run(c.method+" "+c.path, func(t *testing.T) {
+			req, err := http.NewRequest(c.method, c.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = doc.Validate()(&http.Response{StatusCode: c.status, Header: http.Header{}}, req)
+			var verr *openapi.ValidationError
+			if assert.ErrorAs(t, err, &verr) {
+				assert.Equal(t, c.want, verr.Violations[0].String())
+				assert.True(t, strings.HasPrefix(err.Error(), "openapi contract violation for "+c.method+" "+c.path))
+			}
+		})
+	}
+}
+
+func TestFindOperationPrefersLiteralPath(t *testing.T) {
+	doc := loadPetstore(t)
+
+	op, values, violation := doc.FindOperation(http.MethodGet, "/pets/mine")
+	assert.Nil(t, violation)
+	assert.Equal(t, "getMyPet", op.ID)
+	assert.Empty(t, values)
+
+	op, values, violation = doc.FindOperation(http.MethodGet, "/v1/pets/42")
+	assert.Nil(t, violation)
+	assert.Equal(t, "getPet", op.ID)
+	assert.Equal(t, map[string]string{"id": "42"}, values)
+}
+
+func TestParseFailsWithoutOpenAPIVersion(t *testing.T) {
+	_, err := openapi.Parse([]byte(`{"paths": {}}`))
+	assert.EqualError(t, err, "failed to parse openapi document: openapi version is not defined")
+}
+
+// downstreamHandler returns a handler that proxies the request to the petstore
+func downstreamHandler(t *testing.T) http.HandlerFunc {
+	t.Helper()
+	return func(w http.ResponseWriter, r *http.Request) {
+		res, err := http.Get("https://petstore.example.com/v1" + r.URL.Path)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		w.Header().Set("X-Downstream-Content-Type", res.Header.Get("Content-Type"))
+		w.Header().Set("X-Downstream-Total-Count", res.Header.Get("X-Total-Count"))
+		w.WriteHeader(res.StatusCode)
+		_, _ = w.Write(body)
+	}
+}
+
+func TestMocksRespondWithExamples(t *testing.T) {
+	doc := loadPetstore(t)
+
+	spectest.New().
+		Mocks(doc.Mocks("")...).
+		HandlerFunc(downstreamHandler(t)).
+		Get("/pets/7").
+		Expect(t).
+		Status(http.StatusOK).
+		Header("X-Downstream-Content-Type", "application/json").
+		Body(`{"id": 1, "name": "Tama"}`).
+		End()
+}
+
+func TestMocksSynthesizeResponsesFromSchemas(t *testing.T) {
+	doc := loadPetstore(t)
+
+	spectest.New().
+		Mocks(doc.Mocks("https://petstore.example.com/v1")...).
+		HandlerFunc(downstreamHandler(t)).
+		Get("/pets").
+		Expect(t).
+		Status(http.StatusOK).
+		Header("X-Downstream-Total-Count", "0").
+		Body(`[{"id": 0, "name": "string", "tag": "string"}]`).
+		End()
+}
+
+func TestMocksMatchLiteralPathBeforeTemplate(t *testing.T) {
+	doc := loadPetstore(t)
+
+	spectest.New().
+		Mocks(doc.Mocks("", openapi.Override("getPet", func(r *spectest.MockResponse) {
+			r.Status(http.StatusNotFound).Body(`{"message": "not found"}`)
+		}))...).
+		HandlerFunc(downstreamHandler(t)).
+		Get("/pets/mine").
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+}
+
+func TestMocksOverrideOperation(t *testing.T) {
+	doc := loadPetstore(t)
+
+	spectest.New().
+		Mocks(doc.Mocks("", openapi.Override("GET /pets/{id}", func(r *spectest.MockResponse) {
+			r.Status(http.StatusNotFound).Body(`{"message": "not found"}`)
+		}))...).
+		HandlerFunc(downstreamHandler(t)).
+		Get("/pets/7").
+		Expect(t).
+		Status(http.StatusNotFound).
+		Body(`{"message": "not found"}`).
+		End()
+}
+
+func TestMocksAnswerAnyNumberOfCalls(t *testing.T) {
+	doc := loadPetstore(t)
+
+	spectest.New().
+		Mocks(doc.Mocks("")...).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for i := 0; i < 2; i++ {
+				res, err := http.Get("https://petstore.example.com/v1/pets/7")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				res.Body.Close() //nolint
+				w.WriteHeader(res.StatusCode)
+			}
+		}).
+		Get("/").
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+}
+
+func TestMocksAreDescribedByTheirOperation(t *testing.T) {
+	doc := loadPetstore(t)
+
+	var names []string
+	for _, mock := range doc.Mocks("") {
+		names = append(names, mock.String())
+	}
+
+	assert.Contains(t, names, "GET https://petstore.example.com/v1/pets/{id}")
+	assert.Contains(t, names, "POST https://petstore.example.com/v1/pets")
+}
+
+func TestMocksPanicOnUnknownOverride(t *testing.T) {
+	doc := loadPetstore(t)
+
+	assert.PanicsWithError(t, `openapi override "getPets" does not match any operationId or "METHOD /path" of the document`, func() {
+		doc.Mocks("", openapi.Override("getPets", func(r *spectest.MockResponse) {}))
+	})
+}
+
+func TestMocksOverrideCanSetTimes(t *testing.T) {
+	doc := loadPetstore(t)
+	var failures []string
+	verifier := mocks.NewVerifier()
+	verifier.FailFn = func(t spectest.TestingT, msg string, msgAndArgs ...interface{}) bool {
+		failures = append(failures, msg)
+		return false
+	}
+
+	spectest.New().
+		Verifier(verifier).
+		Mocks(doc.Mocks("", openapi.Override("getPet", func(r *spectest.MockResponse) {
+			r.Status(http.StatusOK).Times(2)
+		}))...).
+		HandlerFunc(downstreamHandler(t)).
+		Get("/pets/7").
+		Expect(t).
+		End()
+
+	assert.Equal(t, []string{
+		"mock was not invoked expected times: GET https://petstore.example.com/v1/pets/{id} was called 1 time, expected 2 times",
+	}, failures)
+}
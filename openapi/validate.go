@@ -0,0 +1,422 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nao1215/spectest"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// Violation is a part of the request or response that does not conform to the document
+type Violation struct {
+	// In is the part of the request or response, e.g. "query parameter 'limit'" or "response body"
+	In string
+	// Field is the field of the value that does not conform, e.g. "pets.0.name". It is empty for the whole value.
+	Field string
+	// Message describes the violation
+	Message string
+}
+
+// String returns the human readable violation
+func (v Violation) String() string {
+	if v.Field == "" {
+		return fmt.Sprintf("%s: %s", v.In, v.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", v.In, v.Field, v.Message)
+}
+
+// ValidationError is returned when the request or response does not conform to the document
+type ValidationError struct {
+	// Method is the http method of the request
+	Method string
+	// Path is the path of the request
+	Path string
+	// Violations is the list of violations
+	Violations []Violation
+}
+
+// Error returns the list of violations
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Violations)+1)
+	lines = append(lines, fmt.Sprintf("openapi contract violation for %s %s", e.Method, e.Path))
+	for _, v := range e.Violations {
+		lines = append(lines, "- "+v.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate validates the http request and response against the document
+func (d *Document) Validate() spectest.Assert {
+	return d.assert(true, true)
+}
+
+// ValidateRequest validates the http request against the document
+func (d *Document) ValidateRequest() spectest.Assert {
+	return d.assert(true, false)
+}
+
+// ValidateResponse validates the http response against the document
+func (d *Document) ValidateResponse() spectest.Assert {
+	return d.assert(false, true)
+}
+
+// assert returns the spectest.Assert validating the request and/or the response.
+func (d *Document) assert(request, response bool) spectest.Assert {
+	return func(res *http.Response, req *http.Request) error {
+		verr := &ValidationError{Method: req.Method, Path: req.URL.Path}
+		op, pathValues, violation := d.FindOperation(req.Method, req.URL.Path)
+		if violation != nil {
+			verr.Violations = append(verr.Violations, *violation)
+			return verr
+		}
+		if request {
+			verr.Violations = append(verr.Violations, d.validateRequest(op, pathValues, req)...)
+		}
+		if response {
+			verr.Violations = append(verr.Violations, d.validateResponse(op, res)...)
+		}
+		if len(verr.Violations) > 0 {
+			return verr
+		}
+		return nil
+	}
+}
+
+// FindOperation returns the operation matching the method and path, and the values of the path parameters.
+// If no operation matches, the violation is returned.
+func (d *Document) FindOperation(method, path string) (*Operation, map[string]string, *Violation) {
+	var best *Operation
+	var bestValues map[string]string
+	pathMatched := false
+	for _, candidate := range d.candidatePaths(path) {
+		for _, op := range d.operations {
+			matches := op.pattern.FindStringSubmatch(candidate)
+			if matches == nil {
+				continue
+			}
+			pathMatched = true
+			if op.Method != strings.ToUpper(method) {
+				continue
+			}
+			// prefer the most specific template, e.g. /pets/mine over /pets/{id}
+			if best != nil && len(op.pathParameters) >= len(best.pathParameters) {
+				continue
+			}
+			values := map[string]string{}
+			for i, name := range op.pathParameters {
+				value, err := url.PathUnescape(matches[i+1])
+				if err != nil {
+					value = matches[i+1]
+				}
+				values[name] = value
+			}
+			best, bestValues = op, values
+		}
+		if best != nil {
+			return best, bestValues, nil
+		}
+	}
+	if pathMatched {
+		return nil, nil, &Violation{In: "operation", Message: fmt.Sprintf("method %s is not documented for path %s", method, path)}
+	}
+	return nil, nil, &Violation{In: "operation", Message: fmt.Sprintf("path %s is not documented", path)}
+}
+
+// candidatePaths returns the path with and without the base paths of the servers section.
+func (d *Document) candidatePaths(path string) []string {
+	candidates := []string{}
+	for _, basePath := range d.basePaths {
+		if strings.HasPrefix(path, basePath+"/") {
+			candidates = append(candidates, strings.TrimPrefix(path, basePath))
+		}
+	}
+	return append(candidates, path)
+}
+
+// validateRequest validates the parameters and the body of the request.
+func (d *Document) validateRequest(op *Operation, pathValues map[string]string, req *http.Request) []Violation {
+	violations := []Violation{}
+	query := req.URL.Query()
+	for _, p := range op.parameters {
+		var values []string
+		switch p.in {
+		case "path":
+			if v, ok := pathValues[p.name]; ok {
+				values = []string{v}
+			}
+		case "query":
+			values = query[p.name]
+		case "header":
+			values = req.Header.Values(p.name)
+		case "cookie":
+			if c, err := req.Cookie(p.name); err == nil {
+				values = []string{c.Value}
+			}
+		}
+		in := fmt.Sprintf("%s parameter '%s'", p.in, p.name)
+		if len(values) == 0 {
+			if p.required {
+				violations = append(violations, Violation{In: in, Message: "is required"})
+			}
+			continue
+		}
+		if p.schemaPointer == "" {
+			continue
+		}
+		violations = append(violations, d.validateValue(in, p.schemaPointer, d.convertValues(values, p.schema, p.explode))...)
+	}
+
+	rawBody, ok := op.raw["requestBody"]
+	if !ok {
+		return violations
+	}
+	requestBody, pointer, err := d.resolve(rawBody, op.pointer+"/requestBody")
+	if err != nil {
+		return append(violations, Violation{In: "request body", Message: err.Error()})
+	}
+	body := readBody(&req.Body)
+	if len(body) == 0 {
+		if required, _ := requestBody["required"].(bool); required {
+			violations = append(violations, Violation{In: "request body", Message: "is required"})
+		}
+		return violations
+	}
+	return append(violations, d.validateContent("request body", requestBody, pointer, req.Header.Get("Content-Type"), body)...)
+}
+
+// validateResponse validates the status code, the headers and the body of the response.
+func (d *Document) validateResponse(op *Operation, res *http.Response) []Violation {
+	responses, _ := op.raw["responses"].(map[string]interface{})
+	key := responseKey(responses, res.StatusCode)
+	if key == "" {
+		return []Violation{{In: "status", Message: fmt.Sprintf("status code %d is not documented", res.StatusCode)}}
+	}
+	response, pointer, err := d.resolve(responses[key], op.pointer+"/responses/"+key)
+	if err != nil {
+		return []Violation{{In: "response", Message: err.Error()}}
+	}
+
+	violations := []Violation{}
+	headers, _ := response["headers"].(map[string]interface{})
+	for _, name := range sortedKeys(headers) {
+		if strings.EqualFold(name, "Content-Type") {
+			continue
+		}
+		header, headerPointer, err := d.resolve(headers[name], pointer+"/headers/"+escapePointer(name))
+		if err != nil {
+			violations = append(violations, Violation{In: "response header '" + name + "'", Message: err.Error()})
+			continue
+		}
+		in := fmt.Sprintf("response header '%s'", name)
+		values := res.Header.Values(name)
+		if len(values) == 0 {
+			if required, _ := header["required"].(bool); required {
+				violations = append(violations, Violation{In: in, Message: "is required"})
+			}
+			continue
+		}
+		if _, ok := header["schema"]; !ok {
+			continue
+		}
+		schema, schemaPointer, err := d.resolve(header["schema"], headerPointer+"/schema")
+		if err != nil {
+			violations = append(violations, Violation{In: in, Message: err.Error()})
+			continue
+		}
+		violations = append(violations, d.validateValue(in, schemaPointer, d.convertValues(values, schema, false))...)
+	}
+
+	body := readBody(&res.Body)
+	if len(body) == 0 {
+		return violations
+	}
+	return append(violations, d.validateContent("response body", response, pointer, res.Header.Get("Content-Type"), body)...)
+}
+
+// validateContent validates the body against the schema of the content of the request body or response.
+func (d *Document) validateContent(in string, object map[string]interface{}, pointer, contentType string, body []byte) []Violation {
+	content, ok := object["content"].(map[string]interface{})
+	if !ok {
+		return []Violation{{In: in, Message: "body is not documented"}}
+	}
+	key := contentKey(content, contentType)
+	if key == "" {
+		return []Violation{{In: in, Message: fmt.Sprintf("content type '%s' is not documented", contentType)}}
+	}
+	mediaType, _ := content[key].(map[string]interface{})
+	if _, ok := mediaType["schema"]; !ok || !isJSON(key, contentType) {
+		return nil
+	}
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return []Violation{{In: in, Message: fmt.Sprintf("invalid json: %s", err)}}
+	}
+	_, schemaPointer, err := d.resolve(mediaType["schema"], pointer+"/content/"+escapePointer(key)+"/schema")
+	if err != nil {
+		return []Violation{{In: in, Message: err.Error()}}
+	}
+	return d.validateValue(in, schemaPointer, value)
+}
+
+// validateValue validates the value against the schema at the given pointer.
+func (d *Document) validateValue(in, schemaPointer string, value interface{}) []Violation {
+	schema, err := d.schema(schemaPointer)
+	if err != nil {
+		return []Violation{{In: in, Message: err.Error()}}
+	}
+	result, err := schema.Validate(gojsonschema.NewGoLoader(value))
+	if err != nil {
+		return []Violation{{In: in, Message: err.Error()}}
+	}
+	violations := []Violation{}
+	for _, e := range result.Errors() {
+		field := e.Field()
+		if field == gojsonschema.STRING_ROOT_SCHEMA_PROPERTY {
+			field = ""
+		}
+		violations = append(violations, Violation{In: in, Field: field, Message: e.Description()})
+	}
+	return violations
+}
+
+// convertValues converts the parameter or header values to the type of the schema,
+// so that they can be validated. Values that can not be converted are kept as strings.
+func (d *Document) convertValues(values []string, schema map[string]interface{}, explode bool) interface{} {
+	if schemaType(schema) != "array" {
+		return convertValue(values[0], schemaType(schema))
+	}
+	if !explode && len(values) == 1 {
+		values = strings.Split(values[0], ",")
+	}
+	itemType := ""
+	if items, _, err := d.resolve(schema["items"], ""); err == nil {
+		itemType = schemaType(items)
+	}
+	converted := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		converted = append(converted, convertValue(v, itemType))
+	}
+	return converted
+}
+
+// convertValue converts the value to the given schema type.
+func convertValue(value, typ string) interface{} {
+	switch typ {
+	case "integer":
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// schemaType returns the type of the schema. For a type list, the first type other than null is returned.
+func schemaType(schema map[string]interface{}) string {
+	switch typ := schema["type"].(type) {
+	case string:
+		return typ
+	case []interface{}:
+		for _, t := range typ {
+			if s, ok := t.(string); ok && s != "null" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// responseKey returns the key of the responses object for the status code:
+// the status code, the range (e.g. 2XX) or default.
+func responseKey(responses map[string]interface{}, statusCode int) string {
+	code := strconv.Itoa(statusCode)
+	for _, key := range []string{code, code[:1] + "XX", code[:1] + "xx", "default"} {
+		if _, ok := responses[key]; ok {
+			return key
+		}
+	}
+	return ""
+}
+
+// contentKey returns the key of the content object for the content type:
+// the media type, the media range (e.g. application/*) or */*.
+func contentKey(content map[string]interface{}, contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = ""
+	}
+	keys := map[string]string{}
+	for key := range content {
+		parsed, _, err := mime.ParseMediaType(key)
+		if err != nil {
+			parsed = key
+		}
+		keys[strings.ToLower(parsed)] = key
+	}
+	candidates := []string{strings.ToLower(mediaType)}
+	if i := strings.Index(mediaType, "/"); i > 0 {
+		candidates = append(candidates, strings.ToLower(mediaType[:i])+"/*")
+	}
+	candidates = append(candidates, "*/*")
+	for _, candidate := range candidates {
+		if key, ok := keys[candidate]; ok {
+			return key
+		}
+	}
+	return ""
+}
+
+// isJSON returns true if the documented media type or the actual content type is JSON.
+func isJSON(documented, contentType string) bool {
+	for _, t := range []string{documented, contentType} {
+		mediaType, _, err := mime.ParseMediaType(t)
+		if err != nil {
+			continue
+		}
+		if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+			return true
+		}
+	}
+	return false
+}
+
+// readBody reads the body and restores it, so that it can be read again.
+func readBody(body *io.ReadCloser) []byte {
+	if *body == nil {
+		return nil
+	}
+	b, err := io.ReadAll(*body)
+	if err != nil {
+		return nil
+	}
+	*body = io.NopCloser(bytes.NewReader(b))
+	return b
+}
+
+// sortedKeys returns the keys of the map in order.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
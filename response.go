@@ -2,7 +2,6 @@ package spectest
 
 import (
 	"bytes"
-	"flag"
 	"fmt"
 	"io"
 	"net/http"
@@ -26,6 +25,12 @@ type Response struct {
 	cookiesNotPresent []string
 	assert            []Assert
 	goldenFile        *goldenFile
+	captures          []variableCapture
+	eventually        *eventually
+	stream            *stream
+	scenarioStates    []mockScenarioState
+	jsonMatch         jsonMatch
+	snapshot          *Snapshot
 }
 
 func newResponse(s *SpecTest) *Response {
@@ -64,13 +69,10 @@ func (r *Response) BodyFromFile(path string) *Response {
 }
 
 // BodyFromGoldenFile reads the given file and uses the content as the expected response body.
-// If the update flag is set, the golden file will be updated with the actual response body.
-// Example: go test -update
+// If the -spectest.update flag or SnapshotUpdateEnv is set, the golden file will be updated with the actual response body.
+// Example: go test -spectest.update
 func (r *Response) BodyFromGoldenFile(path string) *Response {
-	update := false
-	flag.BoolVar(&update, "update", false, "update golden files")
-
-	r.goldenFile = newGoldenFile(path, update, &defaultFileSystem{})
+	r.goldenFile = newGoldenFile(path, updateSnapshots(), &defaultFileSystem{})
 	if !r.goldenFile.update {
 		if !file.IsFile(path) {
 			r.goldenFile.update = true // create a new golden file
@@ -162,28 +164,51 @@ func (r *Response) End() Result {
 	}()
 	r.specTest.assertValidHandlerOrNetwork()
 
+	// the test runs before the result is built, since the order in which the fields
+	// of a composite literal are read relative to the calls is not specified
+	res := r.runTestAndGenerateReportIfNeeded()
 	return Result{
-		Response:       r.runTestAndGenerateReportIfNeeded(),
+		Response:       res,
 		unmatchedMocks: r.specTest.mocks.findUnmatchedMocks(),
+		mockScenarios:  r.specTest.mockScenarios(),
+		failures:       r.specTest.failures,
 	}
 }
 
 // runTestWithReportIfNeeded runs the test and returns the response.
 // If the reporter is set, it will return the report response.
 func (r *Response) runTestAndGenerateReportIfNeeded() *http.Response {
+	if r.specTest.scenario != nil {
+		return r.specTest.scenario.runStep(r.specTest)
+	}
 	if r.specTest.reporter != nil {
 		return r.specTest.report()
 	}
-	return r.runTest()
+	return r.run(r.runTest)
 }
 
-// runTest runs the test. This method is not thread safe.
+// run runs the given attempt once, or until it passes if the response is expected eventually.
+func (r *Response) run(attempt func() *http.Response) *http.Response {
+	if r.eventually == nil {
+		return attempt()
+	}
+	return r.eventually.run(r.specTest, attempt)
+}
+
+// runTest runs the test. This method is not thread safe: a SpecTest must not be shared
+// between goroutines. Use SpecTest.IsolateMocks to run mocked tests in parallel.
 func (r *Response) runTest() *http.Response {
 	specTest := r.specTest
 	specTest.interval.Start()
 	defer specTest.interval.End()
 
-	if specTest.mocks.len() > 0 {
+	if specTest.cassette != nil {
+		defer specTest.cassette.start(specTest)()
+	}
+	for _, server := range specTest.mockServers {
+		defer server.attach(specTest)()
+	}
+	if specTest.mocks.len() > 0 || specTest.cassette != nil && (specTest.cassette.active == CassetteReplay || specTest.cassette.recorder != nil) {
 		specTest.transport = newTransport(
 			specTest.mocks,
 			specTest.httpClient,
@@ -192,10 +217,21 @@ func (r *Response) runTest() *http.Response {
 			specTest.mocksObservers,
 			r.specTest,
 		)
+		if specTest.mocksIsolated {
+			specTest.transport.routeID = nextMockRouteID()
+		}
+		specTest.transport.passthrough = specTest.passthrough
+		if specTest.cassette != nil {
+			specTest.transport.passthrough = specTest.passthrough || specTest.cassette.passthrough()
+			specTest.transport.recorder = specTest.cassette.recorder
+		}
 		defer specTest.transport.Reset()
 		specTest.transport.Hijack()
 	}
 	res, req := specTest.doRequest()
+	if r.stream != nil {
+		res = r.stream.read(specTest, res)
+	}
 
 	defer func() {
 		if len(specTest.observers) > 0 {
@@ -209,6 +245,7 @@ func (r *Response) runTest() *http.Response {
 		r.specTest.t.Fatal(err)
 	}
 	specTest.assertAll(res, req)
+	specTest.captureVariables(res)
 	return copyHTTPResponse(res)
 }
 
@@ -230,11 +267,24 @@ func (s *SpecTest) assertAll(res *http.Response, req *http.Request) {
 	if s.verifier == nil {
 		s.verifier = DefaultVerifier{}
 	}
+	s.failures = nil
+	defer s.reportFailures()
 	s.assertMocks()
+	s.assertMockSpies()
 	s.assertResponse(res)
+	if s.response.snapshot != nil {
+		s.response.snapshot.assert(s, res)
+	}
+	if s.response.stream != nil {
+		s.response.stream.assert(s)
+	}
 	s.assertHeaders(res)
 	s.assertCookies(res)
 	s.assertFunc(res, req)
+	s.assertMockScenarioStates()
+	if s.cassette != nil {
+		s.cassette.assert(s)
+	}
 }
 
 // copyHTTPResponse copies the given http.Response
@@ -10,6 +10,8 @@ import (
 type Result struct {
 	Response       *http.Response
 	unmatchedMocks []UnmatchedMock
+	mockScenarios  *mockScenarios
+	failures       []AssertionFailure
 }
 
 // UnmatchedMocks returns any mocks that were not used, e.g. there was not a matching http Request for the mock
@@ -17,6 +19,11 @@ func (r Result) UnmatchedMocks() []UnmatchedMock {
 	return r.unmatchedMocks
 }
 
+// Failures returns the failed checks of the test in soft assertion mode, see SpecTest.SoftAssertions
+func (r Result) Failures() []AssertionFailure {
+	return r.failures
+}
+
 // JSON unmarshal the result response body to a valid struct
 func (r Result) JSON(t interface{}) {
 	data, err := io.ReadAll(r.Response.Body)
@@ -0,0 +1,288 @@
+package spectest
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/nao1215/spectest/jsonpath/jsonpath"
+)
+
+// Scenario runs an ordered list of specs against the same handler.
+// Values captured from the response of a step can be referenced by later steps
+// using the "{{name}}" placeholder in the url, query, headers, cookies, form data and body.
+// Every step is recorded in one combined Recorder, so the report shows the whole flow.
+type Scenario struct {
+	// name is the name of the scenario. It will appear in the test report as title.
+	name string
+	// handler is the http handler that is invoked by every step
+	handler http.Handler
+	// reporter is the report formatter.
+	reporter ReportFormatter
+	// recorder is the combined recorder of all steps.
+	recorder *Recorder
+	// vars is the list of variables shared between steps.
+	vars map[string]string
+	// steps is the list of steps in the order they were added.
+	steps []*SpecTest
+	// interval is the time interval of the whole scenario.
+	interval *Interval
+	// contracts is a list of assertions that are applied to every step.
+	contracts []Assert
+}
+
+// NewScenario creates a new scenario. The name is optional and will appear in test reports.
+// The name is only used name[0]. name[1]... are ignored.
+func NewScenario(name ...string) *Scenario {
+	scenario := &Scenario{
+		recorder: NewTestRecorder(),
+		vars:     map[string]string{},
+		interval: NewInterval(),
+	}
+	if len(name) > 0 {
+		scenario.name = name[0]
+	}
+	return scenario
+}
+
+// Handler defines the http handler that is invoked by every step of the scenario
+func (s *Scenario) Handler(handler http.Handler) *Scenario {
+	s.handler = handler
+	return s
+}
+
+// HandlerFunc defines the http handler that is invoked by every step of the scenario
+func (s *Scenario) HandlerFunc(handlerFunc http.HandlerFunc) *Scenario {
+	s.handler = handlerFunc
+	return s
+}
+
+// Report provides a hook to add custom formatting to the output of the scenario.
+// The report is generated once, when End is called.
+func (s *Scenario) Report(reporter ReportFormatter) *Scenario {
+	s.reporter = reporter
+	return s
+}
+
+// Recorder provides a hook to add a recorder to the scenario
+func (s *Scenario) Recorder(recorder *Recorder) *Scenario {
+	s.recorder = recorder
+	return s
+}
+
+// Contract sets the assertions that every step must satisfy, e.g. openapi.Document.Validate()
+func (s *Scenario) Contract(contracts ...Assert) *Scenario {
+	s.contracts = append(s.contracts, contracts...)
+	return s
+}
+
+// Var sets a variable that can be referenced by the steps as "{{name}}"
+func (s *Scenario) Var(name, value string) *Scenario {
+	s.vars[name] = value
+	return s
+}
+
+// Vars returns a copy of the variables captured so far
+func (s *Scenario) Vars() map[string]string {
+	vars := make(map[string]string, len(s.vars))
+	for k, v := range s.vars {
+		vars[k] = v
+	}
+	return vars
+}
+
+// Step creates a new step of the scenario. The returned SpecTest is bound to the
+// scenario handler and variables. The step is run when its Response.End is called,
+// so steps are run in the order they are ended.
+// The name is optional and will appear in test reports.
+func (s *Scenario) Step(name ...string) *SpecTest {
+	step := New(name...)
+	step.handler = s.handler
+	step.scenario = s
+	step.vars = s.vars
+	step.contracts = s.contracts
+	s.steps = append(s.steps, step)
+	return step
+}
+
+// End finalizes the scenario and generates the report if the reporter is set.
+func (s *Scenario) End() {
+	if s.reporter == nil {
+		return
+	}
+	defer s.recorder.Reset()
+
+	s.recorder.
+		AddTitle(s.title()).
+		AddSubTitle(s.subTitle()).
+		AddMeta(s.newMeta())
+	s.reporter.Format(s.recorder)
+}
+
+// runStep runs the given step and records the result in the combined recorder.
+func (s *Scenario) runStep(step *SpecTest) *http.Response {
+	if len(s.steps) > 0 && s.steps[0] == step {
+		s.interval.Start()
+	}
+	defer s.interval.End()
+
+	step.recorder = s.recorder
+	res, _ := step.runTestAndRecord()
+	return res
+}
+
+// title returns the title of the scenario report.
+func (s *Scenario) title() string {
+	if s.name != "" {
+		return s.name
+	}
+	return "scenario"
+}
+
+// subTitle returns the subtitle of the scenario report. It is the list of steps.
+func (s *Scenario) subTitle() string {
+	names := make([]string, 0, len(s.steps))
+	for i, step := range s.steps {
+		name := step.name
+		if name == "" {
+			name = fmt.Sprintf("step %d", i+1)
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, " -> ")
+}
+
+// newMeta creates a new meta data object for the scenario report.
+func (s *Scenario) newMeta() *Meta {
+	meta := newMeta()
+	meta.Name = s.name
+	meta.Duration = s.interval.Duration().Nanoseconds()
+	meta.Variables = s.Vars()
+	if len(s.steps) > 0 {
+		first := s.steps[0]
+		meta.Method = first.request.method
+		meta.Path = first.request.url
+		if first.meta.Host != "" {
+			meta.Host = first.meta.Host
+		}
+		meta.ReportFileName = first.meta.ReportFileName
+	}
+	for _, step := range s.steps {
+		meta.Failures = append(meta.Failures, step.failures...)
+	}
+	if status, err := s.recorder.ResponseStatus(); err == nil {
+		meta.StatusCode = status
+	}
+	return meta
+}
+
+// variableCapture captures a value from the response into a named variable.
+type variableCapture struct {
+	// name is the name of the variable
+	name string
+	// source is the description of the value source. It is used in error messages.
+	source string
+	// extract extracts the value from the response.
+	extract func(res *http.Response) (string, error)
+}
+
+// CaptureJSONPath captures the value extracted by the jsonpath expression from the response body
+// into the named variable. Later steps of a scenario can reference it as "{{name}}".
+func (r *Response) CaptureJSONPath(name, expression string) *Response {
+	r.captures = append(r.captures, variableCapture{
+		name:   name,
+		source: fmt.Sprintf("jsonpath '%s'", expression),
+		extract: func(res *http.Response) (string, error) {
+			value, err := jsonpath.JSONPath(copyHTTPResponse(res).Body, expression)
+			if err != nil {
+				return "", err
+			}
+			if value == nil {
+				return "", fmt.Errorf("no value found for jsonpath '%s'", expression)
+			}
+			return fmt.Sprintf("%v", value), nil
+		},
+	})
+	return r
+}
+
+// CaptureHeader captures the value of the response header into the named variable.
+// Later steps of a scenario can reference it as "{{name}}".
+func (r *Response) CaptureHeader(name, header string) *Response {
+	r.captures = append(r.captures, variableCapture{
+		name:   name,
+		source: fmt.Sprintf("header '%s'", header),
+		extract: func(res *http.Response) (string, error) {
+			value := res.Header.Get(header)
+			if value == "" {
+				return "", fmt.Errorf("header '%s' not present in response", header)
+			}
+			return value, nil
+		},
+	})
+	return r
+}
+
+// CaptureCookie captures the value of the response cookie into the named variable.
+// Later steps of a scenario can reference it as "{{name}}".
+func (r *Response) CaptureCookie(name, cookie string) *Response {
+	r.captures = append(r.captures, variableCapture{
+		name:   name,
+		source: fmt.Sprintf("cookie '%s'", cookie),
+		extract: func(res *http.Response) (string, error) {
+			for _, c := range res.Cookies() {
+				if c.Name == cookie {
+					return c.Value, nil
+				}
+			}
+			return "", fmt.Errorf("cookie '%s' not present in response", cookie)
+		},
+	})
+	return r
+}
+
+// captureVariables stores the captured values of the response in the variables.
+// If a value can not be captured, the test will fail.
+func (s *SpecTest) captureVariables(res *http.Response) {
+	for _, c := range s.response.captures {
+		value, err := c.extract(res)
+		if err != nil {
+			s.verifier.NoError(s.t, fmt.Errorf("failed to capture %s from %s: %w", c.name, c.source, err), failureMessageArgs{Name: s.name})
+			continue
+		}
+		s.vars[c.name] = value
+	}
+}
+
+// variablePattern is the pattern of the variable placeholder. e.g. {{order_id}}
+var variablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// interpolate replaces the variable placeholders in the given string with the variable values.
+// Placeholders that refer to unknown variables are left untouched.
+func (s *SpecTest) interpolate(str string) string {
+	if s.scenario == nil || !strings.Contains(str, "{{") {
+		return str
+	}
+	return variablePattern.ReplaceAllStringFunc(str, func(placeholder string) string {
+		name := variablePattern.FindStringSubmatch(placeholder)[1]
+		if value, ok := s.vars[name]; ok {
+			return value
+		}
+		return placeholder
+	})
+}
+
+// interpolateValues replaces the variable placeholders in the given values.
+func (s *SpecTest) interpolateValues(values map[string][]string) map[string][]string {
+	if s.scenario == nil {
+		return values
+	}
+	out := make(map[string][]string, len(values))
+	for k, v := range values {
+		for _, value := range v {
+			out[k] = append(out[k], s.interpolate(value))
+		}
+	}
+	return out
+}
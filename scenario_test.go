@@ -0,0 +1,182 @@
+package spectest_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/nao1215/spectest"
+)
+
+func scenarioHandler(t *testing.T) *http.ServeMux {
+	t.Helper()
+
+	handler := http.NewServeMux()
+	handler.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3cr3t"})
+		w.Header().Set("X-Request-Id", "req-1")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"token": "abc"}`))
+	})
+	handler.HandleFunc("/orders", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["request_id"] != "req-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": 42}`))
+	})
+	handler.HandleFunc("/orders/42", func(w http.ResponseWriter, r *http.Request) {
+		c, err := r.Cookie("session")
+		if err != nil || c.Value != "s3cr3t" || r.URL.Query().Get("token") != "abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": 42, "status": "created"}`))
+	})
+	return handler
+}
+
+func TestScenarioCapturesValuesBetweenSteps(t *testing.T) {
+	scenario := spectest.NewScenario("order flow").Handler(scenarioHandler(t))
+
+	scenario.Step("login").
+		Post("/login").
+		Expect(t).
+		Status(http.StatusOK).
+		CaptureJSONPath("token", "$.token").
+		CaptureHeader("request_id", "X-Request-Id").
+		CaptureCookie("session", "session").
+		End()
+
+	scenario.Step("create order").
+		Post("/orders").
+		Header("Authorization", "Bearer {{token}}").
+		JSON(`{"request_id": "{{request_id}}"}`).
+		Expect(t).
+		Status(http.StatusCreated).
+		CaptureJSONPath("order_id", "$.id").
+		End()
+
+	scenario.Step("fetch order").
+		Get("/orders/{{order_id}}").
+		Query("token", "{{ token }}").
+		Cookie("session", "{{session}}").
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"id": 42, "status": "created"}`).
+		End()
+
+	scenario.End()
+
+	want := map[string]string{
+		"token":      "abc",
+		"request_id": "req-1",
+		"session":    "s3cr3t",
+		"order_id":   "42",
+	}
+	spectest.DefaultVerifier{}.Equal(t, want, scenario.Vars())
+}
+
+func TestScenarioRecordsAllStepsInOneReport(t *testing.T) {
+	reporter := &RecorderCaptor{}
+	scenario := spectest.NewScenario("order flow").
+		Handler(scenarioHandler(t)).
+		Report(reporter)
+
+	scenario.Step("login").
+		Post("/login").
+		Expect(t).
+		Status(http.StatusOK).
+		CaptureJSONPath("token", "$.token").
+		CaptureHeader("request_id", "X-Request-Id").
+		End()
+
+	scenario.Step().
+		Post("/orders").
+		Header("Authorization", "Bearer {{token}}").
+		JSON(`{"request_id": "{{request_id}}"}`).
+		Expect(t).
+		Status(http.StatusCreated).
+		End()
+
+	scenario.End()
+
+	r := reporter.capturedRecorder
+	spectest.DefaultVerifier{}.Equal(t, "order flow", r.Title)
+	spectest.DefaultVerifier{}.Equal(t, "login -> step 2", r.SubTitle)
+	spectest.DefaultVerifier{}.Equal(t, 4, len(r.Events))
+	spectest.DefaultVerifier{}.Equal(t, http.StatusCreated, r.Meta.StatusCode)
+	spectest.DefaultVerifier{}.Equal(t, "/login", r.Meta.Path)
+	spectest.DefaultVerifier{}.Equal(t, "abc", r.Meta.Variables["token"])
+	spectest.DefaultVerifier{}.Equal(t, "req-1", r.Meta.Variables["request_id"])
+}
+
+func TestScenarioSeedVariables(t *testing.T) {
+	scenario := spectest.NewScenario().
+		Handler(scenarioHandler(t)).
+		Var("order_id", "42").
+		Var("token", "abc").
+		Var("session", "s3cr3t")
+
+	scenario.Step().
+		Get("/orders/{{order_id}}").
+		Query("token", "{{token}}").
+		Cookie("session", "{{session}}").
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+
+	scenario.End()
+}
+
+func TestScenarioFailsWhenValueCanNotBeCaptured(t *testing.T) {
+	verifier := &captureFailVerifier{}
+	scenario := spectest.NewScenario().Handler(scenarioHandler(t))
+
+	scenario.Step().
+		Verifier(verifier).
+		Post("/login").
+		Expect(t).
+		CaptureJSONPath("id", "$.id").
+		CaptureHeader("missing", "X-Missing").
+		End()
+
+	spectest.DefaultVerifier{}.Equal(t, 2, len(verifier.errors))
+	spectest.DefaultVerifier{}.Equal(t, "failed to capture missing from header 'X-Missing': header 'X-Missing' not present in response", verifier.errors[1].Error())
+	spectest.DefaultVerifier{}.Equal(t, 0, len(scenario.Vars()))
+}
+
+func TestPlaceholdersAreNotReplacedOutsideScenario(t *testing.T) {
+	spectest.New().
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/{{id}}" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}).
+		Get("/{{id}}").
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+}
+
+type captureFailVerifier struct {
+	spectest.NoopVerifier
+	errors []error
+}
+
+func (v *captureFailVerifier) NoError(_ spectest.TestingT, err error, _ ...interface{}) bool {
+	if err != nil {
+		v.errors = append(v.errors, err)
+	}
+	return err == nil
+}
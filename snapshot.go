@@ -0,0 +1,309 @@
+package spectest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	difflib "github.com/nao1215/diff"
+	"github.com/nao1215/gorky/file"
+)
+
+// SnapshotUpdateEnv is the environment variable that updates the snapshots and the golden files
+// instead of comparing them, e.g. SPECTEST_UPDATE=true go test ./...
+const SnapshotUpdateEnv = "SPECTEST_UPDATE"
+
+// snapshotExt is the extension of the snapshot files
+const snapshotExt = ".snap"
+
+// updateSnapshotsFlag updates the snapshots and the golden files, e.g. go test ./... -spectest.update
+// It takes precedence over SnapshotUpdateEnv.
+var updateSnapshotsFlag = flag.Bool("spectest.update", false, "update snapshots and golden files")
+
+// usedSnapshots is the set of the paths of the snapshot files that were compared or written during the run
+var usedSnapshots = struct {
+	sync.Mutex
+	paths map[string]bool
+}{paths: map[string]bool{}}
+
+// Snapshot stores the status, the selected headers and the body of a response in a file, and compares
+// the next responses to it. The file is named after the name of the test, e.g. testdata/snapshots/TestGetUser.snap.
+// A missing snapshot is created. The snapshots are updated with the -spectest.update flag or SnapshotUpdateEnv.
+type Snapshot struct {
+	// dir is the directory of the snapshot files
+	dir string
+	// name is the name of the snapshot file without extension. If empty, the name of the test is used.
+	name string
+	// headers is the list of the headers that are stored in the snapshot
+	headers []string
+	// redactJSONPaths is the list of JSONPaths of the values of a JSON body that are redacted
+	redactJSONPaths []string
+	// redactPatterns is the list of patterns that are redacted from the headers and the body
+	redactPatterns []*regexp.Regexp
+}
+
+// NewSnapshot creates a new snapshot stored in testdata/snapshots
+func NewSnapshot() *Snapshot {
+	return &Snapshot{dir: filepath.Join("testdata", "snapshots")}
+}
+
+// Dir sets the directory of the snapshot files
+func (s *Snapshot) Dir(dir string) *Snapshot {
+	s.dir = dir
+	return s
+}
+
+// Name sets the name of the snapshot file, instead of the name of the test.
+// It is needed to store more than one snapshot in a test.
+func (s *Snapshot) Name(name string) *Snapshot {
+	s.name = name
+	return s
+}
+
+// Headers stores the values of the given headers in the snapshot. No header is stored by default.
+func (s *Snapshot) Headers(names ...string) *Snapshot {
+	s.headers = append(s.headers, names...)
+	return s
+}
+
+// RedactJSONPaths replaces the values of a JSON body at the given JSONPaths with REDACTED,
+// e.g. RedactJSONPaths("$.id", "$.items[*].createdAt")
+func (s *Snapshot) RedactJSONPaths(paths ...string) *Snapshot {
+	s.redactJSONPaths = append(s.redactJSONPaths, paths...)
+	return s
+}
+
+// RedactPattern replaces the matches of the regular expression with REDACTED in the headers and the body
+func (s *Snapshot) RedactPattern(pattern string) *Snapshot {
+	s.redactPatterns = append(s.redactPatterns, regexp.MustCompile(pattern))
+	return s
+}
+
+// Snapshot compares the response to the snapshot, see Snapshot
+func (r *Response) Snapshot(snapshot *Snapshot) *Response {
+	r.snapshot = snapshot
+	return r
+}
+
+// updateSnapshots returns true if the snapshots and the golden files are updated: by flag or environment variable.
+func updateSnapshots() bool {
+	if *updateSnapshotsFlag {
+		return true
+	}
+	update, _ := strconv.ParseBool(os.Getenv(SnapshotUpdateEnv))
+	return update
+}
+
+// path returns the path of the snapshot file of the test.
+func (s *Snapshot) path(specTest *SpecTest) (string, error) {
+	name := s.name
+	if name == "" {
+		if t, ok := specTest.t.(interface{ Name() string }); ok {
+			name = t.Name()
+		}
+	}
+	if name == "" {
+		return "", errors.New("snapshot requires a name: set Snapshot.Name when the test does not have one")
+	}
+	return filepath.Join(s.dir, snapshotFileName(name)+snapshotExt), nil
+}
+
+// snapshotFileName replaces the characters of a test name that are not safe in a file name, e.g. the slashes of subtests
+func snapshotFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', ' ':
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// assert compares the response to the snapshot file, or writes the snapshot file if it is missing or updated.
+func (s *Snapshot) assert(specTest *SpecTest, res *http.Response) {
+	path, err := s.path(specTest)
+	if err != nil {
+		specTest.t.Fatal(err)
+		return
+	}
+	usedSnapshots.Lock()
+	usedSnapshots.paths[filepath.Clean(path)] = true
+	usedSnapshots.Unlock()
+
+	actual, err := s.render(res)
+	if err != nil {
+		specTest.t.Fatal(err)
+		return
+	}
+	if updateSnapshots() || !file.IsFile(path) {
+		if err := newGoldenFile(path, true, &defaultFileSystem{}).write([]byte(actual)); err != nil {
+			specTest.t.Fatal(err)
+		}
+		return
+	}
+
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		specTest.t.Fatal(err)
+		return
+	}
+	expected := strings.ReplaceAll(string(b), "\r\n", "\n")
+	if expected == actual {
+		return
+	}
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(expected),
+		B:        difflib.SplitLines(actual),
+		FromFile: "Snapshot",
+		ToFile:   "Response",
+		Context:  2,
+	})
+	specTest.check(FailureSnapshot, path).Fail(specTest.t,
+		fmt.Sprintf("response does not match snapshot %s, run the test with -spectest.update or %s=true to update it:\n%s",
+			path, SnapshotUpdateEnv, diff),
+		failureMessageArgs{Name: specTest.name})
+}
+
+// render formats the response as the content of a snapshot file: the status line, the selected headers
+// sorted by name, an empty line and the body. A JSON body is indented with sorted keys.
+func (s *Snapshot) render(res *http.Response) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "HTTP %d\n", res.StatusCode)
+
+	names := make([]string, 0, len(s.headers))
+	for _, name := range s.headers {
+		names = append(names, textproto.CanonicalMIMEHeaderKey(name))
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range res.Header.Values(name) {
+			fmt.Fprintf(&b, "%s: %s\n", name, value)
+		}
+	}
+	b.WriteString("\n")
+
+	body, err := s.body(res)
+	if err != nil {
+		return "", err
+	}
+	b.WriteString(body)
+	if body != "" && !strings.HasSuffix(body, "\n") {
+		b.WriteString("\n")
+	}
+
+	snapshot := b.String()
+	for _, pattern := range s.redactPatterns {
+		snapshot = pattern.ReplaceAllString(snapshot, redacted)
+	}
+	return snapshot, nil
+}
+
+// body returns the body of the response. A JSON body is redacted and normalized.
+func (s *Snapshot) body(res *http.Response) (string, error) {
+	if res.Body == nil {
+		return "", nil
+	}
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(data))
+
+	var body interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return string(data), nil
+	}
+	for _, path := range s.redactJSONPaths {
+		pattern, err := parseJSONPathPattern(path)
+		if err != nil {
+			return "", err
+		}
+		body = redactJSON(nil, body, pattern)
+	}
+
+	var normalized bytes.Buffer
+	encoder := json.NewEncoder(&normalized)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(body); err != nil {
+		return "", err
+	}
+	return normalized.String(), nil
+}
+
+// redactJSON replaces the values at the paths that match the pattern with REDACTED.
+func redactJSON(path jsonPath, value interface{}, pattern jsonPathPattern) interface{} {
+	if len(path) > 0 && pattern.matches(path) {
+		return redacted
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			value[key] = redactJSON(path.key(key), field, pattern)
+		}
+	case []interface{}:
+		for i, element := range value {
+			value[i] = redactJSON(path.index(i), element, pattern)
+		}
+	}
+	return value
+}
+
+// ObsoleteSnapshots returns the snapshot files in the directory that were not used by the tests of the run.
+// It is meant to be called from TestMain after m.Run, when all the tests of the package have run.
+func ObsoleteSnapshots(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	usedSnapshots.Lock()
+	defer usedSnapshots.Unlock()
+	var obsolete []string
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() || filepath.Ext(path) != snapshotExt || usedSnapshots.paths[filepath.Clean(path)] {
+			continue
+		}
+		obsolete = append(obsolete, path)
+	}
+	return obsolete, nil
+}
+
+// PruneSnapshots removes the snapshot files in the directory that were not used by the tests of the run,
+// and returns their paths. Like ObsoleteSnapshots, it is meant to be called from TestMain after m.Run.
+//
+//	func TestMain(m *testing.M) {
+//		code := m.Run()
+//		if code == 0 && flag.Lookup("test.run").Value.String() == "" {
+//			_, _ = spectest.PruneSnapshots("testdata/snapshots")
+//		}
+//		os.Exit(code)
+//	}
+func PruneSnapshots(dir string) ([]string, error) {
+	obsolete, err := ObsoleteSnapshots(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range obsolete {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	return obsolete, nil
+}
@@ -0,0 +1,152 @@
+package spectest_test
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nao1215/spectest"
+	"github.com/nao1215/spectest/mocks"
+)
+
+// snapshotHandler responds with a user that has a generated id and a timestamp
+func snapshotHandler(id int, name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", fmt.Sprintf("req-%d", id))
+		w.WriteHeader(http.StatusOK)
+		_, _ = fmt.Fprintf(w, `{"name": %q, "id": %d, "created_at": "2024-01-0%dT00:00:00Z", "roles": ["admin"]}`, name, id, id)
+	}
+}
+
+func TestSnapshotIsCreatedAndCompared(t *testing.T) {
+	dir := t.TempDir()
+	snapshot := func() *spectest.Snapshot {
+		return spectest.NewSnapshot().
+			Dir(dir).
+			Headers("x-request-id", "Content-Type").
+			RedactJSONPaths("$.id").
+			RedactPattern(`\d{4}-\d{2}-\d{2}T[0-9:]+Z`).
+			RedactPattern(`req-\d+`)
+	}
+
+	for _, id := range []int{1, 2} {
+		spectest.New().
+			HandlerFunc(snapshotHandler(id, "alice")).
+			Get("/user").
+			Expect(t).
+			Snapshot(snapshot()).
+			End()
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "TestSnapshotIsCreatedAndCompared.snap"))
+	spectest.DefaultVerifier{}.NoError(t, err)
+	spectest.DefaultVerifier{}.Equal(t, `HTTP 200
+Content-Type: application/json
+X-Request-Id: REDACTED
+
+{
+  "created_at": "REDACTED",
+  "id": "REDACTED",
+  "name": "alice",
+  "roles": [
+    "admin"
+  ]
+}
+`, string(b))
+}
+
+func TestSnapshotMismatchShowsDiff(t *testing.T) {
+	dir := t.TempDir()
+	spectest.New().
+		HandlerFunc(snapshotHandler(1, "alice")).
+		Get("/user").
+		Expect(t).
+		Snapshot(spectest.NewSnapshot().Dir(dir).Name("user")).
+		End()
+
+	var failures []string
+	verifier := mocks.NewVerifier()
+	verifier.FailFn = func(t spectest.TestingT, failureMessage string, msgAndArgs ...interface{}) bool {
+		failures = append(failures, failureMessage)
+		return false
+	}
+	spectest.New().
+		Verifier(verifier).
+		HandlerFunc(snapshotHandler(1, "bob")).
+		Get("/user").
+		Expect(t).
+		Snapshot(spectest.NewSnapshot().Dir(dir).Name("user")).
+		End()
+
+	spectest.DefaultVerifier{}.Equal(t, 1, len(failures))
+	spectest.DefaultVerifier{}.True(t, strings.Contains(failures[0], "response does not match snapshot "+filepath.Join(dir, "user.snap")))
+	spectest.DefaultVerifier{}.True(t, strings.Contains(failures[0], "-  \"name\": \"alice\",\n+  \"name\": \"bob\",\n"))
+}
+
+func TestSnapshotIsUpdatedByEnvironmentVariable(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"alice", "bob"} {
+		spectest.New().
+			HandlerFunc(snapshotHandler(1, name)).
+			Get("/user").
+			Expect(t).
+			Snapshot(spectest.NewSnapshot().Dir(dir).Name("user")).
+			End()
+		t.Setenv(spectest.SnapshotUpdateEnv, "true")
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "user.snap"))
+	spectest.DefaultVerifier{}.NoError(t, err)
+	spectest.DefaultVerifier{}.True(t, strings.Contains(string(b), `"name": "bob"`))
+}
+
+func TestSnapshotOfSubtestAndTextBody(t *testing.T) {
+	dir := t.TempDir()
+	t.Run("plain text", func(t *testing.T) {
+		spectest.New().
+			HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+				_, _ = w.Write([]byte("accepted"))
+			}).
+			Get("/").
+			Expect(t).
+			Snapshot(spectest.NewSnapshot().Dir(dir)).
+			End()
+	})
+
+	b, err := os.ReadFile(filepath.Join(dir, "TestSnapshotOfSubtestAndTextBody_plain_text.snap"))
+	spectest.DefaultVerifier{}.NoError(t, err)
+	spectest.DefaultVerifier{}.Equal(t, "HTTP 202\n\naccepted\n", string(b))
+}
+
+func TestPruneSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	obsolete := filepath.Join(dir, "TestRemoved.snap")
+	for _, path := range []string{obsolete, filepath.Join(dir, "notes.txt")} {
+		if err := os.WriteFile(path, []byte("HTTP 200\n\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	spectest.New().
+		HandlerFunc(snapshotHandler(1, "alice")).
+		Get("/user").
+		Expect(t).
+		Snapshot(spectest.NewSnapshot().Dir(dir)).
+		End()
+
+	listed, err := spectest.ObsoleteSnapshots(dir)
+	spectest.DefaultVerifier{}.NoError(t, err)
+	spectest.DefaultVerifier{}.Equal(t, []string{obsolete}, listed)
+
+	pruned, err := spectest.PruneSnapshots(dir)
+	spectest.DefaultVerifier{}.NoError(t, err)
+	spectest.DefaultVerifier{}.Equal(t, []string{obsolete}, pruned)
+	_, err = os.Stat(obsolete)
+	spectest.DefaultVerifier{}.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dir, "TestPruneSnapshots.snap"))
+	spectest.DefaultVerifier{}.NoError(t, err)
+}
@@ -0,0 +1,247 @@
+package spectest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FailureCategory is the kind of check that failed in soft assertion mode
+type FailureCategory string
+
+const (
+	// FailureStatus is a check of the status code of the response
+	FailureStatus FailureCategory = "status"
+	// FailureBody is a check of the body of the response
+	FailureBody FailureCategory = "body"
+	// FailureHeader is a check of a header of the response
+	FailureHeader FailureCategory = "header"
+	// FailureCookie is a check of a cookie of the response
+	FailureCookie FailureCategory = "cookie"
+	// FailureMock is a check of the calls of the mocks
+	FailureMock FailureCategory = "mock"
+	// FailureAssert is a custom assertion of Response.Assert or a contract
+	FailureAssert FailureCategory = "assert"
+	// FailureStream is a check of the events or the chunks of a streamed response
+	FailureStream FailureCategory = "stream"
+	// FailureSnapshot is a comparison of the response to its snapshot
+	FailureSnapshot FailureCategory = "snapshot"
+	// FailureScenario is a check of the states of the mock scenarios or a variable capture
+	FailureScenario FailureCategory = "scenario"
+	// FailureCassette is a check of the requests that were not found in a cassette
+	FailureCassette FailureCategory = "cassette"
+)
+
+// AssertionFailure is a failed check of a test run in soft assertion mode, see SpecTest.SoftAssertions.
+// It is also an error: an Assert func can return it to describe its failure with a path and values.
+type AssertionFailure struct {
+	// Category is the kind of the check
+	Category FailureCategory `json:"category"`
+	// Path is the JSONPath of the value of the body, or the name of the header or the cookie
+	Path string `json:"path,omitempty"`
+	// Expected is the expected value, if the check compares values
+	Expected interface{} `json:"expected,omitempty"`
+	// Actual is the actual value, if the check compares values
+	Actual interface{} `json:"actual,omitempty"`
+	// Message describes the failure
+	Message string `json:"message"`
+}
+
+// Error returns the message of the failure
+func (f *AssertionFailure) Error() string {
+	return f.Message
+}
+
+// String formats the failure on one line, e.g. header Content-Type: mismatched values
+func (f AssertionFailure) String() string {
+	location := string(f.Category)
+	if f.Path != "" {
+		location += " " + f.Path
+	}
+	return location + ": " + f.Message
+}
+
+// SoftAssertions runs every check of the response, instead of reporting each failure as it happens,
+// and reports the failures as one summary at the end of the test. The failures are also available from
+// Result.Failures and in the reports. Assertion chains of the jsonpath package run every assertion too.
+func (s *SpecTest) SoftAssertions() *SpecTest {
+	s.softAssertions = true
+	return s
+}
+
+// check returns the verifier of a check. In soft assertion mode, the failures are recorded
+// with the category and the path instead of being reported.
+func (s *SpecTest) check(category FailureCategory, path string) Verifier {
+	if !s.softAssertions {
+		return s.verifier
+	}
+	return &failureRecorder{specTest: s, category: category, path: path}
+}
+
+// failJSONDiffs reports the differences between the expected and the actual JSON bodies.
+// In soft assertion mode, each difference is recorded with its JSONPath.
+func (s *SpecTest) failJSONDiffs(category FailureCategory, message string, diffs []jsonDiff) {
+	if len(diffs) == 0 {
+		return
+	}
+	if !s.softAssertions {
+		s.verifier.Fail(s.t, message+":\n"+joinJSONDiffs(diffs), failureMessageArgs{Name: s.name})
+		return
+	}
+	for _, d := range diffs {
+		s.failures = append(s.failures, AssertionFailure{
+			Category: category,
+			Path:     d.path.String(),
+			Expected: d.expected,
+			Actual:   d.actual,
+			Message:  d.message,
+		})
+	}
+}
+
+// reportFailures reports the failures recorded in soft assertion mode as one failure.
+func (s *SpecTest) reportFailures() {
+	if !s.softAssertions || len(s.failures) == 0 {
+		return
+	}
+	s.verifier.Fail(s.t, formatFailures(s.failures), failureMessageArgs{Name: s.name})
+}
+
+// formatFailures formats the failures as a numbered list with their expected and actual values.
+func formatFailures(failures []AssertionFailure) string {
+	var b strings.Builder
+	if len(failures) == 1 {
+		b.WriteString("1 check failed:\n")
+	} else {
+		fmt.Fprintf(&b, "%d checks failed:\n", len(failures))
+	}
+	for i, f := range failures {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, strings.ReplaceAll(f.String(), "\n", "\n   "))
+		if f.Expected != nil || f.Actual != nil {
+			fmt.Fprintf(&b, "   expected: %s\n", formatFailureValue(f.Expected))
+			fmt.Fprintf(&b, "   actual:   %s\n", formatFailureValue(f.Actual))
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// formatFailureValue formats an expected or actual value of a failure
+func formatFailureValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []byte:
+		return fmt.Sprintf("%q", string(v))
+	case map[string]interface{}, []interface{}, float64, bool, nil:
+		return formatJSON(v)
+	default:
+		return fmt.Sprintf("%#v", v)
+	}
+}
+
+// failureRecorder is the verifier of a check in soft assertion mode. It records the failures
+// in the SpecTest instead of reporting them.
+type failureRecorder struct {
+	// specTest is the test that records the failures
+	specTest *SpecTest
+	// category is the kind of the check
+	category FailureCategory
+	// path is the JSONPath, the header name or the cookie name of the check
+	path string
+}
+
+var _ Verifier = &failureRecorder{}
+
+// record records a failure of the check
+func (r *failureRecorder) record(expected, actual interface{}, message string) bool {
+	r.specTest.failures = append(r.specTest.failures, AssertionFailure{
+		Category: r.category,
+		Path:     r.path,
+		Expected: expected,
+		Actual:   actual,
+		Message:  message,
+	})
+	return false
+}
+
+// Equal records a failure if the values are not equal
+func (r *failureRecorder) Equal(_ TestingT, expected, actual interface{}, msgAndArgs ...interface{}) bool {
+	if objectsAreEqual(expected, actual) {
+		return true
+	}
+	message := failureMessage(msgAndArgs)
+	_, isCondition := expected.(bool)
+	if isCondition && message != "" {
+		return r.record(nil, nil, message)
+	}
+	if message == "" {
+		message = "not equal"
+	}
+	return r.record(expected, actual, message)
+}
+
+// True records a failure if the value is false
+func (r *failureRecorder) True(_ TestingT, value bool, msgAndArgs ...interface{}) bool {
+	if value {
+		return true
+	}
+	message := failureMessage(msgAndArgs)
+	if message == "" {
+		message = "should be true"
+	}
+	return r.record(nil, nil, message)
+}
+
+// JSONEq records a failure for each difference between the JSON values
+func (r *failureRecorder) JSONEq(_ TestingT, expected string, actual string, _ ...interface{}) bool {
+	diffs, err := jsonMatch{}.match([]byte(expected), []byte(actual))
+	if err != nil {
+		return r.record(expected, actual, err.Error())
+	}
+	r.specTest.failJSONDiffs(r.category, "", diffs)
+	return len(diffs) == 0
+}
+
+// Fail records a failure
+func (r *failureRecorder) Fail(_ TestingT, message string, _ ...interface{}) bool {
+	return r.record(nil, nil, message)
+}
+
+// NoError records a failure for the error. The errors joined by errors.Join are recorded separately,
+// and an AssertionFailure is recorded with its path and values.
+func (r *failureRecorder) NoError(_ TestingT, err error, _ ...interface{}) bool {
+	if err == nil {
+		return true
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			r.NoError(nil, err)
+		}
+		return false
+	}
+	var failure *AssertionFailure
+	if !errors.As(err, &failure) {
+		return r.record(nil, nil, err.Error())
+	}
+	recorded := *failure
+	if recorded.Category == "" {
+		recorded.Category = r.category
+	}
+	if recorded.Path == "" {
+		recorded.Path = r.path
+	}
+	recorded.Message = err.Error()
+	r.specTest.failures = append(r.specTest.failures, recorded)
+	return false
+}
+
+// failureMessage returns the messages of the arguments of a verifier method
+func failureMessage(msgAndArgs []interface{}) string {
+	var messages []string
+	for _, msg := range msgAndArgs {
+		if s, ok := msg.(string); ok && s != "" {
+			messages = append(messages, s)
+		}
+	}
+	return strings.Join(messages, ", ")
+}
@@ -0,0 +1,110 @@
+package spectest_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/nao1215/spectest"
+	"github.com/nao1215/spectest/mocks"
+)
+
+func softAssertionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusInternalServerError)
+	_, _ = w.Write([]byte(`{"name": "bob", "age": 30, "tags": ["a"]}`))
+}
+
+func TestSoftAssertionsReportEveryFailureOnce(t *testing.T) {
+	var failures []string
+	verifier := mocks.NewVerifier()
+	verifier.FailFn = func(t spectest.TestingT, failureMessage string, msgAndArgs ...interface{}) bool {
+		failures = append(failures, failureMessage)
+		return false
+	}
+
+	result := spectest.New().
+		SoftAssertions().
+		Verifier(verifier).
+		HandlerFunc(softAssertionHandler).
+		Get("/user").
+		Expect(t).
+		Status(http.StatusOK).
+		Header("Content-Type", "application/json").
+		HeaderPresent("X-Request-Id").
+		Body(`{"name": "alice", "age": 30, "tags": ["a", "b"]}`).
+		Assert(func(*http.Response, *http.Request) error {
+			return errors.Join(
+				errors.New("first custom failure"),
+				&spectest.AssertionFailure{Path: "$.age", Expected: 31, Actual: 30, Message: "too young"},
+			)
+		}).
+		End()
+
+	spectest.DefaultVerifier{}.Equal(t, []spectest.AssertionFailure{
+		{Category: spectest.FailureStatus, Expected: http.StatusOK, Actual: http.StatusInternalServerError, Message: "Status code 500 not equal to 200"},
+		{Category: spectest.FailureBody, Path: "$.name", Expected: "alice", Actual: "bob", Message: `expected "alice", received "bob"`},
+		{Category: spectest.FailureBody, Path: "$.tags", Expected: []interface{}{"a", "b"}, Actual: []interface{}{"a"}, Message: "expected 2 elements, received 1"},
+		{Category: spectest.FailureHeader, Path: "Content-Type", Message: "mismatched values for header 'Content-Type'. Expected application/json but received text/plain"},
+		{Category: spectest.FailureHeader, Path: "X-Request-Id", Message: "expected header 'X-Request-Id' not present in response"},
+		{Category: spectest.FailureAssert, Message: "first custom failure"},
+		{Category: spectest.FailureAssert, Path: "$.age", Expected: 31, Actual: 30, Message: "too young"},
+	}, result.Failures())
+	spectest.DefaultVerifier{}.Equal(t, []string{`7 checks failed:
+1. status: Status code 500 not equal to 200
+   expected: 200
+   actual:   500
+2. body $.name: expected "alice", received "bob"
+   expected: "alice"
+   actual:   "bob"
+3. body $.tags: expected 2 elements, received 1
+   expected: ["a","b"]
+   actual:   ["a"]
+4. header Content-Type: mismatched values for header 'Content-Type'. Expected application/json but received text/plain
+5. header X-Request-Id: expected header 'X-Request-Id' not present in response
+6. assert: first custom failure
+7. assert $.age: too young
+   expected: 31
+   actual:   30`}, failures)
+}
+
+func TestSoftAssertionsPass(t *testing.T) {
+	result := spectest.New().
+		SoftAssertions().
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}).
+		Get("/").
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+
+	spectest.DefaultVerifier{}.Equal(t, 0, len(result.Failures()))
+}
+
+func TestWithoutSoftAssertionsFailuresAreReportedAsTheyHappen(t *testing.T) {
+	var failures []string
+	verifier := mocks.NewVerifier()
+	verifier.EqualFn = func(t spectest.TestingT, expected, actual interface{}, msgAndArgs ...interface{}) bool {
+		if expected != actual {
+			failures = append(failures, "equal")
+		}
+		return expected == actual
+	}
+	verifier.JSONEqFn = func(t spectest.TestingT, expected string, actual string, msgAndArgs ...interface{}) bool {
+		failures = append(failures, "json")
+		return false
+	}
+
+	result := spectest.New().
+		Verifier(verifier).
+		HandlerFunc(softAssertionHandler).
+		Get("/user").
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"name": "alice"}`).
+		End()
+
+	spectest.DefaultVerifier{}.Equal(t, []string{"equal", "json"}, failures)
+	spectest.DefaultVerifier{}.Equal(t, 0, len(result.Failures()))
+}
@@ -21,6 +21,10 @@ type SpecTest struct {
 	debug *debug
 	// mockResponseDelayEnabled will turn on mock response delays (defaults to OFF)
 	mockResponseDelayEnabled bool
+	// mocksIsolated will bind the mocks to the inbound request context (defaults to OFF)
+	mocksIsolated bool
+	// passthrough will send the outbound requests that do not match any mock to the real services (defaults to OFF)
+	passthrough bool
 	// network is used to enable/disable networking for the test
 	network *network
 	// reporter is the report formatter.
@@ -57,6 +61,21 @@ type SpecTest struct {
 	meta *Meta
 	// interval is the time interval for the test report.
 	interval *Interval
+	// scenario is the scenario the test belongs to. It is nil if the test is not a scenario step.
+	scenario *Scenario
+	// vars is the list of variables captured from the response.
+	// It is shared between the steps of a scenario.
+	vars map[string]string
+	// cassette records or replays the outbound http interactions
+	cassette *Cassette
+	// mockServers is the list of mock servers attached to the test
+	mockServers []*MockServer
+	// contracts is a list of assertions that are applied to every request and response, e.g. an OpenAPI contract.
+	contracts []Assert
+	// softAssertions runs every check and reports the failures as one summary (defaults to OFF)
+	softAssertions bool
+	// failures is the list of the failed checks of the last run in soft assertion mode
+	failures []AssertionFailure
 }
 
 // Observe will be called by with the request and response on completion
@@ -70,6 +89,7 @@ func New(name ...string) *SpecTest {
 		interval: NewInterval(),
 		meta:     newMeta(),
 		network:  newNetwork(),
+		vars:     map[string]string{},
 	}
 	specTest.request = newRequest(specTest)
 	specTest.response = newResponse(specTest)
@@ -108,6 +128,30 @@ func (s *SpecTest) EnableMockResponseDelay() *SpecTest {
 	return s
 }
 
+// IsolateMocks binds the mocks to the context of the inbound request instead of replacing
+// http.DefaultTransport (or the transport of the client given by HTTPClient) for the duration of the test.
+// Outbound requests are sent to the mocks of the SpecTest that started them, so mocked tests
+// can run with t.Parallel(). The handler must propagate the inbound request context to its
+// outbound requests, e.g. http.NewRequestWithContext(r.Context(), ...).
+// Outbound requests that do not carry the context of a SpecTest are sent to the native transport.
+//
+// While at least one isolated SpecTest is running, http.DefaultTransport (or the transport of the client)
+// is a router that dispatches the outbound requests, so it is not a *http.Transport. The native transport
+// is restored when the last isolated SpecTest ends. Do not run tests that replace the transport themselves,
+// e.g. SpecTest without IsolateMocks, in parallel with isolated tests.
+func (s *SpecTest) IsolateMocks() *SpecTest {
+	s.mocksIsolated = true
+	return s
+}
+
+// Passthrough sends the outbound requests that do not match any mock to the real services instead of failing them,
+// so that only a few endpoints are stubbed in end-to-end runs. The requests are sent with the transport
+// that the mocks replaced, e.g. http.DefaultTransport or the transport of the client given by HTTPClient.
+func (s *SpecTest) Passthrough() *SpecTest {
+	s.passthrough = true
+	return s
+}
+
 // Debug logs to the console the http wire representation of all http interactions
 // that are intercepted by spectest. This includes the inbound request to the application
 // under test, the response returned by the application and any interactions that are
@@ -155,19 +199,17 @@ func (s *SpecTest) HandlerFunc(handlerFunc http.HandlerFunc) *SpecTest {
 }
 
 // Mocks is a builder method for setting the mocks.
-// A mock that expects multiple executions will reset the expected call
-// count to 1 when generated as a mock that expects a single execution.
+// The test uses copies of the mocks, so the same mock can be used by several tests.
 func (s *SpecTest) Mocks(mocks ...*Mock) *SpecTest {
-	var m Mocks
-	for i := range mocks {
-		times := mocks[i].response.mock.execCount.expect
-		for j := 1; j <= int(times); j++ {
-			mockCopy := mocks[i].deepCopy()
-			mockCopy.execCount = newExecCount(1)
-			m = append(m, mockCopy)
-		}
-	}
-	s.mocks = m
+	s.mocks = copyMocks(mocks)
+	return s
+}
+
+// MockServers attaches the mock servers to the test, so that their interactions are seen by the mock
+// observers and appear in the report, and so that they log and delay like the mocks of the test.
+// The test fails if a mock of a server was not called the expected number of times.
+func (s *SpecTest) MockServers(servers ...*MockServer) *SpecTest {
+	s.mockServers = append(s.mockServers, servers...)
 	return s
 }
 
@@ -189,6 +231,13 @@ func (s *SpecTest) ObserveMocks(observer Observe) *SpecTest {
 	return s
 }
 
+// Contract is a builder method for setting the assertions that every request and response
+// must satisfy, e.g. openapi.Document.Validate(). They run before the Assert funcs of the response.
+func (s *SpecTest) Contract(contracts ...Assert) *SpecTest {
+	s.contracts = append(s.contracts, contracts...)
+	return s
+}
+
 // Request returns the request spec
 func (s *SpecTest) Request() *Request {
 	return s.request
@@ -342,22 +391,43 @@ func (s *SpecTest) Tracef(format string, args ...interface{}) *Request {
 
 // report will run the test and return the report.
 func (s *SpecTest) report() *http.Response {
-	capture := newCapture()
-	s.observers = capture.appendObserver(s.observers)
-	s.mocksObservers = capture.appendMockObservers(s.mocksObservers)
-
 	if s.recorder == nil {
 		s.recorder = NewTestRecorder()
 	}
 	defer s.recorder.Reset()
 
-	res := s.response.runTest()
-	s.recordResult(capture)
+	res, capture := s.runTestAndRecord()
+	if capture == nil {
+		return res // no attempt was completed, so there is nothing to report
+	}
 	s.recorder.AddMeta(s.newMeta(capture))
 	s.reporter.Format(s.recorder)
 	return res
 }
 
+// runTestAndRecord will run the test and record the result in the recorder.
+// If the response is expected eventually, every attempt is recorded.
+// The recorder must be set before calling this method.
+func (s *SpecTest) runTestAndRecord() (*http.Response, *capture) {
+	var last *capture
+	res := s.response.run(func() *http.Response {
+		observers, mocksObservers := s.observers, s.mocksObservers
+		defer func() {
+			s.observers, s.mocksObservers = observers, mocksObservers
+		}()
+
+		capture := newCapture()
+		s.observers = capture.appendObserver(s.observers)
+		s.mocksObservers = capture.appendMockObservers(s.mocksObservers)
+
+		res := s.response.runTest()
+		s.recordResult(capture)
+		last = capture
+		return res
+	})
+	return res, last
+}
+
 // newMeta creates a new meta data object.
 // This meta data is used for creating report.
 func (s *SpecTest) newMeta(capture *capture) *Meta {
@@ -368,6 +438,7 @@ func (s *SpecTest) newMeta(capture *capture) *Meta {
 	meta.Duration = s.interval.Duration().Nanoseconds()
 	meta.Name = s.name
 	meta.ReportFileName = s.meta.ReportFileName
+	meta.Failures = s.failures
 	if s.meta.Host != "" {
 		meta.Host = s.meta.Host
 	}
@@ -401,6 +472,15 @@ func (s SpecTest) recordResult(capture *capture) {
 				Timestamp: interaction.timestamp,
 			})
 		}
+		if fault := faultOf(interaction.request); fault != "" {
+			s.recorder.AddMessageResponse(MessageResponse{
+				Source:    interaction.GetRequestHost(),
+				Target:    SystemUnderTestDefaultName,
+				Header:    "fault",
+				Body:      fault,
+				Timestamp: interaction.timestamp,
+			})
+		}
 	}
 
 	s.recorder.AddHTTPResponse(HTTPResponse{
@@ -410,17 +490,23 @@ func (s SpecTest) recordResult(capture *capture) {
 		Timestamp: s.interval.Finished,
 	})
 
-	sort.Slice(s.recorder.Events, func(i, j int) bool {
+	sort.SliceStable(s.recorder.Events, func(i, j int) bool {
 		return s.recorder.Events[i].GetTime().Before(s.recorder.Events[j].GetTime())
 	})
 }
 
-// assertMocks will assert that all mocks were invoked the expected number of times.
+// assertMocks will assert that all mocks, including the mocks of the attached mock servers,
+// were invoked the expected number of times.
 // If a mock was not invoked the expected number of times, the test will fail.
 func (s *SpecTest) assertMocks() {
-	for _, mock := range s.mocks {
-		if !mock.state.isRunning() && mock.execCount.isComplete() {
-			s.verifier.Fail(s.t, "mock was not invoked expected times", failureMessageArgs{Name: s.name})
+	mocks := append(Mocks{}, s.mocks...)
+	for _, server := range s.mockServers {
+		mocks = append(mocks, server.mocks...)
+	}
+	for _, mock := range mocks {
+		if !mock.execCount.isSatisfied() {
+			s.check(FailureMock, "").Fail(s.t, fmt.Sprintf("mock was not invoked expected times: %s was called %s, expected %s",
+				mock, formatTimes(mock.execCount.actual), mock.execCount), failureMessageArgs{Name: s.name})
 		}
 	}
 }
@@ -428,11 +514,11 @@ func (s *SpecTest) assertMocks() {
 // assertFunc will run the assert functions.
 // If an assert function fails, the test will fail.
 func (s *SpecTest) assertFunc(res *http.Response, req *http.Request) {
-	if len(s.response.assert) > 0 {
-		for _, assertFn := range s.response.assert {
+	if len(s.contracts) > 0 || len(s.response.assert) > 0 {
+		for _, assertFn := range append(append([]Assert{}, s.contracts...), s.response.assert...) {
 			err := assertFn(copyHTTPResponse(res), copyHTTPRequest(req))
 			if err != nil {
-				s.verifier.NoError(s.t, err, failureMessageArgs{Name: s.name})
+				s.check(FailureAssert, "").NoError(s.t, err, failureMessageArgs{Name: s.name})
 			}
 		}
 	}
@@ -446,21 +532,32 @@ func (s *SpecTest) doRequest() (*http.Response, *http.Request) {
 	if s.request.interceptor != nil {
 		s.request.interceptor(req)
 	}
+	if s.response.stream != nil {
+		req = s.response.stream.prepare(req)
+	}
+	if s.transport != nil && s.transport.routeID != 0 && !s.network.isEnable() {
+		req = req.WithContext(withMockRoute(req.Context(), s.transport.routeID))
+	}
 	resRecorder := httptest.NewRecorder()
 	s.debug.dumpRequest(req)
 
 	var res *http.Response
 	var err error
-	if !s.network.isEnable() {
+	switch {
+	case !s.network.isEnable() && s.response.stream != nil:
+		res = s.serveStream(copyHTTPRequest(req))
+	case !s.network.isEnable():
 		s.serveHTTP(resRecorder, copyHTTPRequest(req))
 		res = resRecorder.Result()
-	} else {
+	default:
 		res, err = s.network.Do(copyHTTPRequest(req))
 		if err != nil {
 			s.t.Fatal(err)
 		}
 	}
-	s.debug.dumpResponse(res)
+	if s.response.stream == nil {
+		s.debug.dumpResponse(res) // a stream is dumped after it is read
+	}
 
 	return res, req
 }
@@ -479,7 +576,7 @@ func (s *SpecTest) serveHTTP(res *httptest.ResponseRecorder, req *http.Request)
 // If the response does not match the expected response, the test will fail.
 func (s *SpecTest) assertResponse(res *http.Response) {
 	if s.response.status != 0 {
-		s.verifier.Equal(s.t, s.response.status, res.StatusCode, fmt.Sprintf("Status code %d not equal to %d", res.StatusCode, s.response.status), failureMessageArgs{Name: s.name})
+		s.check(FailureStatus, "").Equal(s.t, s.response.status, res.StatusCode, fmt.Sprintf("Status code %d not equal to %d", res.StatusCode, s.response.status), failureMessageArgs{Name: s.name})
 	}
 
 	if s.response.body == "" {
@@ -491,10 +588,38 @@ func (s *SpecTest) assertResponse(res *http.Response) {
 		resBodyBytes, _ = io.ReadAll(res.Body)
 		res.Body = io.NopCloser(bytes.NewBuffer(resBodyBytes))
 	}
-	if json.Valid([]byte(s.response.body)) {
-		s.verifier.JSONEq(s.t, s.response.body, string(resBodyBytes), failureMessageArgs{Name: s.name})
-	} else {
-		s.verifier.Equal(s.t, s.response.body, string(resBodyBytes), failureMessageArgs{Name: s.name})
+	switch {
+	case json.Valid([]byte(s.response.body)) && s.response.jsonMatch.isEnabled(s.response.body):
+		diffs, err := s.response.jsonMatch.match([]byte(s.response.body), resBodyBytes)
+		if err != nil {
+			s.check(FailureBody, "").NoError(s.t, err, failureMessageArgs{Name: s.name})
+		} else {
+			s.failJSONDiffs(FailureBody, "response body did not match expected JSON body", diffs)
+		}
+	case json.Valid([]byte(s.response.body)):
+		s.check(FailureBody, "").JSONEq(s.t, s.response.body, string(resBodyBytes), failureMessageArgs{Name: s.name})
+	case isXMLContentType(res.Header.Get("Content-Type")):
+		s.assertXMLBody(resBodyBytes)
+	default:
+		s.check(FailureBody, "").Equal(s.t, s.response.body, string(resBodyBytes), failureMessageArgs{Name: s.name})
+	}
+}
+
+// assertXMLBody compares the canonical forms of the expected and the actual XML bodies, so that
+// the formatting, the order of the attributes and the namespace prefixes do not matter.
+func (s *SpecTest) assertXMLBody(resBodyBytes []byte) {
+	expected, err := canonicalXML([]byte(s.response.body))
+	if err != nil {
+		s.check(FailureBody, "").Equal(s.t, s.response.body, string(resBodyBytes), failureMessageArgs{Name: s.name})
+		return
+	}
+	actual, err := canonicalXML(resBodyBytes)
+	if err != nil {
+		s.check(FailureBody, "").Fail(s.t, fmt.Sprintf("response body is not valid XML: %s\n\n%s", err, resBodyBytes), failureMessageArgs{Name: s.name})
+		return
+	}
+	if expected != actual {
+		s.check(FailureBody, "").Fail(s.t, "response body did not match expected XML body:\n"+bodyDiff(expected, actual), failureMessageArgs{Name: s.name})
 	}
 }
 
@@ -525,8 +650,8 @@ func (s *SpecTest) assertExpectedCookie(response *http.Response, expectedCookie
 			mismatchedFields = append(mismatchedFields, errors...)
 		}
 	}
-	s.verifier.Equal(s.t, true, foundCookie, "ExpectedCookie not found - "+*expectedCookie.name, failureMessageArgs{Name: s.name})
-	s.verifier.Equal(s.t, 0, len(mismatchedFields), strings.Join(mismatchedFields, ","), failureMessageArgs{Name: s.name})
+	s.check(FailureCookie, *expectedCookie.name).Equal(s.t, true, foundCookie, "ExpectedCookie not found - "+*expectedCookie.name, failureMessageArgs{Name: s.name})
+	s.check(FailureCookie, *expectedCookie.name).Equal(s.t, 0, len(mismatchedFields), strings.Join(mismatchedFields, ","), failureMessageArgs{Name: s.name})
 }
 
 // assertPresentCookie checks if the given cookie name is present in the response's cookies.
@@ -538,7 +663,7 @@ func (s *SpecTest) assertPresentCookie(response *http.Response, cookieName strin
 			break
 		}
 	}
-	s.verifier.Equal(s.t, true, foundCookie, "ExpectedCookie not found - "+cookieName, failureMessageArgs{Name: s.name})
+	s.check(FailureCookie, cookieName).Equal(s.t, true, foundCookie, "ExpectedCookie not found - "+cookieName, failureMessageArgs{Name: s.name})
 }
 
 // assertNotPresentCookie checks if the given cookie name is not present in the response's cookies.
@@ -550,7 +675,7 @@ func (s *SpecTest) assertNotPresentCookie(response *http.Response, cookieName st
 			break
 		}
 	}
-	s.verifier.Equal(s.t, false, foundCookie, "ExpectedCookie found - "+cookieName, failureMessageArgs{Name: s.name})
+	s.check(FailureCookie, cookieName).Equal(s.t, false, foundCookie, "ExpectedCookie found - "+cookieName, failureMessageArgs{Name: s.name})
 }
 
 // assertHeaders will assert the headers.
@@ -570,7 +695,7 @@ func (s *SpecTest) assertHeaders(res *http.Response) {
 // assertExpectedHeaders checks if the expected headers and their values are present in the response.
 func (s *SpecTest) assertExpectedHeaders(res *http.Response, expectedHeader string, expectedValues []string) {
 	resHeaderValues, foundHeader := res.Header[expectedHeader]
-	s.verifier.Equal(s.t, true, foundHeader, fmt.Sprintf("expected header '%s' not present in response", expectedHeader), failureMessageArgs{Name: s.name})
+	s.check(FailureHeader, expectedHeader).Equal(s.t, true, foundHeader, fmt.Sprintf("expected header '%s' not present in response", expectedHeader), failureMessageArgs{Name: s.name})
 
 	if !foundHeader {
 		return
@@ -584,21 +709,21 @@ func (s *SpecTest) assertExpectedHeaders(res *http.Response, expectedHeader stri
 				break
 			}
 		}
-		s.verifier.Equal(s.t, true, foundValue, fmt.Sprintf("mismatched values for header '%s'. Expected %s but received %s", expectedHeader, expectedValue, strings.Join(resHeaderValues, ",")), failureMessageArgs{Name: s.name})
+		s.check(FailureHeader, expectedHeader).Equal(s.t, true, foundValue, fmt.Sprintf("mismatched values for header '%s'. Expected %s but received %s", expectedHeader, expectedValue, strings.Join(resHeaderValues, ",")), failureMessageArgs{Name: s.name})
 	}
 }
 
 // assertPresentHeaders checks if the given headers are present in the response's headers.
 func (s *SpecTest) assertPresentHeaders(res *http.Response, expectedName string) {
 	if res.Header.Get(expectedName) == "" {
-		s.verifier.Fail(s.t, fmt.Sprintf("expected header '%s' not present in response", expectedName), failureMessageArgs{Name: s.name})
+		s.check(FailureHeader, expectedName).Fail(s.t, fmt.Sprintf("expected header '%s' not present in response", expectedName), failureMessageArgs{Name: s.name})
 	}
 }
 
 // assertNotPresentHeaders checks if the given headers are not present in the response's headers.
 func (s *SpecTest) assertNotPresentHeaders(res *http.Response, name string) {
 	if res.Header.Get(name) != "" {
-		s.verifier.Fail(s.t, fmt.Sprintf("did not expect header '%s' in response", name), failureMessageArgs{Name: s.name})
+		s.check(FailureHeader, name).Fail(s.t, fmt.Sprintf("did not expect header '%s' in response", name), failureMessageArgs{Name: s.name})
 	}
 }
 
@@ -623,29 +748,31 @@ func (s *SpecTest) buildRequest() *http.Request {
 		s.setMultipartHeaders()
 	}
 
-	req, _ := http.NewRequest(s.request.method, s.request.url, bytes.NewBufferString(s.request.body)) // TODO: handle error
+	req, _ := http.NewRequest(s.request.method, s.interpolate(s.request.url), bytes.NewBufferString(s.interpolate(s.request.body))) // TODO: handle error
 	if s.request.context != nil {
 		req = req.WithContext(s.request.context)
 	}
 
-	req.URL.RawQuery = formatQuery(s.request)
+	req.URL.RawQuery = s.formatQuery()
 	req.Host = SystemUnderTestDefaultName
 	if s.network.isEnable() {
 		req.Host = req.URL.Host
 	}
 
-	for k, v := range s.request.headers {
+	for k, v := range s.interpolateValues(s.request.headers) {
 		for _, headerValue := range v {
 			req.Header.Add(k, headerValue)
 		}
 	}
 
 	for _, cookie := range s.request.cookies {
-		req.AddCookie(cookie.ToHTTPCookie())
+		c := cookie.ToHTTPCookie()
+		c.Value = s.interpolate(c.Value)
+		req.AddCookie(c)
 	}
 
 	if s.request.basicAuth != "" {
-		parts := strings.Split(s.request.basicAuth, ":")
+		parts := strings.Split(s.interpolate(s.request.basicAuth), ":")
 		req.SetBasicAuth(parts[0], parts[1])
 	}
 
@@ -655,8 +782,9 @@ func (s *SpecTest) buildRequest() *http.Request {
 // buildFormRequestBody builds the request body for form data.
 func (s *SpecTest) buildFormRequestBody() string {
 	form := url.Values{}
-	for k := range s.request.formData {
-		for _, value := range s.request.formData[k] {
+	formData := s.interpolateValues(s.request.formData)
+	for k := range formData {
+		for _, value := range formData[k] {
 			form.Add(k, value)
 		}
 	}
@@ -674,17 +802,17 @@ func (s *SpecTest) setMultipartHeaders() {
 }
 
 // formatQuery will format the query parameters.
-func formatQuery(request *Request) string {
+func (s *SpecTest) formatQuery() string {
 	var out url.Values = map[string][]string{}
 
-	if request.queryCollection != nil {
-		for _, param := range buildQueryCollection(request.queryCollection) {
+	if s.request.queryCollection != nil {
+		for _, param := range buildQueryCollection(s.interpolateValues(s.request.queryCollection)) {
 			out.Add(param.l, param.r)
 		}
 	}
 
-	if request.query != nil {
-		for k, v := range request.query {
+	if s.request.query != nil {
+		for k, v := range s.interpolateValues(s.request.query) {
 			for _, p := range v {
 				out.Add(k, p)
 			}
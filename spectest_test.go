@@ -653,6 +653,61 @@ func TestApiTestMatchesJSONResponseBodyWithWhitespace(t *testing.T) {
 		End()
 }
 
+func TestApiTestMatchesPartialJSONResponseBody(t *testing.T) {
+	handler := http.NewServeMux()
+	handler.HandleFunc("/orders", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{
+			"id": "5b2f5d5e-0a8a-4a0b-9a3e-2f6e7c1d9b10",
+			"createdAt": "2024-01-02T15:04:05Z",
+			"total": 1200,
+			"items": [{"sku": "b", "requestId": 2}, {"sku": "a", "requestId": 1}],
+			"links": {"self": "/orders/1"}
+		}`))
+	})
+
+	spectest.New().
+		Handler(handler).
+		Post("/orders").
+		Expect(t).
+		Body(`{
+			"id": "<<uuid>>",
+			"createdAt": "<<rfc3339>>",
+			"total": "<<number>>",
+			"items": [{"sku": "a"}, {"sku": "b"}]
+		}`).
+		JSONSubset().
+		UnorderedJSONArrays().
+		IgnoreJSONPaths("$.items[*].requestId").
+		Status(http.StatusCreated).
+		End()
+}
+
+func TestApiTestPartialJSONResponseBodyMismatch(t *testing.T) {
+	var failures []string
+	verifier := mocks.NewVerifier()
+	verifier.FailFn = func(t spectest.TestingT, failureMessage string, msgAndArgs ...interface{}) bool {
+		failures = append(failures, failureMessage)
+		return false
+	}
+
+	spectest.New().
+		Verifier(verifier).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"id": 1, "name": "bob", "role": "admin"}`))
+		}).
+		Get("/user").
+		Expect(t).
+		Body(`{"id": "<<number>>", "name": "alice"}`).
+		IgnoreJSONPaths("$.role").
+		End()
+
+	spectest.DefaultVerifier{}.Equal(t, []string{
+		"response body did not match expected JSON body:\n$.name: expected \"alice\", received \"bob\"",
+	}, failures)
+}
+
 func TestApiTestMatchesTextResponseBody(t *testing.T) {
 	handler := http.NewServeMux()
 	handler.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
@@ -844,6 +899,29 @@ func TestApiTestCustomAssert(t *testing.T) {
 		End()
 }
 
+func TestApiTestContractRunsBeforeCustomAssert(t *testing.T) {
+	var calls []string
+	contract := func(res *http.Response, req *http.Request) error {
+		calls = append(calls, "contract "+req.URL.Path)
+		return nil
+	}
+
+	spectest.New().
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}).
+		Contract(contract).
+		Get("/hello").
+		Expect(t).
+		Assert(func(res *http.Response, req *http.Request) error {
+			calls = append(calls, "assert")
+			return nil
+		}).
+		End()
+
+	spectest.DefaultVerifier{}.Equal(t, []string{"contract /hello", "assert"}, calls)
+}
+
 func TestApiTestVerifierCapturesTheTestMessage(t *testing.T) {
 	verifier := mocks.NewVerifier()
 	verifier.EqualFn = func(t spectest.TestingT, expected, actual interface{}, msgAndArgs ...interface{}) bool {
@@ -1330,7 +1408,8 @@ func TestApiTestErrorIfMockInvocationsDoNotMatchTimes(t *testing.T) {
 
 	verifier := mocks.NewVerifier()
 	verifier.FailFn = func(t spectest.TestingT, failureMessage string, msgAndArgs ...interface{}) bool {
-		spectest.DefaultVerifier{}.Equal(t, "mock was not invoked expected times", failureMessage)
+		spectest.DefaultVerifier{}.Equal(t,
+			"mock was not invoked expected times: GET http://localhost:8080 was called 1 time, expected 2 times", failureMessage)
 		return true
 	}
 
@@ -1351,6 +1430,26 @@ func TestApiTestErrorIfMockInvocationsDoNotMatchTimes(t *testing.T) {
 	spectest.DefaultVerifier{}.Equal(t, "http://localhost:8080", unmatchedMocks[0].URL.String())
 }
 
+func TestApiTestUnmatchedMocksListsEveryUnusedMock(t *testing.T) {
+	res := spectest.New().
+		Mocks(
+			spectest.NewMock().Get("http://localhost:8080/users").RespondWith().Status(http.StatusOK).End(),
+			spectest.NewMock().Get("http://localhost:8080/orders").RespondWith().Status(http.StatusOK).End(),
+		).
+		HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}).
+		Get("/").
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+
+	unmatchedMocks := res.UnmatchedMocks()
+	spectest.DefaultVerifier{}.Equal(t, 2, len(unmatchedMocks))
+	spectest.DefaultVerifier{}.Equal(t, "http://localhost:8080/users", unmatchedMocks[0].URL.String())
+	spectest.DefaultVerifier{}.Equal(t, "http://localhost:8080/orders", unmatchedMocks[1].URL.String())
+}
+
 func TestApiTestMatchesTimes(t *testing.T) {
 	getUser := spectest.NewMock().
 		Get("http://localhost:8080").
@@ -1373,6 +1472,124 @@ func TestApiTestMatchesTimes(t *testing.T) {
 	spectest.DefaultVerifier{}.Equal(t, 0, len(res.UnmatchedMocks()))
 }
 
+func TestApiTestMockCallCountExpectations(t *testing.T) {
+	tests := map[string]struct {
+		mock            *spectest.MockResponse
+		calls           int
+		expectedFailure string
+	}{
+		"at least": {
+			mock:  newCountMock().AtLeast(2),
+			calls: 3,
+		},
+		"at least not reached": {
+			mock:            newCountMock().AtLeast(2),
+			calls:           1,
+			expectedFailure: "mock was not invoked expected times: GET http://localhost:8080/count was called 1 time, expected at least 2 times",
+		},
+		"at most": {
+			mock:  newCountMock().AtMost(2),
+			calls: 0,
+		},
+		"at most exceeded": {
+			mock:            newCountMock().AtMost(2),
+			calls:           3,
+			expectedFailure: "mock was not invoked expected times: GET http://localhost:8080/count was called 3 times, expected at most 2 times",
+		},
+		"never": {
+			mock:  newCountMock().Never(),
+			calls: 0,
+		},
+		"never called": {
+			mock:            newCountMock().Never(),
+			calls:           1,
+			expectedFailure: "mock was not invoked expected times: GET http://localhost:8080/count was called 1 time, expected never",
+		},
+		"any times": {
+			mock:  newCountMock().AnyTimes(),
+			calls: 5,
+		},
+		"times exceeded": {
+			mock:            newCountMock().Times(1),
+			calls:           2,
+			expectedFailure: "mock was not invoked expected times: GET http://localhost:8080/count was called 2 times, expected 1 time",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var failures []string
+			verifier := mocks.NewVerifier()
+			verifier.FailFn = func(t spectest.TestingT, failureMessage string, msgAndArgs ...interface{}) bool {
+				failures = append(failures, failureMessage)
+				return true
+			}
+
+			spectest.New().
+				Mocks(test.mock.End()).
+				Verifier(verifier).
+				HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					for i := 0; i < test.calls; i++ {
+						res, err := http.Get("http://localhost:8080/count")
+						if err == nil {
+							_ = res.Body.Close()
+						}
+					}
+					w.WriteHeader(http.StatusOK)
+				}).
+				Get("/").
+				Expect(t).
+				Status(http.StatusOK).
+				End()
+
+			if test.expectedFailure == "" {
+				spectest.DefaultVerifier{}.Equal(t, 0, len(failures))
+				return
+			}
+			spectest.DefaultVerifier{}.Equal(t, []string{test.expectedFailure}, failures)
+		})
+	}
+}
+
+func TestApiTestMockCalledMoreThanExpectedReturnsError(t *testing.T) {
+	getUser := spectest.NewMock().
+		Get("http://localhost:8080/count").
+		RespondWith().
+		Status(http.StatusOK).
+		Times(1).
+		End()
+	var callErr error
+
+	spectest.New().
+		Mocks(getUser).
+		Verifier(mocks.NewVerifier()).
+		HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			for i := 0; i < 2; i++ {
+				res, err := http.Get("http://localhost:8080/count")
+				if err != nil {
+					callErr = err
+					continue
+				}
+				_ = res.Body.Close()
+			}
+			w.WriteHeader(http.StatusOK)
+		}).
+		Get("/").
+		Expect(t).
+		End()
+
+	spectest.DefaultVerifier{}.True(t, strings.Contains(callErr.Error(),
+		"mock GET http://localhost:8080/count was already called 1 time, expected 1 time"))
+	spectest.DefaultVerifier{}.Equal(t, 2, len(getUser.Calls()))
+}
+
+func newCountMock() *spectest.MockResponse {
+	return spectest.NewMock().
+		Get("http://localhost:8080/count").
+		RespondWith().
+		Status(http.StatusOK)
+}
+
 type RecorderCaptor struct {
 	capturedRecorder spectest.Recorder
 }
@@ -1756,4 +1973,18 @@ func TestGoldenFile(t *testing.T) {
 			Status(http.StatusOK).
 			End()
 	})
+
+	t.Run("The golden file can be used more than once in a test binary.", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "golden.json")
+		for i := 0; i < 2; i++ {
+			spectest.New().
+				HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					_, _ = w.Write([]byte(`{"a": 12345}`))
+				}).
+				Get("/hello").
+				Expect(t).
+				BodyFromGoldenFile(path).
+				End()
+		}
+	})
 }
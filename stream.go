@@ -0,0 +1,612 @@
+package spectest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultStreamTimeout is the default maximum duration to read a streaming response.
+const defaultStreamTimeout = 5 * time.Second
+
+// ServerSentEvent is an event of a text/event-stream response.
+type ServerSentEvent struct {
+	// ID is the value of the id field
+	ID string
+	// Event is the value of the event field
+	Event string
+	// Data is the value of the data fields. Multiple data fields are joined with a new line.
+	Data string
+	// Retry is the value of the retry field in milliseconds
+	Retry int
+	// Elapsed is the time from the start of the request until the event was received.
+	// It is ignored when events are compared.
+	Elapsed time.Duration
+}
+
+// StreamChunk is a chunk of a streaming response. For NDJSON responses, a chunk is a line.
+// For other responses, a chunk is a piece of the body written by the handler at once.
+type StreamChunk struct {
+	// Data is the content of the chunk
+	Data string
+	// Elapsed is the time from the start of the request until the chunk was received.
+	// It is ignored when chunks are compared.
+	Elapsed time.Duration
+}
+
+// stream is the expectation of a streaming response.
+// The response body is read event by event (or chunk by chunk) while the handler is writing it.
+type stream struct {
+	// events is the expected events
+	events []ServerSentEvent
+	// eventsSet is true if the events are expected
+	eventsSet bool
+	// eventCount is the expected number of events. -1 means no expectation.
+	eventCount int
+	// assertEvents is the list of user defined assertions for the events
+	assertEvents []func([]ServerSentEvent) error
+	// chunks is the expected chunks
+	chunks []string
+	// chunksSet is true if the chunks are expected
+	chunksSet bool
+	// jsonChunks is true if the chunks are compared as JSON
+	jsonChunks bool
+	// chunkCount is the expected number of chunks. -1 means no expectation.
+	chunkCount int
+	// assertChunks is the list of user defined assertions for the chunks
+	assertChunks []func([]StreamChunk) error
+	// cancelAfter is the number of events or chunks to read before the request is cancelled. 0 means never.
+	cancelAfter int
+	// timeout is the maximum duration to read the stream
+	timeout time.Duration
+
+	// cancel cancels the request
+	cancel context.CancelFunc
+	// handlerDone is closed when the handler returns. It is nil if networking is enabled.
+	handlerDone chan struct{}
+	// handlerPanic is the value recovered from the handler
+	handlerPanic interface{}
+	// receivedEvents is the events received in the last run
+	receivedEvents []ServerSentEvent
+	// receivedChunks is the chunks received in the last run
+	receivedChunks []StreamChunk
+	// isEventStream is true if the last response was a text/event-stream
+	isEventStream bool
+	// isNDJSON is true if the last response was newline delimited JSON
+	isNDJSON bool
+	// timedOut is true if the stream did not start or end before the timeout in the last run
+	timedOut bool
+}
+
+// newStream creates a new stream expectation
+func newStream() *stream {
+	return &stream{
+		eventCount: -1,
+		chunkCount: -1,
+		timeout:    defaultStreamTimeout,
+	}
+}
+
+// streamExpectation returns the stream expectation of the response, creating it if needed.
+func (r *Response) streamExpectation() *stream {
+	if r.stream == nil {
+		r.stream = newStream()
+	}
+	return r.stream
+}
+
+// Events is the expected sequence of server-sent events. The response must be a text/event-stream.
+// The events are compared by id, event, data and retry.
+func (r *Response) Events(events ...ServerSentEvent) *Response {
+	st := r.streamExpectation()
+	st.events = append(st.events, events...)
+	st.eventsSet = true
+	return r
+}
+
+// EventCount is the expected number of server-sent events
+func (r *Response) EventCount(n int) *Response {
+	r.streamExpectation().eventCount = n
+	return r
+}
+
+// AssertEvents allows the consumer to provide a user defined function containing their own
+// assertions for the server-sent events, e.g. the time between events
+func (r *Response) AssertEvents(fn func([]ServerSentEvent) error) *Response {
+	st := r.streamExpectation()
+	st.assertEvents = append(st.assertEvents, fn)
+	return r
+}
+
+// Chunks is the expected sequence of chunks of a streaming response (NDJSON lines or written pieces of the body)
+func (r *Response) Chunks(chunks ...string) *Response {
+	st := r.streamExpectation()
+	st.chunks = append(st.chunks, chunks...)
+	st.chunksSet = true
+	return r
+}
+
+// JSONChunks is the expected sequence of chunks of a streaming response. Each chunk is compared as JSON.
+func (r *Response) JSONChunks(chunks ...string) *Response {
+	r.Chunks(chunks...)
+	r.stream.jsonChunks = true
+	return r
+}
+
+// ChunkCount is the expected number of chunks of a streaming response
+func (r *Response) ChunkCount(n int) *Response {
+	r.streamExpectation().chunkCount = n
+	return r
+}
+
+// AssertChunks allows the consumer to provide a user defined function containing their own
+// assertions for the chunks of a streaming response
+func (r *Response) AssertChunks(fn func([]StreamChunk) error) *Response {
+	st := r.streamExpectation()
+	st.assertChunks = append(st.assertChunks, fn)
+	return r
+}
+
+// CancelAfter cancels the request after n events or chunks have been received.
+// Use it to test endless streams.
+func (r *Response) CancelAfter(n int) *Response {
+	r.streamExpectation().cancelAfter = n
+	return r
+}
+
+// StreamTimeout sets the maximum duration to read a streaming response. The default is 5 seconds.
+// The test fails if the handler does not start the stream, or the stream does not end or is not cancelled
+// by CancelAfter, before the timeout.
+func (r *Response) StreamTimeout(timeout time.Duration) *Response {
+	r.streamExpectation().timeout = timeout
+	return r
+}
+
+// prepare binds the request to a cancellable context.
+func (st *stream) prepare(req *http.Request) *http.Request {
+	ctx, cancel := context.WithCancel(req.Context())
+	st.cancel = cancel
+	st.timedOut = false
+	st.handlerDone = nil
+	st.handlerPanic = nil
+	return req.WithContext(ctx)
+}
+
+// serveStream serves the request with the http handler in a new goroutine.
+// The returned response body is read while the handler is writing it.
+func (s *SpecTest) serveStream(req *http.Request) *http.Response {
+	st := s.response.stream
+	pr, pw := io.Pipe()
+	w := newStreamWriter(pw)
+	done := make(chan struct{})
+	st.handlerDone = done
+
+	go func() {
+		defer close(done)
+		defer func() {
+			if err := recover(); err != nil {
+				st.handlerPanic = err
+				w.WriteHeader(http.StatusInternalServerError)
+				pw.CloseWithError(fmt.Errorf("handler panicked: %v", err))
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			pw.Close() //nolint
+		}()
+		s.handler.ServeHTTP(w, req)
+	}()
+
+	timer := time.NewTimer(st.timeout)
+	defer timer.Stop()
+	select {
+	case <-w.ready:
+	case <-done:
+	case <-timer.C:
+		// the handler did not send the header, e.g. it is waiting for an event
+		st.timedOut = true
+		st.cancel()
+		pr.Close() //nolint
+		return &http.Response{
+			Header:        http.Header{},
+			Body:          http.NoBody,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			ContentLength: 0,
+		}
+	}
+	return &http.Response{
+		StatusCode:    w.status,
+		Status:        fmt.Sprintf("%d %s", w.status, http.StatusText(w.status)),
+		Header:        w.snapshot,
+		Body:          pr,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		ContentLength: -1,
+	}
+}
+
+// read reads the streaming response event by event (or chunk by chunk) until the stream ends,
+// the number of messages given to CancelAfter is received or the timeout expires.
+// It returns a copy of the response whose body holds what was read.
+func (st *stream) read(s *SpecTest, res *http.Response) *http.Response {
+	defer st.cancel()
+	st.receivedEvents = nil
+	st.receivedChunks = nil
+	if res == nil || res.Body == nil {
+		return res
+	}
+	if st.timedOut {
+		st.waitHandler()
+		return res
+	}
+	st.isEventStream = isEventStream(res.Header)
+	st.isNDJSON = isNDJSON(res.Header)
+
+	var body bytes.Buffer
+	messages := make(chan interface{})
+	stop := make(chan struct{})
+	finished := make(chan error, 1)
+	go func() {
+		finished <- st.parse(io.TeeReader(res.Body, &body), s.interval.Started, messages, stop)
+	}()
+
+	timer := time.NewTimer(st.timeout)
+	defer timer.Stop()
+	received := 0
+loop:
+	for {
+		select {
+		case message := <-messages:
+			st.receive(s, message)
+			received++
+			if st.cancelAfter > 0 && received >= st.cancelAfter {
+				break loop
+			}
+		case <-finished:
+			finished <- nil
+			break loop
+		case <-timer.C:
+			st.timedOut = true
+			break loop
+		}
+	}
+	close(stop)
+	st.cancel()
+	res.Body.Close() //nolint
+	<-finished
+	if st.waitHandler() && st.handlerPanic != nil {
+		s.t.Fatalf("%s", st.handlerPanic)
+	}
+
+	copied := copyHTTPResponse(&http.Response{
+		Header:        res.Header,
+		StatusCode:    res.StatusCode,
+		Status:        res.Status,
+		Body:          io.NopCloser(&body),
+		Proto:         res.Proto,
+		ProtoMajor:    res.ProtoMajor,
+		ProtoMinor:    res.ProtoMinor,
+		ContentLength: int64(body.Len()),
+	})
+	s.debug.dumpResponse(copied)
+	return copied
+}
+
+// waitHandler waits for the handler to return after the request was cancelled, and returns
+// true if it returned. A handler that ignores the cancellation is abandoned after the timeout.
+func (st *stream) waitHandler() bool {
+	if st.handlerDone == nil {
+		return false
+	}
+	select {
+	case <-st.handlerDone:
+		return true
+	case <-time.After(st.timeout):
+		return false
+	}
+}
+
+// receive stores and records the received event or chunk.
+func (st *stream) receive(s *SpecTest, message interface{}) {
+	var header, body string
+	switch m := message.(type) {
+	case ServerSentEvent:
+		st.receivedEvents = append(st.receivedEvents, m)
+		header, body = m.recordHeader(), m.Data
+	case StreamChunk:
+		st.receivedChunks = append(st.receivedChunks, m)
+		header, body = fmt.Sprintf("chunk %d", len(st.receivedChunks)), m.Data
+	}
+	if s.recorder == nil {
+		return
+	}
+	s.recorder.AddMessageResponse(MessageResponse{
+		Source:    SystemUnderTestDefaultName,
+		Target:    ConsumerDefaultName,
+		Header:    header,
+		Body:      body,
+		Timestamp: time.Now().UTC(),
+	})
+}
+
+// parse reads the body and sends each event or chunk to messages until the body ends or stop is closed.
+func (st *stream) parse(body io.Reader, started time.Time, messages chan<- interface{}, stop <-chan struct{}) error {
+	send := func(message interface{}) bool {
+		select {
+		case messages <- message:
+			return true
+		case <-stop:
+			return false
+		}
+	}
+	switch {
+	case st.isEventStream:
+		return parseEvents(body, func(e ServerSentEvent) bool {
+			e.Elapsed = time.Since(started)
+			return send(e)
+		})
+	default:
+		return parseChunks(body, st.lineDelimited(), func(data string) bool {
+			return send(StreamChunk{Data: data, Elapsed: time.Since(started)})
+		})
+	}
+}
+
+// lineDelimited returns true if the chunks are lines of the body.
+func (st *stream) lineDelimited() bool {
+	return st.jsonChunks || st.isNDJSON
+}
+
+// parseEvents parses a text/event-stream body. An event is dispatched on a blank line
+// if at least one field was set. Comment lines are ignored.
+func parseEvents(body io.Reader, dispatch func(ServerSentEvent) bool) error {
+	scanner := bufio.NewScanner(body)
+	var event ServerSentEvent
+	var data []string
+	fieldSet := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if fieldSet {
+				event.Data = strings.Join(data, "\n")
+				if !dispatch(event) {
+					return nil
+				}
+			}
+			event, data, fieldSet = ServerSentEvent{}, nil, false
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		name, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch name {
+		case "id":
+			event.ID = value
+		case "event":
+			event.Event = value
+		case "data":
+			data = append(data, value)
+		case "retry":
+			retry, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			event.Retry = retry
+		default:
+			continue
+		}
+		fieldSet = true
+	}
+	return scanner.Err()
+}
+
+// parseChunks reads the body line by line if lineDelimited is true, otherwise read by read.
+// Empty lines are ignored.
+func parseChunks(body io.Reader, lineDelimited bool, dispatch func(string) bool) error {
+	if lineDelimited {
+		scanner := bufio.NewScanner(body)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			if !dispatch(line) {
+				return nil
+			}
+		}
+		return scanner.Err()
+	}
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 && !dispatch(string(buf[:n])) {
+			return nil
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// assert runs the assertions of the stream.
+func (st *stream) assert(s *SpecTest) {
+	if st.timedOut {
+		s.check(FailureStream, "").Fail(s.t, fmt.Sprintf("stream did not end within %s", st.timeout), failureMessageArgs{Name: s.name})
+	}
+	if st.eventsSet || st.eventCount >= 0 || len(st.assertEvents) > 0 {
+		st.assertServerSentEvents(s)
+	}
+	if st.chunksSet || st.chunkCount >= 0 || len(st.assertChunks) > 0 {
+		st.assertStreamChunks(s)
+	}
+}
+
+// assertServerSentEvents asserts the received server-sent events.
+func (st *stream) assertServerSentEvents(s *SpecTest) {
+	if !st.isEventStream {
+		s.check(FailureStream, "").Fail(s.t, "expected a text/event-stream response", failureMessageArgs{Name: s.name})
+		return
+	}
+	if st.eventCount >= 0 {
+		s.check(FailureStream, "").Equal(s.t, st.eventCount, len(st.receivedEvents),
+			fmt.Sprintf("Event count %d not equal to %d", len(st.receivedEvents), st.eventCount), failureMessageArgs{Name: s.name})
+	}
+	if st.eventsSet {
+		s.check(FailureStream, "").Equal(s.t, withoutElapsed(st.events), withoutElapsed(st.receivedEvents), failureMessageArgs{Name: s.name})
+	}
+	for _, fn := range st.assertEvents {
+		if err := fn(st.receivedEvents); err != nil {
+			s.check(FailureStream, "").NoError(s.t, err, failureMessageArgs{Name: s.name})
+		}
+	}
+}
+
+// assertStreamChunks asserts the received chunks.
+func (st *stream) assertStreamChunks(s *SpecTest) {
+	if st.chunkCount >= 0 {
+		s.check(FailureStream, "").Equal(s.t, st.chunkCount, len(st.receivedChunks),
+			fmt.Sprintf("Chunk count %d not equal to %d", len(st.receivedChunks), st.chunkCount), failureMessageArgs{Name: s.name})
+	}
+	if st.chunksSet {
+		received := make([]string, 0, len(st.receivedChunks))
+		for _, chunk := range st.receivedChunks {
+			received = append(received, chunk.Data)
+		}
+		if st.jsonChunks && len(received) == len(st.chunks) {
+			for i := range st.chunks {
+				s.check(FailureStream, "").JSONEq(s.t, st.chunks[i], received[i], failureMessageArgs{Name: s.name})
+			}
+		} else {
+			s.check(FailureStream, "").Equal(s.t, st.chunks, received, failureMessageArgs{Name: s.name})
+		}
+	}
+	for _, fn := range st.assertChunks {
+		if err := fn(st.receivedChunks); err != nil {
+			s.check(FailureStream, "").NoError(s.t, err, failureMessageArgs{Name: s.name})
+		}
+	}
+}
+
+// recordHeader returns the header of the event shown in the report.
+func (e ServerSentEvent) recordHeader() string {
+	name := e.Event
+	if name == "" {
+		name = "message"
+	}
+	header := "event: " + name
+	if e.ID != "" {
+		header += ", id: " + e.ID
+	}
+	if e.Retry != 0 {
+		header += ", retry: " + strconv.Itoa(e.Retry)
+	}
+	return header
+}
+
+// withoutElapsed returns a copy of the events without the elapsed time, so that they can be compared.
+func withoutElapsed(events []ServerSentEvent) []ServerSentEvent {
+	copied := make([]ServerSentEvent, 0, len(events))
+	for _, e := range events {
+		e.Elapsed = 0
+		copied = append(copied, e)
+	}
+	return copied
+}
+
+// isEventStream returns true if the content type of the header is text/event-stream.
+func isEventStream(header http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && mediaType == "text/event-stream"
+}
+
+// isNDJSON returns true if the content type of the header is newline delimited JSON.
+func isNDJSON(header http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines":
+		return true
+	}
+	return false
+}
+
+// streamWriter is a http.ResponseWriter that writes the body to a pipe, so that the body
+// can be read while the handler is writing it.
+type streamWriter struct {
+	// header is the header map of the handler
+	header http.Header
+	// snapshot is the header at the time WriteHeader was called
+	snapshot http.Header
+	// status is the status code
+	status int
+	// wroteHeader is true if WriteHeader was called
+	wroteHeader bool
+	// ready is closed when WriteHeader is called
+	ready chan struct{}
+	// body is the write side of the pipe
+	body *io.PipeWriter
+}
+
+var (
+	_ http.ResponseWriter = &streamWriter{}
+	_ http.Flusher        = &streamWriter{}
+)
+
+// newStreamWriter creates a new streamWriter
+func newStreamWriter(body *io.PipeWriter) *streamWriter {
+	return &streamWriter{
+		header: http.Header{},
+		ready:  make(chan struct{}),
+		body:   body,
+	}
+}
+
+// Header returns the header map
+func (w *streamWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader sends the status code and the header
+func (w *streamWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = statusCode
+	w.snapshot = w.header.Clone()
+	close(w.ready)
+}
+
+// Write writes the data to the pipe. It blocks until the data is read.
+func (w *streamWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.header.Get("Content-Type") == "" {
+			w.header.Set("Content-Type", http.DetectContentType(data))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.body.Write(data)
+}
+
+// Flush sends the header if it was not sent yet. The body is never buffered.
+func (w *streamWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,178 @@
+package spectest_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nao1215/spectest"
+	"github.com/nao1215/spectest/mocks"
+)
+
+func sseHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	flusher := w.(http.Flusher)
+	_, _ = fmt.Fprint(w, ": welcome\n\nretry: 3000\n\n")
+	flusher.Flush()
+	for i := 1; i <= 2; i++ {
+		_, _ = fmt.Fprintf(w, "id: %d\nevent: tick\ndata: line %d\ndata: end\n\n", i, i)
+		flusher.Flush()
+	}
+}
+
+func endlessHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	for i := 0; ; i++ {
+		select {
+		case <-r.Context().Done():
+			return
+		default:
+		}
+		if _, err := fmt.Fprintf(w, "{\"n\": %d}\n", i); err != nil {
+			return
+		}
+		w.(http.Flusher).Flush()
+	}
+}
+
+func TestStreamServerSentEvents(t *testing.T) {
+	spectest.New().
+		HandlerFunc(sseHandler).
+		Get("/events").
+		Expect(t).
+		Status(http.StatusOK).
+		EventCount(3).
+		Events(
+			spectest.ServerSentEvent{Retry: 3000},
+			spectest.ServerSentEvent{ID: "1", Event: "tick", Data: "line 1\nend"},
+			spectest.ServerSentEvent{ID: "2", Event: "tick", Data: "line 2\nend"},
+		).
+		AssertEvents(func(events []spectest.ServerSentEvent) error {
+			for i := 1; i < len(events); i++ {
+				if events[i].Elapsed < events[i-1].Elapsed {
+					return errors.New("events are not in order")
+				}
+			}
+			return nil
+		}).
+		End()
+}
+
+func TestStreamCancelAfterEvents(t *testing.T) {
+	spectest.New().
+		HandlerFunc(endlessHandler).
+		Get("/numbers").
+		Expect(t).
+		CancelAfter(3).
+		JSONChunks(`{"n": 0}`, `{"n": 1}`, `{"n": 2}`).
+		End()
+}
+
+func TestStreamChunks(t *testing.T) {
+	spectest.New().
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("hello "))
+			_, _ = w.Write([]byte("world"))
+		}).
+		Get("/chunks").
+		Expect(t).
+		Chunks("hello ", "world").
+		ChunkCount(2).
+		Body("hello world").
+		End()
+}
+
+func TestStreamFailsOnTimeout(t *testing.T) {
+	var failures []string
+	verifier := mocks.NewVerifier()
+	verifier.FailFn = func(t spectest.TestingT, msg string, msgAndArgs ...interface{}) bool {
+		failures = append(failures, msg)
+		return false
+	}
+
+	spectest.New().
+		Verifier(verifier).
+		HandlerFunc(endlessHandler).
+		Get("/numbers").
+		Expect(t).
+		StreamTimeout(50 * time.Millisecond).
+		End()
+
+	spectest.DefaultVerifier{}.Equal(t, []string{"stream did not end within 50ms"}, failures)
+}
+
+func TestStreamFailsOnTimeoutWhenTheHandlerNeverWrites(t *testing.T) {
+	var failures []string
+	verifier := mocks.NewVerifier()
+	verifier.FailFn = func(t spectest.TestingT, msg string, msgAndArgs ...interface{}) bool {
+		failures = append(failures, msg)
+		return false
+	}
+
+	spectest.New().
+		Verifier(verifier).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}).
+		Get("/events").
+		Expect(t).
+		StreamTimeout(50 * time.Millisecond).
+		End()
+
+	spectest.DefaultVerifier{}.Equal(t, []string{"stream did not end within 50ms"}, failures)
+}
+
+func TestStreamFailsWhenEventsAreExpectedFromOtherContent(t *testing.T) {
+	var failures []string
+	verifier := mocks.NewVerifier()
+	verifier.FailFn = func(t spectest.TestingT, msg string, msgAndArgs ...interface{}) bool {
+		failures = append(failures, msg)
+		return false
+	}
+
+	spectest.New().
+		Verifier(verifier).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{}`))
+		}).
+		Get("/json").
+		Expect(t).
+		EventCount(1).
+		End()
+
+	spectest.DefaultVerifier{}.Equal(t, []string{"expected a text/event-stream response"}, failures)
+}
+
+func TestStreamRecordsEventsInReport(t *testing.T) {
+	reporter := &RecorderCaptor{}
+	spectest.New().
+		Report(reporter).
+		HandlerFunc(sseHandler).
+		Get("/events").
+		Expect(t).
+		EventCount(3).
+		End()
+
+	r := reporter.capturedRecorder
+	spectest.DefaultVerifier{}.Equal(t, 5, len(r.Events))
+	spectest.DefaultVerifier{}.Equal(t, http.StatusOK, r.Meta.StatusCode)
+
+	var headers []string
+	for _, event := range r.Events {
+		if e, ok := event.(spectest.MessageResponse); ok {
+			headers = append(headers, e.Header)
+		}
+	}
+	spectest.DefaultVerifier{}.Equal(t, []string{
+		"event: message, retry: 3000",
+		"event: tick, id: 1",
+		"event: tick, id: 2",
+	}, headers)
+	spectest.DefaultVerifier{}.True(t, strings.Contains(r.Events[4].(spectest.HTTPResponse).Value.Status, "200"))
+}
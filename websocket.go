@@ -0,0 +1,359 @@
+package spectest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// defaultWebSocketTimeout is the default timeout for sending or receiving a single frame.
+const defaultWebSocketTimeout = 5 * time.Second
+
+// WebSocket is the user defined WebSocket conversation with the handler under test.
+// The handler is started on an in-process server, and the frames are sent and received in order.
+type WebSocket struct {
+	specTest *SpecTest
+	url      string
+	headers  http.Header
+	timeout  time.Duration
+	frames   []webSocketFrame
+}
+
+// webSocketFrame is a frame to send or an expectation of a received frame.
+type webSocketFrame struct {
+	// send is true if the frame is sent to the handler, false if the frame is expected from the handler
+	send bool
+	// messageType is websocket.TextMessage or websocket.BinaryMessage
+	messageType int
+	// data is the frame payload. For expected frames, it is the expected payload.
+	data []byte
+	// json is true if the expected payload is compared as JSON
+	json bool
+	// assert is the user defined assertion for the received frame
+	assert func(messageType int, data []byte) error
+}
+
+// WebSocket is a builder method for testing a WebSocket endpoint of the handler.
+// The url is the path of the endpoint, e.g. "/ws".
+func (s *SpecTest) WebSocket(url string) *WebSocket {
+	return &WebSocket{
+		specTest: s,
+		url:      url,
+		headers:  http.Header{},
+		timeout:  defaultWebSocketTimeout,
+	}
+}
+
+// Header is a builder method to set the headers of the handshake request
+func (w *WebSocket) Header(key, value string) *WebSocket {
+	w.headers.Add(textproto.CanonicalMIMEHeaderKey(key), value)
+	return w
+}
+
+// Timeout sets the maximum duration to send or receive a single frame. The default is 5 seconds.
+func (w *WebSocket) Timeout(timeout time.Duration) *WebSocket {
+	w.timeout = timeout
+	return w
+}
+
+// SendText sends a text frame to the handler
+func (w *WebSocket) SendText(message string) *WebSocket {
+	w.frames = append(w.frames, webSocketFrame{send: true, messageType: websocket.TextMessage, data: []byte(message)})
+	return w
+}
+
+// SendBinary sends a binary frame to the handler
+func (w *WebSocket) SendBinary(data []byte) *WebSocket {
+	w.frames = append(w.frames, webSocketFrame{send: true, messageType: websocket.BinaryMessage, data: data})
+	return w
+}
+
+// SendJSON sends a text frame to the handler.
+// If v is not a string or []byte it will marshall the provided variable as json
+func (w *WebSocket) SendJSON(v interface{}) *WebSocket {
+	data, err := marshalJSON(v)
+	if err != nil {
+		panic(err)
+	}
+	w.frames = append(w.frames, webSocketFrame{send: true, messageType: websocket.TextMessage, data: data})
+	return w
+}
+
+// ExpectText expects the next frame from the handler to be the given text frame
+func (w *WebSocket) ExpectText(message string) *WebSocket {
+	w.frames = append(w.frames, webSocketFrame{messageType: websocket.TextMessage, data: []byte(message)})
+	return w
+}
+
+// ExpectBinary expects the next frame from the handler to be the given binary frame
+func (w *WebSocket) ExpectBinary(data []byte) *WebSocket {
+	w.frames = append(w.frames, webSocketFrame{messageType: websocket.BinaryMessage, data: data})
+	return w
+}
+
+// ExpectJSON expects the next frame from the handler to be a text frame that is equivalent to the given JSON.
+// If v is not a string or []byte it will marshall the provided variable as json
+func (w *WebSocket) ExpectJSON(v interface{}) *WebSocket {
+	data, err := marshalJSON(v)
+	if err != nil {
+		panic(err)
+	}
+	w.frames = append(w.frames, webSocketFrame{messageType: websocket.TextMessage, data: data, json: true})
+	return w
+}
+
+// ExpectMessage expects the next frame from the handler to pass the given assertion.
+// messageType is websocket.TextMessage or websocket.BinaryMessage.
+func (w *WebSocket) ExpectMessage(fn func(messageType int, data []byte) error) *WebSocket {
+	w.frames = append(w.frames, webSocketFrame{assert: fn})
+	return w
+}
+
+// End runs the conversation with the handler. The frames are sent and received in the order
+// they were defined. If a frame is not received before the timeout, the remaining frames are skipped.
+func (w *WebSocket) End(t TestingT) {
+	s := w.specTest
+	s.t = t
+	if s.verifier == nil {
+		s.verifier = DefaultVerifier{}
+	}
+	if s.handler == nil {
+		t.Fatal("define a http.Handler to test a WebSocket endpoint")
+		return
+	}
+	if s.recorder == nil {
+		s.recorder = NewTestRecorder()
+	}
+	if s.reporter != nil {
+		defer s.recorder.Reset()
+	}
+
+	s.interval.Start()
+	if s.mocks.len() > 0 {
+		s.transport = newTransport(s.mocks, s.httpClient, s.debug, s.mockResponseDelayEnabled, s.mocksObservers, s)
+		if s.mocksIsolated {
+			s.transport.routeID = nextMockRouteID()
+		}
+		s.transport.passthrough = s.passthrough
+		defer s.transport.Reset()
+		s.transport.Hijack()
+	}
+
+	server := httptest.NewUnstartedServer(s.handler)
+	if s.transport != nil && s.transport.routeID != 0 {
+		// the handler is served by a real server, so the route id is carried by the base context of the server
+		ctx := withMockRoute(context.Background(), s.transport.routeID)
+		server.Config.BaseContext = func(net.Listener) context.Context { return ctx }
+	}
+	server.Start()
+	defer server.Close()
+
+	s.failures = nil
+	w.converse(server)
+	s.interval.End()
+	s.assertMocks()
+	s.assertMockSpies()
+	s.reportFailures()
+
+	if s.reporter != nil {
+		s.recorder.
+			AddTitle(fmt.Sprintf("WebSocket %s", s.interpolate(w.url))).
+			AddSubTitle(s.name).
+			AddMeta(w.newMeta())
+		s.reporter.Format(s.recorder)
+	}
+}
+
+// converse connects to the server and sends and receives the frames.
+func (w *WebSocket) converse(server *httptest.Server) {
+	s := w.specTest
+	path := s.interpolate(w.url)
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + path
+
+	dialer := websocket.Dialer{HandshakeTimeout: w.timeout}
+	w.recordRequest("GET "+path+" (upgrade)", headerString(w.headers))
+	conn, res, err := dialer.Dial(wsURL, w.headers)
+	if res != nil && res.Body != nil {
+		defer res.Body.Close() //nolint
+	}
+	if err != nil {
+		w.recordResponse("handshake failed", err.Error())
+		s.verifier.Fail(s.t, fmt.Sprintf("failed to connect to websocket %s: %s", path, err), failureMessageArgs{Name: s.name})
+		return
+	}
+	defer conn.Close() //nolint
+	w.recordResponse(res.Status, headerString(res.Header))
+
+	for i, frame := range w.frames {
+		if frame.send {
+			if err := w.send(conn, frame); err != nil {
+				s.verifier.Fail(s.t, fmt.Sprintf("failed to send websocket frame %d: %s", i+1, err), failureMessageArgs{Name: s.name})
+				return
+			}
+			continue
+		}
+		if err := w.receive(conn, frame); err != nil {
+			s.verifier.Fail(s.t, fmt.Sprintf("failed to receive websocket frame %d: %s", i+1, err), failureMessageArgs{Name: s.name})
+			return
+		}
+	}
+	w.close(conn)
+}
+
+// send sends the frame to the handler and records it.
+func (w *WebSocket) send(conn *websocket.Conn, frame webSocketFrame) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(w.timeout)); err != nil {
+		return err
+	}
+	data := frame.data
+	if frame.messageType == websocket.TextMessage {
+		data = []byte(w.specTest.interpolate(string(data)))
+	}
+	w.recordRequest(frameName(frame.messageType), string(data))
+	return conn.WriteMessage(frame.messageType, data)
+}
+
+// receive receives the next frame from the handler, records it and asserts it.
+func (w *WebSocket) receive(conn *websocket.Conn, frame webSocketFrame) error {
+	s := w.specTest
+	if err := conn.SetReadDeadline(time.Now().Add(w.timeout)); err != nil {
+		return err
+	}
+	messageType, data, err := conn.ReadMessage()
+	if err != nil {
+		return err
+	}
+	w.recordResponse(frameName(messageType), string(data))
+
+	if frame.assert != nil {
+		s.verifier.NoError(s.t, frame.assert(messageType, data), failureMessageArgs{Name: s.name})
+		return nil
+	}
+	if messageType != frame.messageType {
+		s.verifier.Fail(s.t, fmt.Sprintf("expected %s but received %s", frameName(frame.messageType), frameName(messageType)), failureMessageArgs{Name: s.name})
+		return nil
+	}
+	switch {
+	case frame.json:
+		s.verifier.JSONEq(s.t, s.interpolate(string(frame.data)), string(data), failureMessageArgs{Name: s.name})
+	case messageType == websocket.BinaryMessage:
+		if !bytes.Equal(frame.data, data) {
+			s.verifier.Equal(s.t, frame.data, data, failureMessageArgs{Name: s.name})
+		}
+	default:
+		s.verifier.Equal(s.t, s.interpolate(string(frame.data)), string(data), failureMessageArgs{Name: s.name})
+	}
+	return nil
+}
+
+// close sends a close frame and waits for the close frame of the handler.
+func (w *WebSocket) close(conn *websocket.Conn) {
+	w.recordRequest("close", "")
+	err := conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(w.timeout))
+	if err != nil {
+		w.recordResponse("closed", err.Error())
+		return
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(w.timeout)); err != nil {
+		w.recordResponse("closed", err.Error())
+		return
+	}
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) {
+				w.recordResponse(fmt.Sprintf("close %d", closeErr.Code), closeErr.Text)
+				return
+			}
+			w.recordResponse("closed", err.Error())
+			return
+		}
+	}
+}
+
+// recordRequest records a frame sent to the handler.
+func (w *WebSocket) recordRequest(header, body string) {
+	w.specTest.recorder.AddMessageRequest(MessageRequest{
+		Source:    ConsumerDefaultName,
+		Target:    SystemUnderTestDefaultName,
+		Header:    header,
+		Body:      body,
+		Timestamp: time.Now().UTC(),
+	})
+}
+
+// recordResponse records a frame received from the handler.
+func (w *WebSocket) recordResponse(header, body string) {
+	w.specTest.recorder.AddMessageResponse(MessageResponse{
+		Source:    SystemUnderTestDefaultName,
+		Target:    ConsumerDefaultName,
+		Header:    header,
+		Body:      body,
+		Timestamp: time.Now().UTC(),
+	})
+}
+
+// newMeta creates a new meta data object for the WebSocket report.
+func (w *WebSocket) newMeta() *Meta {
+	s := w.specTest
+	meta := newMeta()
+	meta.StatusCode = http.StatusSwitchingProtocols
+	meta.Path = s.interpolate(w.url)
+	meta.Method = http.MethodGet
+	meta.Duration = s.interval.Duration().Nanoseconds()
+	meta.Name = s.name
+	meta.ReportFileName = s.meta.ReportFileName
+	if s.meta.Host != "" {
+		meta.Host = s.meta.Host
+	}
+	return meta
+}
+
+// frameName returns the human readable name of the websocket message type.
+func frameName(messageType int) string {
+	switch messageType {
+	case websocket.TextMessage:
+		return "text frame"
+	case websocket.BinaryMessage:
+		return "binary frame"
+	case websocket.CloseMessage:
+		return "close frame"
+	case websocket.PingMessage:
+		return "ping frame"
+	case websocket.PongMessage:
+		return "pong frame"
+	}
+	return fmt.Sprintf("frame type %d", messageType)
+}
+
+// headerString returns the wire representation of the header.
+func headerString(header http.Header) string {
+	var buf bytes.Buffer
+	if err := header.Write(&buf); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// marshalJSON returns v as JSON. If v is a string or []byte, it is returned as is.
+func marshalJSON(v interface{}) ([]byte, error) {
+	switch x := v.(type) {
+	case string:
+		return []byte(x), nil
+	case []byte:
+		return x, nil
+	default:
+		return json.Marshal(x)
+	}
+}
@@ -0,0 +1,240 @@
+package spectest_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nao1215/spectest"
+	"github.com/nao1215/spectest/mocks"
+)
+
+func websocketEchoHandler() http.Handler {
+	handler := http.NewServeMux()
+	handler.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		header := http.Header{}
+		header.Set("X-Greeting", r.Header.Get("X-Greeting"))
+		conn, err := upgrader.Upgrade(w, r, header)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	})
+	handler.HandleFunc("/silent", func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	return handler
+}
+
+func TestWebSocketConversation(t *testing.T) {
+	spectest.New().
+		Handler(websocketEchoHandler()).
+		WebSocket("/echo").
+		Header("X-Greeting", "hello").
+		SendText("hello").
+		ExpectText("hello").
+		SendBinary([]byte{0x01, 0x02}).
+		ExpectBinary([]byte{0x01, 0x02}).
+		SendJSON(map[string]interface{}{"a": 1}).
+		ExpectJSON(`{"a": 1}`).
+		SendText("custom").
+		ExpectMessage(func(messageType int, data []byte) error {
+			if messageType != websocket.TextMessage || string(data) != "custom" {
+				return fmt.Errorf("unexpected message %q", data)
+			}
+			return nil
+		}).
+		End(t)
+}
+
+func TestWebSocketFailsOnUnexpectedMessage(t *testing.T) {
+	var equals []interface{}
+	var failures []string
+	verifier := mocks.NewVerifier()
+	verifier.EqualFn = func(t spectest.TestingT, expected, actual interface{}, msgAndArgs ...interface{}) bool {
+		equals = append(equals, expected, actual)
+		return false
+	}
+	verifier.FailFn = func(t spectest.TestingT, msg string, msgAndArgs ...interface{}) bool {
+		failures = append(failures, msg)
+		return false
+	}
+
+	spectest.New().
+		Handler(websocketEchoHandler()).
+		Verifier(verifier).
+		WebSocket("/echo").
+		SendText("hello").
+		ExpectText("goodbye").
+		SendText("hello").
+		ExpectBinary([]byte("hello")).
+		End(t)
+
+	spectest.DefaultVerifier{}.Equal(t, []interface{}{"goodbye", "hello"}, equals)
+	spectest.DefaultVerifier{}.Equal(t, []string{"expected binary frame but received text frame"}, failures)
+}
+
+func TestWebSocketFailsOnTimeout(t *testing.T) {
+	var failures []string
+	verifier := mocks.NewVerifier()
+	verifier.FailFn = func(t spectest.TestingT, msg string, msgAndArgs ...interface{}) bool {
+		failures = append(failures, msg)
+		return false
+	}
+
+	spectest.New().
+		Handler(websocketEchoHandler()).
+		Verifier(verifier).
+		WebSocket("/silent").
+		Timeout(50 * time.Millisecond).
+		SendText("hello").
+		ExpectText("hello").
+		ExpectText("never checked").
+		End(t)
+
+	spectest.DefaultVerifier{}.Equal(t, 1, len(failures))
+	spectest.DefaultVerifier{}.True(t, strings.HasPrefix(failures[0], "failed to receive websocket frame 2: "))
+	spectest.DefaultVerifier{}.Equal(t, false, verifier.EqualInvoked)
+}
+
+func TestWebSocketRecordsFramesInReport(t *testing.T) {
+	reporter := &RecorderCaptor{}
+	spectest.New("echo").
+		Handler(websocketEchoHandler()).
+		Report(reporter).
+		WebSocket("/echo").
+		SendText("hello").
+		ExpectText("hello").
+		End(t)
+
+	r := reporter.capturedRecorder
+	spectest.DefaultVerifier{}.Equal(t, "WebSocket /echo", r.Title)
+	spectest.DefaultVerifier{}.Equal(t, "echo", r.SubTitle)
+	spectest.DefaultVerifier{}.Equal(t, http.StatusSwitchingProtocols, r.Meta.StatusCode)
+	spectest.DefaultVerifier{}.Equal(t, 6, len(r.Events))
+
+	headers := make([]string, 0, len(r.Events))
+	for _, event := range r.Events {
+		switch e := event.(type) {
+		case spectest.MessageRequest:
+			headers = append(headers, "-> "+e.Header)
+		case spectest.MessageResponse:
+			headers = append(headers, "<- "+e.Header)
+		}
+	}
+	spectest.DefaultVerifier{}.Equal(t, []string{
+		"-> GET /echo (upgrade)",
+		"<- 101 Switching Protocols",
+		"-> text frame",
+		"<- text frame",
+		"-> close",
+		"<- close 1000",
+	}, headers)
+}
+
+// websocketQuotaHandler checks the quota with the downstream service, then echoes the frames.
+func websocketQuotaHandler(ctx func(r *http.Request) context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := http.NewRequestWithContext(ctx(r), http.MethodGet, "http://127.0.0.1:1/quota", nil)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		res.Body.Close() //nolint
+		websocketEchoHandler().ServeHTTP(w, r)
+	}
+}
+
+func TestWebSocketFailsWhenMocksAreNotCalled(t *testing.T) {
+	var failures []string
+	verifier := mocks.NewVerifier()
+	verifier.FailFn = func(t spectest.TestingT, msg string, msgAndArgs ...interface{}) bool {
+		failures = append(failures, msg)
+		return false
+	}
+	quotaMock := spectest.NewMock().
+		Get("http://127.0.0.1:1/quota").
+		RespondWith().
+		Status(http.StatusOK).
+		Times(2).
+		End()
+
+	spectest.New().
+		Verifier(verifier).
+		Mocks(quotaMock).
+		Handler(websocketEchoHandler()).
+		WebSocket("/echo").
+		SendText("hello").
+		ExpectText("hello").
+		End(t)
+
+	spectest.DefaultVerifier{}.Equal(t, []string{
+		"mock was not invoked expected times: GET http://127.0.0.1:1/quota was called 0 times, expected 2 times",
+	}, failures)
+}
+
+func TestWebSocketWithIsolatedMocks(t *testing.T) {
+	quotaMock := spectest.NewMock().
+		Get("http://127.0.0.1:1/quota").
+		RespondWith().
+		Status(http.StatusOK).
+		End()
+
+	spectest.New().
+		IsolateMocks().
+		Mocks(quotaMock).
+		Handler(websocketQuotaHandler(func(r *http.Request) context.Context { return r.Context() })).
+		WebSocket("/echo").
+		SendText("hello").
+		ExpectText("hello").
+		End(t)
+
+	// requests without the context of the test are not sent to its mocks
+	var failures []string
+	verifier := mocks.NewVerifier()
+	verifier.FailFn = func(t spectest.TestingT, msg string, msgAndArgs ...interface{}) bool {
+		failures = append(failures, msg)
+		return false
+	}
+	spectest.New().
+		IsolateMocks().
+		Verifier(verifier).
+		Mocks(quotaMock).
+		Handler(websocketQuotaHandler(func(*http.Request) context.Context { return context.Background() })).
+		WebSocket("/echo").
+		SendText("hello").
+		ExpectText("hello").
+		End(t)
+
+	spectest.DefaultVerifier{}.Equal(t, 1, len(failures))
+	spectest.DefaultVerifier{}.True(t, strings.HasPrefix(failures[0], "failed to connect to websocket /echo: "))
+}
@@ -0,0 +1,154 @@
+package spectest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strings"
+)
+
+// isXMLContentType returns true if the media type is XML, e.g. application/xml, text/xml or application/soap+xml
+func isXMLContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+// canonicalXML returns the canonical form of an XML document, so that two equivalent documents have the same form:
+// the XML declaration, the comments, the processing instructions and the white space between the elements are removed,
+// the text is trimmed, the attributes are sorted, the namespace prefixes are replaced with the namespace URIs and
+// the elements are indented. An empty element is written with a start and an end tag.
+func canonicalXML(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = true
+
+	var b strings.Builder
+	var elements []canonicalElement
+	hasRoot := false
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			if len(elements) == 0 && hasRoot {
+				return "", errors.New("XML document has more than one root element")
+			}
+			hasRoot = true
+			if len(elements) > 0 {
+				elements[len(elements)-1].closeStartTag(&b, true)
+			}
+			namespace := ""
+			if len(elements) > 0 {
+				namespace = elements[len(elements)-1].namespace
+			}
+			b.WriteString(strings.Repeat("  ", len(elements)) + "<" + token.Name.Local)
+			if token.Name.Space != namespace {
+				fmt.Fprintf(&b, " xmlns=%q", token.Name.Space)
+			}
+			for _, attr := range canonicalAttributes(token.Attr) {
+				fmt.Fprintf(&b, " %s=%q", attr.name, attr.value)
+			}
+			elements = append(elements, canonicalElement{name: token.Name.Local, namespace: token.Name.Space})
+		case xml.EndElement:
+			element := &elements[len(elements)-1]
+			element.closeStartTag(&b, false)
+			if element.hasChildren {
+				b.WriteString(strings.Repeat("  ", len(elements)-1))
+			}
+			b.WriteString("</" + element.name + ">\n")
+			elements = elements[:len(elements)-1]
+		case xml.CharData:
+			text := strings.TrimSpace(string(token))
+			if text == "" {
+				continue
+			}
+			if len(elements) == 0 {
+				return "", errors.New("XML document has text outside of the root element")
+			}
+			element := &elements[len(elements)-1]
+			if element.hasChildren {
+				element.closeStartTag(&b, true)
+				b.WriteString(strings.Repeat("  ", len(elements)))
+				escapeXMLText(&b, text)
+				b.WriteString("\n")
+				continue
+			}
+			element.closeStartTag(&b, false)
+			escapeXMLText(&b, text)
+		}
+	}
+	if !hasRoot {
+		return "", errors.New("XML document has no root element")
+	}
+	return b.String(), nil
+}
+
+// canonicalElement is an open element of the canonical form of an XML document
+type canonicalElement struct {
+	name      string
+	namespace string
+	// startTagClosed is true once the > of the start tag is written
+	startTagClosed bool
+	// hasChildren is true if the element has child elements, so that its content is indented
+	hasChildren bool
+}
+
+// closeStartTag writes the > of the start tag, followed by a new line if the element has children
+func (e *canonicalElement) closeStartTag(b *strings.Builder, child bool) {
+	if child && !e.hasChildren {
+		e.hasChildren = true
+		if !e.startTagClosed {
+			b.WriteString(">")
+		}
+		e.startTagClosed = true
+		b.WriteString("\n")
+		return
+	}
+	if !e.startTagClosed {
+		b.WriteString(">")
+		e.startTagClosed = true
+	}
+}
+
+// canonicalAttribute is an attribute of the canonical form of an XML document
+type canonicalAttribute struct {
+	name  string
+	value string
+}
+
+// canonicalAttributes returns the attributes sorted by name, without the namespace declarations.
+// The name of an attribute in a namespace is written {namespace}name.
+func canonicalAttributes(attrs []xml.Attr) []canonicalAttribute {
+	var canonical []canonicalAttribute
+	for _, attr := range attrs {
+		if attr.Name.Space == "xmlns" || attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+			continue
+		}
+		name := attr.Name.Local
+		if attr.Name.Space != "" {
+			name = "{" + attr.Name.Space + "}" + name
+		}
+		canonical = append(canonical, canonicalAttribute{name: name, value: attr.Value})
+	}
+	sort.Slice(canonical, func(i, j int) bool {
+		return canonical[i].name < canonical[j].name
+	})
+	return canonical
+}
+
+// escapeXMLText writes the text with the XML special characters escaped
+func escapeXMLText(b *strings.Builder, text string) {
+	_ = xml.EscapeText(b, []byte(text))
+}
@@ -0,0 +1,101 @@
+package spectest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalXML(t *testing.T) {
+	canonical, err := canonicalXML([]byte(`<?xml version="1.0"?>
+<!-- user -->
+<s:Envelope xmlns:s="urn:soap"><s:Body>
+	<u:User xmlns:u="urn:user" role="admin" id="1"><u:Name>  bob  </u:Name><u:Note/>text &amp; more</u:User>
+</s:Body></s:Envelope>`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, `<Envelope xmlns="urn:soap">
+  <Body>
+    <User xmlns="urn:user" id="1" role="admin">
+      <Name>bob</Name>
+      <Note></Note>
+      text &amp; more
+    </User>
+  </Body>
+</Envelope>
+`, canonical)
+}
+
+func TestCanonicalXMLIsEqualForEquivalentDocuments(t *testing.T) {
+	expected, err := canonicalXML([]byte(`<a:user xmlns:a="urn:user" id="1" xmlns:x="urn:x" x:type="t"><a:name>bob</a:name><a:tags/></a:user>`))
+	assert.NoError(t, err)
+	actual, err := canonicalXML([]byte(`<user xmlns="urn:user" xmlns:y="urn:x" y:type="t" id="1">
+	<name>bob</name>
+	<tags></tags>
+</user>`))
+	assert.NoError(t, err)
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestCanonicalXMLErrors(t *testing.T) {
+	for document, message := range map[string]string{
+		"":            "XML document has no root element",
+		"<a></a><b/>": "XML document has more than one root element",
+		"<a></b>":     "XML syntax error on line 1: element <a> closed by </b>",
+		"text<a/>":    "XML document has text outside of the root element",
+	} {
+		_, err := canonicalXML([]byte(document))
+		assert.True(t, err != nil && err.Error() == message, document)
+	}
+}
+
+func TestIsXMLContentType(t *testing.T) {
+	for contentType, isXML := range map[string]bool{
+		"application/xml":                    true,
+		"text/xml; charset=utf-8":            true,
+		"application/soap+xml;charset=UTF-8": true,
+		"application/json":                   false,
+		"text/html":                          false,
+		"":                                   false,
+	} {
+		assert.Equal(t, isXML, isXMLContentType(contentType), contentType)
+	}
+}
+
+func TestResponseBodyComparesXMLCanonically(t *testing.T) {
+	New().
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+			_, _ = w.Write([]byte(`<?xml version="1.0"?><env:Envelope xmlns:env="urn:soap"><env:Body><user id="1" role="admin"><name>bob</name></user></env:Body></env:Envelope>`))
+		}).
+		Get("/user").
+		Expect(t).
+		Body(`<s:Envelope xmlns:s="urn:soap">
+	<s:Body>
+		<user role="admin" id="1">
+			<name>bob</name>
+		</user>
+	</s:Body>
+</s:Envelope>`).
+		End()
+}
+
+func TestResponseBodyXMLMismatchShowsDiff(t *testing.T) {
+	withoutColor(t)
+	recorder := &errorRecorder{}
+
+	New().
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = w.Write([]byte(`<user><name>alice</name></user>`))
+		}).
+		Get("/user").
+		Expect(recorder).
+		Body(`<user><name>bob</name></user>`).
+		End()
+
+	assert.True(t, strings.Contains(recorder.message, "response body did not match expected XML body:"))
+	assert.True(t, strings.Contains(recorder.message, "-  <name>bob</name>"))
+	assert.True(t, strings.Contains(recorder.message, "+  <name>alice</name>"))
+}
@@ -0,0 +1,74 @@
+// Package mocks provides convenience functions for asserting XPath expressions on the XML bodies of mock requests
+package mocks
+
+import (
+	"net/http"
+
+	"github.com/nao1215/spectest"
+	httputil "github.com/nao1215/spectest/jsonpath/http"
+	"github.com/nao1215/spectest/xmlpath/xmlpath"
+)
+
+// Contains is a convenience function to assert that an XPath expression selects a node with the expected value,
+// or a string that contains the expected value
+func Contains(expression string, expected interface{}) spectest.Matcher {
+	return func(req *http.Request, mockReq *spectest.MockRequest) error {
+		return xmlpath.Contains(expression, nil, expected, httputil.CopyRequest(req).Body)
+	}
+}
+
+// Equal is a convenience function to assert that the value of an XPath expression is equal to the expected value
+func Equal(expression string, expected interface{}) spectest.Matcher {
+	return func(req *http.Request, mockReq *spectest.MockRequest) error {
+		return xmlpath.Equal(expression, nil, expected, httputil.CopyRequest(req).Body)
+	}
+}
+
+// NotEqual is a function to check the value of an XPath expression is not equal to the given value
+func NotEqual(expression string, expected interface{}) spectest.Matcher {
+	return func(req *http.Request, mockReq *spectest.MockRequest) error {
+		return xmlpath.NotEqual(expression, nil, expected, httputil.CopyRequest(req).Body)
+	}
+}
+
+// Len asserts that an XPath expression selects the expected number of nodes
+func Len(expression string, expectedLength int) spectest.Matcher {
+	return func(req *http.Request, mockReq *spectest.MockRequest) error {
+		return xmlpath.Length(expression, nil, expectedLength, httputil.CopyRequest(req).Body)
+	}
+}
+
+// GreaterThan asserts that an XPath expression selects at least the given number of nodes
+func GreaterThan(expression string, minimumLength int) spectest.Matcher {
+	return func(req *http.Request, mockReq *spectest.MockRequest) error {
+		return xmlpath.GreaterThan(expression, nil, minimumLength, httputil.CopyRequest(req).Body)
+	}
+}
+
+// LessThan asserts that an XPath expression selects at most the given number of nodes
+func LessThan(expression string, maximumLength int) spectest.Matcher {
+	return func(req *http.Request, mockReq *spectest.MockRequest) error {
+		return xmlpath.LessThan(expression, nil, maximumLength, httputil.CopyRequest(req).Body)
+	}
+}
+
+// Present asserts that an XPath expression selects a node
+func Present(expression string) spectest.Matcher {
+	return func(req *http.Request, mockReq *spectest.MockRequest) error {
+		return xmlpath.Present(expression, nil, httputil.CopyRequest(req).Body)
+	}
+}
+
+// NotPresent asserts that an XPath expression selects no node
+func NotPresent(expression string) spectest.Matcher {
+	return func(req *http.Request, mockReq *spectest.MockRequest) error {
+		return xmlpath.NotPresent(expression, nil, httputil.CopyRequest(req).Body)
+	}
+}
+
+// Matches asserts that the value of an XPath expression matches the given regular expression
+func Matches(expression string, regexp string) spectest.Matcher {
+	return func(req *http.Request, mockReq *spectest.MockRequest) error {
+		return xmlpath.Matches(expression, nil, regexp, httputil.CopyRequest(req).Body)
+	}
+}
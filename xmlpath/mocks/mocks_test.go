@@ -0,0 +1,91 @@
+package mocks_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/nao1215/spectest"
+	"github.com/nao1215/spectest/xmlpath"
+	"github.com/nao1215/spectest/xmlpath/mocks"
+)
+
+const createOrderRequest = `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+	<soap:Body>
+		<o:CreateOrder xmlns:o="urn:orders">
+			<o:Customer id="7">Ada</o:Customer>
+			<o:Item sku="pen">2</o:Item>
+			<o:Item sku="ink">1</o:Item>
+		</o:CreateOrder>
+	</soap:Body>
+</soap:Envelope>`
+
+func TestMocks(t *testing.T) {
+	createOrderMock := spectest.NewMock().
+		Post("/orders-api").
+		AddMatcher(mocks.Equal("//o:Customer", "Ada")).
+		AddMatcher(mocks.Equal("//o:Customer", "Ada")). // ensure body can be re read after running matcher
+		AddMatcher(mocks.Equal("//Customer/@id", 7)).
+		AddMatcher(mocks.NotEqual("//Customer", "Bob")).
+		AddMatcher(mocks.Contains("//Item/@sku", "ink")).
+		AddMatcher(mocks.Len("//Item", 2)).
+		AddMatcher(mocks.GreaterThan("//Item", 1)).
+		AddMatcher(mocks.LessThan("//Item", 2)).
+		AddMatcher(mocks.Present("//soap:Body/o:CreateOrder")).
+		AddMatcher(mocks.NotPresent("//o:Discount")).
+		AddMatcher(mocks.Matches("//Item[@sku='pen']", `^\d+$`)).
+		RespondWith().
+		Header("Content-Type", "text/xml").
+		Body(`<CreateOrderResponse><OrderId>1234</OrderId></CreateOrderResponse>`).
+		Status(http.StatusOK).
+		End()
+
+	spectest.New().
+		Mocks(createOrderMock).
+		Handler(orderHandler()).
+		Post("/order").
+		Expect(t).
+		Status(http.StatusOK).
+		Assert(xmlpath.Equal("/CreateOrderResponse/OrderId", "1234")).
+		End()
+}
+
+func TestMocksDoNotMatch(t *testing.T) {
+	createOrderMock := spectest.NewMock().
+		Post("/orders-api").
+		AddMatcher(mocks.Len("//Item", 3)).
+		RespondWith().
+		Status(http.StatusOK).
+		End()
+
+	spectest.New().
+		Mocks(createOrderMock).
+		Handler(orderHandler()).
+		Post("/order").
+		Expect(t).
+		Status(http.StatusInternalServerError).
+		End()
+}
+
+func orderHandler() *http.ServeMux {
+	handler := http.NewServeMux()
+	handler.HandleFunc("/order", func(w http.ResponseWriter, r *http.Request) {
+		res, err := http.DefaultClient.Post("http://localhost:8080/orders-api", "text/xml", strings.NewReader(createOrderRequest))
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		if err != nil || res.StatusCode != http.StatusOK {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/xml")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(body)
+	})
+	return handler
+}
@@ -0,0 +1,234 @@
+// Package xmlpath provides assertions for XPath expressions on XML bodies, e.g. the responses of SOAP APIs.
+// The expressions are XPath 1.0. A prefixed name is resolved with the namespaces of the assertion chain,
+// or else with the prefixes declared in the document. An unprefixed name matches the local name in any namespace.
+package xmlpath
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/nao1215/spectest"
+	httputil "github.com/nao1215/spectest/jsonpath/http"
+	"github.com/nao1215/spectest/xmlpath/xmlpath"
+)
+
+// Contains is a convenience function to assert that an XPath expression selects a node with the expected value,
+// or a string that contains the expected value
+func Contains(expression string, expected interface{}) func(*http.Response, *http.Request) error {
+	return contains(nil, expression, expected)
+}
+
+// Equal is a convenience function to assert that the value of an XPath expression is equal to the expected value.
+// The value of one node is its text, and the value of several nodes is the []string of their texts.
+func Equal(expression string, expected interface{}) func(*http.Response, *http.Request) error {
+	return equal(nil, expression, expected)
+}
+
+// NotEqual is a function to check the value of an XPath expression is not equal to the given value
+func NotEqual(expression string, expected interface{}) func(*http.Response, *http.Request) error {
+	return notEqual(nil, expression, expected)
+}
+
+// Len asserts that an XPath expression selects the expected number of nodes
+func Len(expression string, expectedLength int) func(*http.Response, *http.Request) error {
+	return length(nil, expression, expectedLength)
+}
+
+// GreaterThan asserts that an XPath expression selects at least the given number of nodes
+func GreaterThan(expression string, minimumLength int) func(*http.Response, *http.Request) error {
+	return greaterThan(nil, expression, minimumLength)
+}
+
+// LessThan asserts that an XPath expression selects at most the given number of nodes
+func LessThan(expression string, maximumLength int) func(*http.Response, *http.Request) error {
+	return lessThan(nil, expression, maximumLength)
+}
+
+// Present asserts that an XPath expression selects a node
+func Present(expression string) func(*http.Response, *http.Request) error {
+	return present(nil, expression)
+}
+
+// NotPresent asserts that an XPath expression selects no node
+func NotPresent(expression string) func(*http.Response, *http.Request) error {
+	return notPresent(nil, expression)
+}
+
+// Matches asserts that the value of an XPath expression matches the given regular expression
+func Matches(expression string, regexp string) func(*http.Response, *http.Request) error {
+	return matches(nil, expression, regexp)
+}
+
+// Chain creates a new assertion chain
+func Chain() *AssertionChain {
+	return &AssertionChain{rootExpression: ""}
+}
+
+// Root creates a new assertion chain prefixed with the given expression, e.g. Root("/Envelope/Body/GetUserResponse")
+func Root(expression string) *AssertionChain {
+	return &AssertionChain{rootExpression: expression + "/"}
+}
+
+// AssertionChain supports chaining assertions, root expressions and namespaces
+type AssertionChain struct {
+	rootExpression string
+	namespaces     map[string]string
+	assertions     []func(*http.Response, *http.Request) error
+}
+
+// Namespace binds the prefix of the expressions of the chain to the namespace URI,
+// e.g. Namespace("soap", "http://schemas.xmlsoap.org/soap/envelope/").
+// The namespaces apply to all the assertions of the chain.
+func (r *AssertionChain) Namespace(prefix, uri string) *AssertionChain {
+	if r.namespaces == nil {
+		r.namespaces = map[string]string{}
+	}
+	r.namespaces[prefix] = uri
+	return r
+}
+
+// Equal adds an Equal assertion to the chain
+func (r *AssertionChain) Equal(expression string, expected interface{}) *AssertionChain {
+	r.assertions = append(r.assertions, equal(r.namespaces, r.rootExpression+expression, expected))
+	return r
+}
+
+// NotEqual adds an NotEqual assertion to the chain
+func (r *AssertionChain) NotEqual(expression string, expected interface{}) *AssertionChain {
+	r.assertions = append(r.assertions, notEqual(r.namespaces, r.rootExpression+expression, expected))
+	return r
+}
+
+// Contains adds an Contains assertion to the chain
+func (r *AssertionChain) Contains(expression string, expected interface{}) *AssertionChain {
+	r.assertions = append(r.assertions, contains(r.namespaces, r.rootExpression+expression, expected))
+	return r
+}
+
+// Len adds an Len assertion to the chain
+func (r *AssertionChain) Len(expression string, expectedLength int) *AssertionChain {
+	r.assertions = append(r.assertions, length(r.namespaces, r.rootExpression+expression, expectedLength))
+	return r
+}
+
+// GreaterThan adds an GreaterThan assertion to the chain
+func (r *AssertionChain) GreaterThan(expression string, minimumLength int) *AssertionChain {
+	r.assertions = append(r.assertions, greaterThan(r.namespaces, r.rootExpression+expression, minimumLength))
+	return r
+}
+
+// LessThan adds an LessThan assertion to the chain
+func (r *AssertionChain) LessThan(expression string, maximumLength int) *AssertionChain {
+	r.assertions = append(r.assertions, lessThan(r.namespaces, r.rootExpression+expression, maximumLength))
+	return r
+}
+
+// Present adds an Present assertion to the chain
+func (r *AssertionChain) Present(expression string) *AssertionChain {
+	r.assertions = append(r.assertions, present(r.namespaces, r.rootExpression+expression))
+	return r
+}
+
+// NotPresent adds an NotPresent assertion to the chain
+func (r *AssertionChain) NotPresent(expression string) *AssertionChain {
+	r.assertions = append(r.assertions, notPresent(r.namespaces, r.rootExpression+expression))
+	return r
+}
+
+// Matches adds an Matches assertion to the chain
+func (r *AssertionChain) Matches(expression, regexp string) *AssertionChain {
+	r.assertions = append(r.assertions, matches(r.namespaces, r.rootExpression+expression, regexp))
+	return r
+}
+
+// End returns an func(*http.Response, *http.Request) error which is a combination of the registered assertions.
+// Every assertion runs, and the errors of the failed assertions are joined with errors.Join.
+func (r *AssertionChain) End() func(*http.Response, *http.Request) error {
+	return func(res *http.Response, req *http.Request) error {
+		var errs []error
+		for _, assertion := range r.assertions {
+			if err := assertion(httputil.CopyResponse(res), httputil.CopyRequest(req)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+func contains(namespaces map[string]string, expression string, expected interface{}) func(*http.Response, *http.Request) error {
+	return assertion(namespaces, expression, expected, func(body io.Reader) error {
+		return xmlpath.Contains(expression, namespaces, expected, body)
+	})
+}
+
+func equal(namespaces map[string]string, expression string, expected interface{}) func(*http.Response, *http.Request) error {
+	return assertion(namespaces, expression, expected, func(body io.Reader) error {
+		return xmlpath.Equal(expression, namespaces, expected, body)
+	})
+}
+
+func notEqual(namespaces map[string]string, expression string, expected interface{}) func(*http.Response, *http.Request) error {
+	return assertion(namespaces, expression, expected, func(body io.Reader) error {
+		return xmlpath.NotEqual(expression, namespaces, expected, body)
+	})
+}
+
+func length(namespaces map[string]string, expression string, expectedLength int) func(*http.Response, *http.Request) error {
+	return assertion(namespaces, expression, expectedLength, func(body io.Reader) error {
+		return xmlpath.Length(expression, namespaces, expectedLength, body)
+	})
+}
+
+func greaterThan(namespaces map[string]string, expression string, minimumLength int) func(*http.Response, *http.Request) error {
+	return assertion(namespaces, expression, minimumLength, func(body io.Reader) error {
+		return xmlpath.GreaterThan(expression, namespaces, minimumLength, body)
+	})
+}
+
+func lessThan(namespaces map[string]string, expression string, maximumLength int) func(*http.Response, *http.Request) error {
+	return assertion(namespaces, expression, maximumLength, func(body io.Reader) error {
+		return xmlpath.LessThan(expression, namespaces, maximumLength, body)
+	})
+}
+
+func present(namespaces map[string]string, expression string) func(*http.Response, *http.Request) error {
+	return assertion(namespaces, expression, nil, func(body io.Reader) error {
+		return xmlpath.Present(expression, namespaces, body)
+	})
+}
+
+func notPresent(namespaces map[string]string, expression string) func(*http.Response, *http.Request) error {
+	return assertion(namespaces, expression, nil, func(body io.Reader) error {
+		return xmlpath.NotPresent(expression, namespaces, body)
+	})
+}
+
+func matches(namespaces map[string]string, expression string, regexp string) func(*http.Response, *http.Request) error {
+	return assertion(namespaces, expression, regexp, func(body io.Reader) error {
+		return xmlpath.Matches(expression, namespaces, regexp, body)
+	})
+}
+
+// assertion runs the check on the body of the response. Its error is a spectest.AssertionFailure
+// with the expression, the expected value and the value of the expression.
+func assertion(namespaces map[string]string, expression string, expected interface{}, check func(body io.Reader) error) func(*http.Response, *http.Request) error {
+	return func(res *http.Response, _ *http.Request) error {
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+		if err := check(bytes.NewReader(body)); err != nil {
+			actual, _ := xmlpath.XMLPath(bytes.NewReader(body), expression, namespaces)
+			return &spectest.AssertionFailure{
+				Category: spectest.FailureBody,
+				Path:     expression,
+				Expected: expected,
+				Actual:   actual,
+				Message:  err.Error(),
+			}
+		}
+		return nil
+	}
+}
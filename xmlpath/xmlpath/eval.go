@@ -0,0 +1,516 @@
+package xmlpath
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Expression is a compiled XPath 1.0 expression
+type Expression struct {
+	expression string
+	root       expr
+}
+
+// String returns the source of the expression
+func (e *Expression) String() string {
+	return e.expression
+}
+
+// Evaluate evaluates the expression on the document. The namespaces bind the prefixes of the expression to
+// namespace URIs; a prefix that is not bound is resolved with the prefixes declared in the document.
+// The value is a node-set ([]*Node), a string, a number (float64) or a boolean.
+func (e *Expression) Evaluate(doc *Node, namespaces map[string]string) (interface{}, error) {
+	return e.root.eval(&context{node: doc, position: 1, size: 1, namespaces: namespaces})
+}
+
+// context is the context of the evaluation of an expression
+type context struct {
+	node       *Node
+	position   int
+	size       int
+	namespaces map[string]string
+}
+
+// with returns the context of the node at the position of a node-set of the size
+func (c *context) with(node *Node, position, size int) *context {
+	return &context{node: node, position: position, size: size, namespaces: c.namespaces}
+}
+
+// namespace returns the namespace URI of a prefix of the expression
+func (c *context) namespace(prefix string) (string, error) {
+	if uri, ok := c.namespaces[prefix]; ok {
+		return uri, nil
+	}
+	if uri, ok := c.node.root().namespaces[prefix]; ok {
+		return uri, nil
+	}
+	if prefix == "xml" {
+		return xmlNamespace, nil
+	}
+	return "", fmt.Errorf("undefined namespace prefix '%s'", prefix)
+}
+
+// expr is a node of the syntax tree of an expression
+type expr interface {
+	eval(c *context) (interface{}, error)
+}
+
+// literalExpr is a string or a number
+type literalExpr struct {
+	value interface{}
+}
+
+func (e *literalExpr) eval(*context) (interface{}, error) {
+	return e.value, nil
+}
+
+// negateExpr is the unary minus
+type negateExpr struct {
+	operand expr
+}
+
+func (e *negateExpr) eval(c *context) (interface{}, error) {
+	v, err := e.operand.eval(c)
+	if err != nil {
+		return nil, err
+	}
+	return -toNumber(v), nil
+}
+
+// binaryExpr is an operator applied to two operands
+type binaryExpr struct {
+	op    string
+	left  expr
+	right expr
+}
+
+func (e *binaryExpr) eval(c *context) (interface{}, error) {
+	left, err := e.left.eval(c)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "and":
+		if !toBool(left) {
+			return false, nil
+		}
+	case "or":
+		if toBool(left) {
+			return true, nil
+		}
+	}
+	right, err := e.right.eval(c)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "and", "or":
+		return toBool(right), nil
+	case "|":
+		leftNodes, ok := left.([]*Node)
+		rightNodes, ok2 := right.([]*Node)
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("operands of '|' must be node-sets")
+		}
+		return documentOrder(append(append([]*Node{}, leftNodes...), rightNodes...)), nil
+	case "=", "!=", "<", "<=", ">", ">=":
+		return compare(e.op, left, right), nil
+	}
+
+	l, r := toNumber(left), toNumber(right)
+	switch e.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "div":
+		return l / r, nil
+	default: // mod
+		return math.Mod(l, r), nil
+	}
+}
+
+// filterExpr is a primary expression filtered by predicates, e.g. (//item)[1]
+type filterExpr struct {
+	primary    expr
+	predicates []expr
+}
+
+func (e *filterExpr) eval(c *context) (interface{}, error) {
+	v, err := e.primary.eval(c)
+	if err != nil {
+		return nil, err
+	}
+	nodes, ok := v.([]*Node)
+	if !ok {
+		return nil, fmt.Errorf("predicates can only filter node-sets")
+	}
+	return filter(c, nodes, e.predicates)
+}
+
+// pathExpr is a location path, or a location path that starts from the node-set of an expression
+type pathExpr struct {
+	start    expr
+	absolute bool
+	steps    []*step
+}
+
+func (e *pathExpr) eval(c *context) (interface{}, error) {
+	nodes := []*Node{c.node}
+	switch {
+	case e.start != nil:
+		v, err := e.start.eval(c)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if nodes, ok = v.([]*Node); !ok {
+			return nil, fmt.Errorf("a location path can only start from a node-set")
+		}
+	case e.absolute:
+		nodes = []*Node{c.node.root()}
+	}
+
+	for _, s := range e.steps {
+		var selected []*Node
+		for _, node := range nodes {
+			matched, err := s.selectFrom(c, node)
+			if err != nil {
+				return nil, err
+			}
+			selected = append(selected, matched...)
+		}
+		nodes = documentOrder(selected)
+	}
+	return nodes, nil
+}
+
+// step is a step of a location path
+type step struct {
+	axis       string
+	test       nodeTest
+	predicates []expr
+}
+
+// selectFrom returns the nodes of the axis of the node that match the node test and the predicates
+func (s *step) selectFrom(c *context, node *Node) ([]*Node, error) {
+	var matched []*Node
+	for _, candidate := range axis(s.axis, node) {
+		ok, err := s.test.matches(c, candidate, s.axis == "attribute")
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, candidate)
+		}
+	}
+	return filter(c, matched, s.predicates)
+}
+
+// filter returns the nodes for which the predicates are true. A number predicate selects the node at the position.
+func filter(c *context, nodes []*Node, predicates []expr) ([]*Node, error) {
+	for _, predicate := range predicates {
+		var kept []*Node
+		for i, node := range nodes {
+			v, err := predicate.eval(c.with(node, i+1, len(nodes)))
+			if err != nil {
+				return nil, err
+			}
+			if n, ok := v.(float64); ok {
+				if n == float64(i+1) {
+					kept = append(kept, node)
+				}
+			} else if toBool(v) {
+				kept = append(kept, node)
+			}
+		}
+		nodes = kept
+	}
+	return nodes, nil
+}
+
+// axis returns the nodes of the axis of the node, in the order of proximity to the node
+func axis(name string, node *Node) []*Node {
+	var nodes []*Node
+	switch name {
+	case "child":
+		return node.Children
+	case "attribute":
+		return node.Attributes
+	case "self":
+		return []*Node{node}
+	case "parent":
+		if node.Parent != nil {
+			nodes = append(nodes, node.Parent)
+		}
+	case "descendant", "descendant-or-self":
+		if name == "descendant-or-self" {
+			nodes = append(nodes, node)
+		}
+		nodes = appendDescendants(nodes, node)
+	case "ancestor", "ancestor-or-self":
+		if name == "ancestor-or-self" {
+			nodes = append(nodes, node)
+		}
+		for parent := node.Parent; parent != nil; parent = parent.Parent {
+			nodes = append(nodes, parent)
+		}
+	case "following-sibling", "preceding-sibling":
+		if node.Type == AttributeNode || node.Parent == nil {
+			return nil
+		}
+		siblings := node.Parent.Children
+		for i, sibling := range siblings {
+			if sibling != node {
+				continue
+			}
+			if name == "following-sibling" {
+				return siblings[i+1:]
+			}
+			for j := i - 1; j >= 0; j-- {
+				nodes = append(nodes, siblings[j])
+			}
+		}
+	case "following", "preceding":
+		for _, other := range appendDescendants(nil, node.root()) {
+			if name == "following" && other.order > node.order && !isAncestor(node, other) {
+				nodes = append(nodes, other)
+			}
+			if name == "preceding" && other.order < node.order && !isAncestor(other, node) {
+				nodes = append([]*Node{other}, nodes...)
+			}
+		}
+	}
+	return nodes
+}
+
+// appendDescendants appends the descendants of the node in document order
+func appendDescendants(nodes []*Node, node *Node) []*Node {
+	for _, child := range node.Children {
+		nodes = append(nodes, child)
+		nodes = appendDescendants(nodes, child)
+	}
+	return nodes
+}
+
+// isAncestor returns true if the ancestor is an ancestor of the node, or the node itself
+func isAncestor(ancestor, node *Node) bool {
+	for ; node != nil; node = node.Parent {
+		if node == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// documentOrder sorts the nodes in document order and removes the duplicates
+func documentOrder(nodes []*Node) []*Node {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].order < nodes[j].order
+	})
+	unique := nodes[:0]
+	for i, node := range nodes {
+		if i == 0 || node != nodes[i-1] {
+			unique = append(unique, node)
+		}
+	}
+	return unique
+}
+
+// testKind is the kind of a node test
+type testKind int
+
+const (
+	// testName matches a name, e.g. item or ns:item
+	testName testKind = iota
+	// testAnyName matches any name, or any name in a namespace: * or ns:*
+	testAnyName
+	// testNode matches any node: node()
+	testNode
+	// testText matches the text nodes: text()
+	testText
+	// testNone matches no node: comment() and processing-instruction(), which are not part of the tree
+	testNone
+)
+
+// nodeTest is the node test of a step
+type nodeTest struct {
+	kind   testKind
+	prefix string
+	local  string
+}
+
+// matches returns true if the node matches the test. The name tests match the attributes on the attribute axis,
+// and the elements on the other axes. An unprefixed name matches the local name in any namespace.
+func (t nodeTest) matches(c *context, node *Node, attributeAxis bool) (bool, error) {
+	switch t.kind {
+	case testNode:
+		return true, nil
+	case testText:
+		return node.Type == TextNode, nil
+	case testNone:
+		return false, nil
+	}
+
+	principal := ElementNode
+	if attributeAxis {
+		principal = AttributeNode
+	}
+	if node.Type != principal || t.kind == testName && node.Name.Local != t.local {
+		return false, nil
+	}
+	if t.prefix == "" {
+		return true, nil
+	}
+	uri, err := c.namespace(t.prefix)
+	if err != nil {
+		return false, err
+	}
+	return node.Name.Space == uri, nil
+}
+
+// compare compares two values with an equality or a relational operator, following the rules of XPath 1.0:
+// a node-set is compared through the string values of its nodes.
+func compare(op string, left, right interface{}) bool {
+	leftNodes, leftIsNodes := left.([]*Node)
+	rightNodes, rightIsNodes := right.([]*Node)
+	switch {
+	case leftIsNodes && rightIsNodes:
+		for _, l := range leftNodes {
+			for _, r := range rightNodes {
+				if compareValues(op, l.String(), r.String()) {
+					return true
+				}
+			}
+		}
+		return false
+	case leftIsNodes:
+		if b, ok := right.(bool); ok {
+			return compareValues(op, len(leftNodes) > 0, b)
+		}
+		for _, l := range leftNodes {
+			if compareValues(op, l.String(), right) {
+				return true
+			}
+		}
+		return false
+	case rightIsNodes:
+		if b, ok := left.(bool); ok {
+			return compareValues(op, b, len(rightNodes) > 0)
+		}
+		for _, r := range rightNodes {
+			if compareValues(op, left, r.String()) {
+				return true
+			}
+		}
+		return false
+	default:
+		return compareValues(op, left, right)
+	}
+}
+
+// compareValues compares two strings, numbers or booleans
+func compareValues(op string, left, right interface{}) bool {
+	if op == "=" || op == "!=" {
+		var equal bool
+		_, leftIsBool := left.(bool)
+		_, rightIsBool := right.(bool)
+		_, leftIsNumber := left.(float64)
+		_, rightIsNumber := right.(float64)
+		switch {
+		case leftIsBool || rightIsBool:
+			equal = toBool(left) == toBool(right)
+		case leftIsNumber || rightIsNumber:
+			equal = toNumber(left) == toNumber(right)
+		default:
+			equal = toString(left) == toString(right)
+		}
+		return equal == (op == "=")
+	}
+
+	l, r := toNumber(left), toNumber(right)
+	switch op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	default: // >=
+		return l >= r
+	}
+}
+
+// toString converts a value to a string: the string value of the first node of a node-set
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case []*Node:
+		if len(v) == 0 {
+			return ""
+		}
+		return v[0].String()
+	case float64:
+		return formatNumber(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// toNumber converts a value to a number, NaN if it is not a number
+func toNumber(v interface{}) float64 {
+	switch v := v.(type) {
+	case float64:
+		return v
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	default:
+		n, err := strconv.ParseFloat(strings.TrimSpace(toString(v)), 64)
+		if err != nil {
+			return math.NaN()
+		}
+		return n
+	}
+}
+
+// toBool converts a value to a boolean: a non-empty node-set or string, a number that is neither 0 nor NaN
+func toBool(v interface{}) bool {
+	switch v := v.(type) {
+	case []*Node:
+		return len(v) > 0
+	case string:
+		return v != ""
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case bool:
+		return v
+	default:
+		return v != nil
+	}
+}
+
+// formatNumber formats a number like XPath: an integer without decimal point, NaN and Infinity
+func formatNumber(n float64) string {
+	switch {
+	case math.IsNaN(n):
+		return "NaN"
+	case math.IsInf(n, 1):
+		return "Infinity"
+	case math.IsInf(n, -1):
+		return "-Infinity"
+	default:
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+}
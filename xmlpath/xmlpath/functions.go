@@ -0,0 +1,178 @@
+package xmlpath
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// function is a function of the XPath 1.0 core function library
+type function struct {
+	minArgs int
+	// maxArgs is the maximum number of arguments, -1 if unlimited
+	maxArgs int
+	call    func(c *context, args []interface{}) (interface{}, error)
+}
+
+// functionExpr is a function call
+type functionExpr struct {
+	name     string
+	function function
+	args     []expr
+}
+
+func (e *functionExpr) eval(c *context) (interface{}, error) {
+	args := make([]interface{}, 0, len(e.args))
+	for _, arg := range e.args {
+		v, err := arg.eval(c)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	return e.function.call(c, args)
+}
+
+// functions is the core function library
+var functions = map[string]function{
+	"last": {0, 0, func(c *context, _ []interface{}) (interface{}, error) {
+		return float64(c.size), nil
+	}},
+	"position": {0, 0, func(c *context, _ []interface{}) (interface{}, error) {
+		return float64(c.position), nil
+	}},
+	"count": {1, 1, func(_ *context, args []interface{}) (interface{}, error) {
+		nodes, err := nodeSetArg("count", args[0])
+		return float64(len(nodes)), err
+	}},
+	"name":          {0, 1, nameFunction(func(n *Node) string { return n.QualifiedName() })},
+	"local-name":    {0, 1, nameFunction(func(n *Node) string { return n.Name.Local })},
+	"namespace-uri": {0, 1, nameFunction(func(n *Node) string { return n.Name.Space })},
+	"string": {0, 1, func(c *context, args []interface{}) (interface{}, error) {
+		return toString(contextArg(c, args)), nil
+	}},
+	"concat": {2, -1, func(_ *context, args []interface{}) (interface{}, error) {
+		var b strings.Builder
+		for _, arg := range args {
+			b.WriteString(toString(arg))
+		}
+		return b.String(), nil
+	}},
+	"starts-with": {2, 2, func(_ *context, args []interface{}) (interface{}, error) {
+		return strings.HasPrefix(toString(args[0]), toString(args[1])), nil
+	}},
+	"ends-with": {2, 2, func(_ *context, args []interface{}) (interface{}, error) {
+		return strings.HasSuffix(toString(args[0]), toString(args[1])), nil
+	}},
+	"contains": {2, 2, func(_ *context, args []interface{}) (interface{}, error) {
+		return strings.Contains(toString(args[0]), toString(args[1])), nil
+	}},
+	"substring-before": {2, 2, func(_ *context, args []interface{}) (interface{}, error) {
+		before, _, found := strings.Cut(toString(args[0]), toString(args[1]))
+		if !found {
+			return "", nil
+		}
+		return before, nil
+	}},
+	"substring-after": {2, 2, func(_ *context, args []interface{}) (interface{}, error) {
+		_, after, _ := strings.Cut(toString(args[0]), toString(args[1]))
+		return after, nil
+	}},
+	"substring": {2, 3, func(_ *context, args []interface{}) (interface{}, error) {
+		runes := []rune(toString(args[0]))
+		start := math.Round(toNumber(args[1]))
+		end := math.Inf(1)
+		if len(args) == 3 {
+			end = start + math.Round(toNumber(args[2]))
+		}
+		var b strings.Builder
+		for i, r := range runes {
+			if position := float64(i + 1); position >= start && position < end {
+				b.WriteRune(r)
+			}
+		}
+		return b.String(), nil
+	}},
+	"string-length": {0, 1, func(c *context, args []interface{}) (interface{}, error) {
+		return float64(utf8.RuneCountInString(toString(contextArg(c, args)))), nil
+	}},
+	"normalize-space": {0, 1, func(c *context, args []interface{}) (interface{}, error) {
+		return strings.Join(strings.Fields(toString(contextArg(c, args))), " "), nil
+	}},
+	"translate": {3, 3, func(_ *context, args []interface{}) (interface{}, error) {
+		from, to := []rune(toString(args[1])), []rune(toString(args[2]))
+		return strings.Map(func(r rune) rune {
+			for i, f := range from {
+				if f != r {
+					continue
+				}
+				if i < len(to) {
+					return to[i]
+				}
+				return -1
+			}
+			return r
+		}, toString(args[0])), nil
+	}},
+	"boolean": {1, 1, func(_ *context, args []interface{}) (interface{}, error) {
+		return toBool(args[0]), nil
+	}},
+	"not": {1, 1, func(_ *context, args []interface{}) (interface{}, error) {
+		return !toBool(args[0]), nil
+	}},
+	"true": {0, 0, func(*context, []interface{}) (interface{}, error) {
+		return true, nil
+	}},
+	"false": {0, 0, func(*context, []interface{}) (interface{}, error) {
+		return false, nil
+	}},
+	"number": {0, 1, func(c *context, args []interface{}) (interface{}, error) {
+		return toNumber(contextArg(c, args)), nil
+	}},
+	"sum": {1, 1, func(_ *context, args []interface{}) (interface{}, error) {
+		nodes, err := nodeSetArg("sum", args[0])
+		sum := 0.0
+		for _, node := range nodes {
+			sum += toNumber(node.String())
+		}
+		return sum, err
+	}},
+	"floor": {1, 1, func(_ *context, args []interface{}) (interface{}, error) {
+		return math.Floor(toNumber(args[0])), nil
+	}},
+	"ceiling": {1, 1, func(_ *context, args []interface{}) (interface{}, error) {
+		return math.Ceil(toNumber(args[0])), nil
+	}},
+	"round": {1, 1, func(_ *context, args []interface{}) (interface{}, error) {
+		return math.Floor(toNumber(args[0]) + 0.5), nil
+	}},
+}
+
+// contextArg returns the argument of a function, or the context node if the argument is omitted
+func contextArg(c *context, args []interface{}) interface{} {
+	if len(args) == 0 {
+		return []*Node{c.node}
+	}
+	return args[0]
+}
+
+// nodeSetArg returns the argument of a function that must be a node-set
+func nodeSetArg(name string, arg interface{}) ([]*Node, error) {
+	nodes, ok := arg.([]*Node)
+	if !ok {
+		return nil, fmt.Errorf("argument of function '%s' must be a node-set", name)
+	}
+	return nodes, nil
+}
+
+// nameFunction returns a function that returns the name of the first node of the argument, or of the context node
+func nameFunction(name func(*Node) string) func(c *context, args []interface{}) (interface{}, error) {
+	return func(c *context, args []interface{}) (interface{}, error) {
+		nodes, err := nodeSetArg("name", contextArg(c, args))
+		if err != nil || len(nodes) == 0 {
+			return "", err
+		}
+		return name(nodes[0]), nil
+	}
+}
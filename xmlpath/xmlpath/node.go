@@ -0,0 +1,210 @@
+package xmlpath
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// xmlNamespace is the namespace bound to the xml prefix
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// NodeType is the type of a node of an XML document
+type NodeType int
+
+const (
+	// DocumentNode is the root of the document
+	DocumentNode NodeType = iota
+	// ElementNode is an element
+	ElementNode
+	// AttributeNode is an attribute of an element
+	AttributeNode
+	// TextNode is the text, or the CDATA section, of an element
+	TextNode
+)
+
+// Node is a node of an XML document. The comments, the processing instructions and
+// the namespace declarations are not part of the tree.
+type Node struct {
+	// Type is the type of the node
+	Type NodeType
+	// Name is the name of an element or an attribute. Name.Space is the namespace URI.
+	Name xml.Name
+	// Prefix is the namespace prefix of the name of an element or an attribute
+	Prefix string
+	// Data is the value of an attribute or a text node
+	Data string
+	// Parent is the parent of the node: the element of an attribute, nil for the document
+	Parent *Node
+	// Children is the list of the element and text children of the node
+	Children []*Node
+	// Attributes is the list of the attributes of an element
+	Attributes []*Node
+
+	// order is the position of the node in document order
+	order int
+	// namespaces maps the prefixes declared in the document to their namespace URI, set on the document node
+	namespaces map[string]string
+}
+
+// Parse parses the XML document
+func Parse(reader io.Reader) (*Node, error) {
+	doc := &Node{Type: DocumentNode, namespaces: map[string]string{}}
+	decoder := xml.NewDecoder(reader)
+	decoder.Strict = true
+
+	current := doc
+	scopes := []map[string]string{{"xml": xmlNamespace}}
+	order := 1
+	for {
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			if current == doc && len(doc.Children) > 0 {
+				return nil, errors.New("document has more than one root element")
+			}
+			scope := make(map[string]string, len(scopes[len(scopes)-1]))
+			for prefix, uri := range scopes[len(scopes)-1] {
+				scope[prefix] = uri
+			}
+			for _, attr := range token.Attr {
+				switch {
+				case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+					scope[""] = attr.Value
+				case attr.Name.Space == "xmlns":
+					scope[attr.Name.Local] = attr.Value
+					if _, ok := doc.namespaces[attr.Name.Local]; !ok {
+						doc.namespaces[attr.Name.Local] = attr.Value
+					}
+				}
+			}
+			scopes = append(scopes, scope)
+
+			space, err := resolve(scope, token.Name.Space, true)
+			if err != nil {
+				return nil, err
+			}
+			element := &Node{
+				Type:   ElementNode,
+				Name:   xml.Name{Space: space, Local: token.Name.Local},
+				Prefix: token.Name.Space,
+				Parent: current,
+				order:  order,
+			}
+			order++
+			for _, attr := range token.Attr {
+				if attr.Name.Local == "xmlns" && attr.Name.Space == "" || attr.Name.Space == "xmlns" {
+					continue
+				}
+				space, err := resolve(scope, attr.Name.Space, false)
+				if err != nil {
+					return nil, err
+				}
+				element.Attributes = append(element.Attributes, &Node{
+					Type:   AttributeNode,
+					Name:   xml.Name{Space: space, Local: attr.Name.Local},
+					Prefix: attr.Name.Space,
+					Data:   attr.Value,
+					Parent: element,
+					order:  order,
+				})
+				order++
+			}
+			current.Children = append(current.Children, element)
+			current = element
+		case xml.EndElement:
+			if current.Type != ElementNode || current.Prefix != token.Name.Space || current.Name.Local != token.Name.Local {
+				return nil, fmt.Errorf("unexpected end element </%s>", qualifiedName(token.Name.Space, token.Name.Local))
+			}
+			current = current.Parent
+			scopes = scopes[:len(scopes)-1]
+		case xml.CharData:
+			if current.Type == DocumentNode {
+				if len(bytes.TrimSpace(token)) > 0 {
+					return nil, errors.New("text outside of the root element")
+				}
+				continue
+			}
+			if last := len(current.Children) - 1; last >= 0 && current.Children[last].Type == TextNode {
+				current.Children[last].Data += string(token)
+				continue
+			}
+			current.Children = append(current.Children, &Node{Type: TextNode, Data: string(token), Parent: current, order: order})
+			order++
+		}
+	}
+	if current != doc {
+		return nil, fmt.Errorf("element <%s> is not closed", qualifiedName(current.Prefix, current.Name.Local))
+	}
+	if len(doc.Children) == 0 {
+		return nil, errors.New("document has no root element")
+	}
+	return doc, nil
+}
+
+// resolve returns the namespace URI of the prefix. An unprefixed attribute is in no namespace.
+func resolve(scope map[string]string, prefix string, isElement bool) (string, error) {
+	if prefix == "" && !isElement {
+		return "", nil
+	}
+	uri, ok := scope[prefix]
+	if !ok && prefix != "" {
+		return "", fmt.Errorf("undefined namespace prefix '%s'", prefix)
+	}
+	return uri, nil
+}
+
+// qualifiedName returns the name with its prefix, e.g. soap:Body
+func qualifiedName(prefix, local string) string {
+	if prefix == "" {
+		return local
+	}
+	return prefix + ":" + local
+}
+
+// QualifiedName returns the name of an element or an attribute with its prefix, e.g. soap:Body
+func (n *Node) QualifiedName() string {
+	return qualifiedName(n.Prefix, n.Name.Local)
+}
+
+// String returns the string value of the node: the value of an attribute or a text node,
+// and the concatenation of the text of the descendants of an element or the document.
+func (n *Node) String() string {
+	switch n.Type {
+	case AttributeNode, TextNode:
+		return n.Data
+	default:
+		var b strings.Builder
+		n.writeText(&b)
+		return b.String()
+	}
+}
+
+// writeText writes the text of the descendants of the node
+func (n *Node) writeText(b *strings.Builder) {
+	for _, child := range n.Children {
+		if child.Type == TextNode {
+			b.WriteString(child.Data)
+		} else {
+			child.writeText(b)
+		}
+	}
+}
+
+// root returns the document node of the node
+func (n *Node) root() *Node {
+	for n.Parent != nil {
+		n = n.Parent
+	}
+	return n
+}
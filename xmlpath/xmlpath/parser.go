@@ -0,0 +1,582 @@
+package xmlpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// tokenKind is the kind of a token of an XPath expression
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	// tokenName is a name test: a name, a prefixed name, * or prefix:*
+	tokenName
+	// tokenFunction is a name followed by (, e.g. count or text
+	tokenFunction
+	// tokenAxis is a name followed by ::, e.g. ancestor
+	tokenAxis
+	tokenString
+	tokenNumber
+	// tokenOperator is one of / // | + - = != < <= > >= and or div mod *
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenLeftBracket
+	tokenRightBracket
+	tokenAt
+	tokenComma
+	tokenDot
+	tokenDotDot
+)
+
+// token is a token of an XPath expression
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// lexer splits an XPath expression into tokens
+type lexer struct {
+	input  string
+	pos    int
+	tokens []token
+}
+
+// tokenize splits the expression into tokens
+func tokenize(expression string) ([]token, error) {
+	l := &lexer{input: expression}
+	for {
+		l.skipSpaces()
+		if l.pos >= len(l.input) {
+			l.tokens = append(l.tokens, token{kind: tokenEOF, pos: l.pos})
+			return l.tokens, nil
+		}
+		if err := l.next(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// skipSpaces skips the white spaces
+func (l *lexer) skipSpaces() {
+	for l.pos < len(l.input) && strings.ContainsRune(" \t\r\n", rune(l.input[l.pos])) {
+		l.pos++
+	}
+}
+
+// emit adds a token that starts at the position
+func (l *lexer) emit(kind tokenKind, value string, start int) {
+	l.tokens = append(l.tokens, token{kind: kind, value: value, pos: start})
+}
+
+// operatorExpected returns true if * and the names and, or, div and mod are operators at this position:
+// there is a preceding token and it is not @, ::, (, [, , or an operator.
+func (l *lexer) operatorExpected() bool {
+	if len(l.tokens) == 0 {
+		return false
+	}
+	switch l.tokens[len(l.tokens)-1].kind { //nolint:exhaustive
+	case tokenAt, tokenAxis, tokenLeftParen, tokenLeftBracket, tokenComma, tokenOperator:
+		return false
+	default:
+		return true
+	}
+}
+
+// next reads the next token
+func (l *lexer) next() error {
+	start := l.pos
+	c := l.input[l.pos]
+	rest := l.input[l.pos:]
+	switch {
+	case strings.HasPrefix(rest, "//"):
+		l.pos += 2
+		l.emit(tokenOperator, "//", start)
+	case strings.HasPrefix(rest, "!="), strings.HasPrefix(rest, "<="), strings.HasPrefix(rest, ">="):
+		l.pos += 2
+		l.emit(tokenOperator, rest[:2], start)
+	case strings.HasPrefix(rest, ".."):
+		l.pos += 2
+		l.emit(tokenDotDot, "..", start)
+	case c == '.' && (len(rest) == 1 || !isDigit(rest[1])):
+		l.pos++
+		l.emit(tokenDot, ".", start)
+	case c == '/' || c == '|' || c == '+' || c == '-' || c == '=' || c == '<' || c == '>':
+		l.pos++
+		l.emit(tokenOperator, string(c), start)
+	case c == '*':
+		l.pos++
+		if l.operatorExpected() {
+			l.emit(tokenOperator, "*", start)
+		} else {
+			l.emit(tokenName, "*", start)
+		}
+	case c == '(':
+		l.pos++
+		l.emit(tokenLeftParen, "(", start)
+	case c == ')':
+		l.pos++
+		l.emit(tokenRightParen, ")", start)
+	case c == '[':
+		l.pos++
+		l.emit(tokenLeftBracket, "[", start)
+	case c == ']':
+		l.pos++
+		l.emit(tokenRightBracket, "]", start)
+	case c == '@':
+		l.pos++
+		l.emit(tokenAt, "@", start)
+	case c == ',':
+		l.pos++
+		l.emit(tokenComma, ",", start)
+	case c == '\'' || c == '"':
+		end := strings.IndexByte(rest[1:], c)
+		if end < 0 {
+			return fmt.Errorf("unterminated string at position %d", start)
+		}
+		l.pos += end + 2
+		l.emit(tokenString, rest[1:end+1], start)
+	case isDigit(c) || c == '.':
+		for l.pos < len(l.input) && (isDigit(l.input[l.pos]) || l.input[l.pos] == '.') {
+			l.pos++
+		}
+		l.emit(tokenNumber, l.input[start:l.pos], start)
+	default:
+		return l.name()
+	}
+	return nil
+}
+
+// name reads a name: an operator name, a function name, an axis name or a name test
+func (l *lexer) name() error {
+	start := l.pos
+	local := l.ncName()
+	if local == "" {
+		return fmt.Errorf("unexpected character '%c' at position %d", l.input[start], start)
+	}
+	if l.operatorExpected() {
+		switch local {
+		case "and", "or", "div", "mod":
+			l.emit(tokenOperator, local, start)
+			return nil
+		}
+		return fmt.Errorf("unexpected name '%s' at position %d", local, start)
+	}
+
+	if strings.HasPrefix(l.input[l.pos:], "::") {
+		l.pos += 2
+		l.emit(tokenAxis, local, start)
+		return nil
+	}
+	name := local
+	if l.pos < len(l.input) && l.input[l.pos] == ':' {
+		l.pos++
+		if l.pos < len(l.input) && l.input[l.pos] == '*' {
+			l.pos++
+			l.emit(tokenName, name+":*", start)
+			return nil
+		}
+		suffix := l.ncName()
+		if suffix == "" {
+			return fmt.Errorf("invalid name '%s:' at position %d", name, start)
+		}
+		name += ":" + suffix
+	}
+
+	end := l.pos
+	l.skipSpaces()
+	if l.pos < len(l.input) && l.input[l.pos] == '(' {
+		l.emit(tokenFunction, name, start)
+		return nil
+	}
+	l.pos = end
+	l.emit(tokenName, name, start)
+	return nil
+}
+
+// ncName reads a name without colon
+func (l *lexer) ncName() string {
+	start := l.pos
+	for i, r := range l.input[l.pos:] {
+		if r == '_' || unicode.IsLetter(r) || i > 0 && (r == '-' || r == '.' || unicode.IsDigit(r)) {
+			continue
+		}
+		l.pos = start + i
+		return l.input[start:l.pos]
+	}
+	l.pos = len(l.input)
+	return l.input[start:]
+}
+
+// isDigit returns true if the character is a decimal digit
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// parser builds the syntax tree of an XPath expression
+type parser struct {
+	expression string
+	tokens     []token
+	pos        int
+}
+
+// Compile parses the XPath 1.0 expression
+func Compile(expression string) (*Expression, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression '%s': %w", expression, err)
+	}
+	p := &parser{expression: expression, tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.peek().kind != tokenEOF {
+		err = p.unexpected()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression '%s': %w", expression, err)
+	}
+	return &Expression{expression: expression, root: root}, nil
+}
+
+// peek returns the current token
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+// advance returns the current token and moves to the next one
+func (p *parser) advance() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// isOperator returns true if the current token is one of the operators
+func (p *parser) isOperator(operators ...string) bool {
+	t := p.peek()
+	if t.kind != tokenOperator {
+		return false
+	}
+	for _, op := range operators {
+		if t.value == op {
+			return true
+		}
+	}
+	return false
+}
+
+// expect consumes a token of the kind
+func (p *parser) expect(kind tokenKind, value string) error {
+	if p.peek().kind != kind {
+		return fmt.Errorf("expected '%s' at position %d", value, p.peek().pos)
+	}
+	p.advance()
+	return nil
+}
+
+// unexpected returns the error of the current token, which is unexpected
+func (p *parser) unexpected() error {
+	return unexpectedToken(p.peek())
+}
+
+// unexpectedToken returns the error of an unexpected token
+func unexpectedToken(t token) error {
+	if t.kind == tokenEOF {
+		return fmt.Errorf("unexpected end of expression")
+	}
+	return fmt.Errorf("unexpected '%s' at position %d", t.value, t.pos)
+}
+
+// parseBinary parses a left-associative sequence of operands separated by the operators
+func (p *parser) parseBinary(operand func() (expr, error), operators ...string) (expr, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator(operators...) {
+		op := p.advance().value
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseOr() (expr, error) {
+	return p.parseBinary(p.parseAnd, "or")
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	return p.parseBinary(p.parseEquality, "and")
+}
+
+func (p *parser) parseEquality() (expr, error) {
+	return p.parseBinary(p.parseRelational, "=", "!=")
+}
+
+func (p *parser) parseRelational() (expr, error) {
+	return p.parseBinary(p.parseAdditive, "<", "<=", ">", ">=")
+}
+
+func (p *parser) parseAdditive() (expr, error) {
+	return p.parseBinary(p.parseMultiplicative, "+", "-")
+}
+
+func (p *parser) parseMultiplicative() (expr, error) {
+	return p.parseBinary(p.parseUnary, "*", "div", "mod")
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	if p.isOperator("-") {
+		p.advance()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &negateExpr{operand: operand}, nil
+	}
+	return p.parseBinary(p.parsePath, "|")
+}
+
+// parsePath parses a location path, or a filter expression optionally followed by a relative location path
+func (p *parser) parsePath() (expr, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokenOperator && (t.value == "/" || t.value == "//"):
+		path := &pathExpr{absolute: true}
+		p.advance()
+		if t.value == "//" {
+			path.steps = append(path.steps, descendantOrSelf())
+		} else if !p.startsStep() {
+			return path, nil
+		}
+		return path, p.parseSteps(path)
+	case p.startsStep():
+		path := &pathExpr{}
+		return path, p.parseSteps(path)
+	}
+
+	primary, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	predicates, err := p.parsePredicates()
+	if err != nil {
+		return nil, err
+	}
+	if len(predicates) > 0 {
+		primary = &filterExpr{primary: primary, predicates: predicates}
+	}
+	if !p.isOperator("/", "//") {
+		return primary, nil
+	}
+	path := &pathExpr{start: primary}
+	if p.advance().value == "//" {
+		path.steps = append(path.steps, descendantOrSelf())
+	}
+	return path, p.parseSteps(path)
+}
+
+// startsStep returns true if the current token starts a step of a location path
+func (p *parser) startsStep() bool {
+	t := p.peek()
+	switch t.kind { //nolint:exhaustive
+	case tokenName, tokenAxis, tokenAt, tokenDot, tokenDotDot:
+		return true
+	case tokenFunction:
+		return isNodeType(t.value)
+	default:
+		return false
+	}
+}
+
+// parseSteps parses the steps of a relative location path
+func (p *parser) parseSteps(path *pathExpr) error {
+	for {
+		s, err := p.parseStep()
+		if err != nil {
+			return err
+		}
+		path.steps = append(path.steps, s)
+		if !p.isOperator("/", "//") {
+			return nil
+		}
+		if p.advance().value == "//" {
+			path.steps = append(path.steps, descendantOrSelf())
+		}
+	}
+}
+
+// parseStep parses a step: an axis, a node test and predicates, or an abbreviated step
+func (p *parser) parseStep() (*step, error) {
+	t := p.advance()
+	switch t.kind { //nolint:exhaustive
+	case tokenDot:
+		return &step{axis: "self", test: nodeTest{kind: testNode}}, nil
+	case tokenDotDot:
+		return &step{axis: "parent", test: nodeTest{kind: testNode}}, nil
+	}
+
+	s := &step{axis: "child"}
+	switch t.kind { //nolint:exhaustive
+	case tokenAt:
+		s.axis = "attribute"
+		t = p.advance()
+	case tokenAxis:
+		if !isAxis(t.value) {
+			return nil, fmt.Errorf("unknown axis '%s' at position %d", t.value, t.pos)
+		}
+		s.axis = t.value
+		t = p.advance()
+	}
+
+	switch {
+	case t.kind == tokenName:
+		s.test = newNameTest(t.value)
+	case t.kind == tokenFunction && isNodeType(t.value):
+		if err := p.expect(tokenLeftParen, "("); err != nil {
+			return nil, err
+		}
+		if t.value == "processing-instruction" && p.peek().kind == tokenString {
+			p.advance()
+		}
+		if err := p.expect(tokenRightParen, ")"); err != nil {
+			return nil, err
+		}
+		s.test = nodeTest{kind: nodeTypes[t.value]}
+	default:
+		return nil, unexpectedToken(t)
+	}
+
+	predicates, err := p.parsePredicates()
+	if err != nil {
+		return nil, err
+	}
+	s.predicates = predicates
+	return s, nil
+}
+
+// parsePredicates parses the predicates in brackets
+func (p *parser) parsePredicates() ([]expr, error) {
+	var predicates []expr
+	for p.peek().kind == tokenLeftBracket {
+		p.advance()
+		predicate, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenRightBracket, "]"); err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, predicate)
+	}
+	return predicates, nil
+}
+
+// parsePrimary parses a parenthesized expression, a literal, a number or a function call
+func (p *parser) parsePrimary() (expr, error) {
+	t := p.advance()
+	switch t.kind { //nolint:exhaustive
+	case tokenLeftParen:
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expect(tokenRightParen, ")")
+	case tokenString:
+		return &literalExpr{value: t.value}, nil
+	case tokenNumber:
+		n, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s' at position %d", t.value, t.pos)
+		}
+		return &literalExpr{value: n}, nil
+	case tokenFunction:
+		return p.parseFunction(t)
+	default:
+		return nil, unexpectedToken(t)
+	}
+}
+
+// parseFunction parses the arguments of a function call
+func (p *parser) parseFunction(name token) (expr, error) {
+	f, ok := functions[name.value]
+	if !ok {
+		return nil, fmt.Errorf("unknown function '%s' at position %d", name.value, name.pos)
+	}
+	if err := p.expect(tokenLeftParen, "("); err != nil {
+		return nil, err
+	}
+	call := &functionExpr{name: name.value, function: f}
+	if p.peek().kind != tokenRightParen {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if p.peek().kind != tokenComma {
+				break
+			}
+			p.advance()
+		}
+	}
+	if err := p.expect(tokenRightParen, ")"); err != nil {
+		return nil, err
+	}
+	if len(call.args) < f.minArgs || f.maxArgs >= 0 && len(call.args) > f.maxArgs {
+		return nil, fmt.Errorf("wrong number of arguments for function '%s' at position %d", name.value, name.pos)
+	}
+	return call, nil
+}
+
+// descendantOrSelf is the step of the abbreviation //
+func descendantOrSelf() *step {
+	return &step{axis: "descendant-or-self", test: nodeTest{kind: testNode}}
+}
+
+// isAxis returns true if the name is a supported axis
+func isAxis(name string) bool {
+	switch name {
+	case "child", "attribute", "self", "parent", "descendant", "descendant-or-self", "ancestor", "ancestor-or-self",
+		"following-sibling", "preceding-sibling", "following", "preceding":
+		return true
+	default:
+		return false
+	}
+}
+
+// nodeTypes are the node type tests
+var nodeTypes = map[string]testKind{
+	"node":                   testNode,
+	"text":                   testText,
+	"comment":                testNone,
+	"processing-instruction": testNone,
+}
+
+// isNodeType returns true if the name is a node type test, e.g. text()
+func isNodeType(name string) bool {
+	_, ok := nodeTypes[name]
+	return ok
+}
+
+// newNameTest creates the test of a name: *, prefix:*, prefix:local or local
+func newNameTest(name string) nodeTest {
+	if name == "*" {
+		return nodeTest{kind: testAnyName}
+	}
+	prefix, local, found := strings.Cut(name, ":")
+	if !found {
+		return nodeTest{kind: testName, local: name}
+	}
+	if local == "*" {
+		return nodeTest{kind: testAnyName, prefix: prefix}
+	}
+	return nodeTest{kind: testName, prefix: prefix, local: local}
+}
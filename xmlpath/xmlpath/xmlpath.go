@@ -0,0 +1,263 @@
+// Package xmlpath is not referenced by user code. It evaluates XPath 1.0 expressions on XML documents.
+package xmlpath
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Contains asserts that an XPath expression selects a node with the expected value, or a string that contains it
+func Contains(expression string, namespaces map[string]string, expected interface{}, data io.Reader) error {
+	value, err := XMLPath(data, expression, namespaces)
+	if err != nil {
+		return err
+	}
+	switch value := value.(type) {
+	case []string:
+		for _, v := range value {
+			if ObjectsAreEqual(v, expected) {
+				return nil
+			}
+		}
+	case string:
+		if strings.Contains(value, fmt.Sprint(expected)) {
+			return nil
+		}
+	case nil:
+		return fmt.Errorf("value not present for expression: '%s'", expression)
+	default:
+		return fmt.Errorf("\"%v\" could not be searched for \"%v\"", value, expected)
+	}
+	return fmt.Errorf("\"%v\" does not contain \"%v\"", value, expected)
+}
+
+// Equal asserts that the value of an XPath expression is equal to the expected value
+func Equal(expression string, namespaces map[string]string, expected interface{}, data io.Reader) error {
+	value, err := XMLPath(data, expression, namespaces)
+	if err != nil {
+		return err
+	}
+	if !ObjectsAreEqual(value, expected) {
+		return fmt.Errorf("\"%v\" not equal to \"%v\"", value, expected)
+	}
+	return nil
+}
+
+// NotEqual asserts that the value of an XPath expression is not equal to the given value
+func NotEqual(expression string, namespaces map[string]string, expected interface{}, data io.Reader) error {
+	value, err := XMLPath(data, expression, namespaces)
+	if err != nil {
+		return err
+	}
+	if ObjectsAreEqual(value, expected) {
+		return fmt.Errorf("\"%s\" value is equal to \"%v\"", expression, expected)
+	}
+	return nil
+}
+
+// Length asserts that an XPath expression selects the expected number of nodes
+func Length(expression string, namespaces map[string]string, expectedLength int, data io.Reader) error {
+	length, err := count(data, expression, namespaces)
+	if err != nil {
+		return err
+	}
+	if length != expectedLength {
+		return fmt.Errorf("\"%d\" not equal to \"%d\"", length, expectedLength)
+	}
+	return nil
+}
+
+// GreaterThan asserts that an XPath expression selects at least the given number of nodes
+func GreaterThan(expression string, namespaces map[string]string, minimumLength int, data io.Reader) error {
+	length, err := count(data, expression, namespaces)
+	if err != nil {
+		return err
+	}
+	if length < minimumLength {
+		return fmt.Errorf("\"%d\" is less than \"%d\"", length, minimumLength)
+	}
+	return nil
+}
+
+// LessThan asserts that an XPath expression selects at most the given number of nodes
+func LessThan(expression string, namespaces map[string]string, maximumLength int, data io.Reader) error {
+	length, err := count(data, expression, namespaces)
+	if err != nil {
+		return err
+	}
+	if length > maximumLength {
+		return fmt.Errorf("\"%d\" is greater than \"%d\"", length, maximumLength)
+	}
+	return nil
+}
+
+// Present asserts that an XPath expression selects a node, or has a non-empty value
+func Present(expression string, namespaces map[string]string, data io.Reader) error {
+	value, err := evaluate(data, expression, namespaces)
+	if err != nil {
+		return err
+	}
+	if isEmpty(value) {
+		return fmt.Errorf("value not present for expression: '%s'", expression)
+	}
+	return nil
+}
+
+// NotPresent asserts that an XPath expression selects no node, or has an empty value
+func NotPresent(expression string, namespaces map[string]string, data io.Reader) error {
+	value, err := evaluate(data, expression, namespaces)
+	if err != nil {
+		return err
+	}
+	if !isEmpty(value) {
+		return fmt.Errorf("value present for expression: '%s'", expression)
+	}
+	return nil
+}
+
+// Matches asserts that the value of an XPath expression matches the regular expression
+func Matches(expression string, namespaces map[string]string, pattern string, data io.Reader) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern: '%s'", pattern)
+	}
+	value, err := XMLPath(data, expression, namespaces)
+	if err != nil {
+		return err
+	}
+	switch value := value.(type) {
+	case nil:
+		return fmt.Errorf("no match for pattern: '%s'", expression)
+	case []string:
+		return fmt.Errorf("unable to match %d nodes, the expression must select one node", len(value))
+	default:
+		if s := Format(value); !re.MatchString(s) {
+			return fmt.Errorf("value '%s' does not match pattern '%s'", s, pattern)
+		}
+		return nil
+	}
+}
+
+// XMLPath evaluates the XPath expression on the XML document. The value of a node-set is nil if it is empty,
+// the string value of its node if it has one node, and the list of the string values of its nodes otherwise.
+// The value of the other expressions is a string, a number (float64) or a boolean.
+func XMLPath(reader io.Reader, expression string, namespaces map[string]string) (interface{}, error) {
+	value, err := evaluate(reader, expression, namespaces)
+	if err != nil {
+		return nil, err
+	}
+	nodes, ok := value.([]*Node)
+	if !ok {
+		return value, nil
+	}
+	switch len(nodes) {
+	case 0:
+		return nil, nil
+	case 1:
+		return nodes[0].String(), nil
+	default:
+		values := make([]string, 0, len(nodes))
+		for _, node := range nodes {
+			values = append(values, node.String())
+		}
+		return values, nil
+	}
+}
+
+// evaluate parses the document and evaluates the expression
+func evaluate(reader io.Reader, expression string, namespaces map[string]string) (interface{}, error) {
+	compiled, err := Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := Parse(reader)
+	if err != nil {
+		return nil, fmt.Errorf("invalid XML document: %w", err)
+	}
+	value, err := compiled.Evaluate(doc, namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("evaluating '%s' resulted in error: '%w'", expression, err)
+	}
+	return value, nil
+}
+
+// count returns the number of the nodes selected by the expression
+func count(reader io.Reader, expression string, namespaces map[string]string) (int, error) {
+	value, err := evaluate(reader, expression, namespaces)
+	if err != nil {
+		return 0, err
+	}
+	nodes, ok := value.([]*Node)
+	if !ok {
+		return 0, errors.New("expression does not select nodes: '" + expression + "'")
+	}
+	return len(nodes), nil
+}
+
+// ObjectsAreEqual returns true if the value of an expression is equal to the expected value.
+// The expected value is compared as a number if it is a number, as a boolean if it is a boolean,
+// as a list of strings if it is a []string and as a string otherwise.
+func ObjectsAreEqual(value, expected interface{}) bool {
+	if value == nil || expected == nil {
+		return value == nil && expected == nil
+	}
+
+	if list, ok := expected.([]string); ok {
+		if s, ok := value.(string); ok {
+			return len(list) == 1 && list[0] == s
+		}
+		return reflect.DeepEqual(value, list)
+	}
+	if _, ok := value.([]string); ok {
+		return false
+	}
+
+	switch expected := expected.(type) {
+	case bool:
+		b, err := strconv.ParseBool(Format(value))
+		return err == nil && b == expected
+	case string:
+		return Format(value) == expected
+	}
+	if n, ok := toFloat(expected); ok {
+		return toNumber(value) == n
+	}
+	return Format(value) == fmt.Sprint(expected)
+}
+
+// Format formats the value of an expression as a string
+func Format(value interface{}) string {
+	return toString(value)
+}
+
+// toFloat converts a Go number to a float64
+func toFloat(v interface{}) (float64, bool) {
+	value := reflect.ValueOf(v)
+	switch value.Kind() { //nolint:exhaustive
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// isEmpty returns true if the value is an empty node-set or an empty string
+func isEmpty(value interface{}) bool {
+	switch value := value.(type) {
+	case []*Node:
+		return len(value) == 0
+	case string:
+		return value == ""
+	default:
+		return false
+	}
+}
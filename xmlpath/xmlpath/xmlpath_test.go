@@ -0,0 +1,195 @@
+package xmlpath
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const catalog = `<?xml version="1.0" encoding="UTF-8"?>
+<!-- a catalog -->
+<catalog xmlns="urn:catalog" xmlns:p="urn:price" xml:lang="en">
+	<book id="b1" available="true">
+		<title>Go</title>
+		<p:price currency="EUR">10.5</p:price>
+		<tag>programming</tag>
+		<tag>go</tag>
+	</book>
+	<book id="b2" available="false">
+		<title><![CDATA[XML & XPath]]></title>
+		<p:price currency="USD">20</p:price>
+	</book>
+	<book id="b3">
+		<title>Empty</title>
+	</book>
+</catalog>`
+
+func TestXMLPath(t *testing.T) {
+	tests := []struct {
+		expression string
+		namespaces map[string]string
+		expected   interface{}
+	}{
+		{expression: "/catalog/book[1]/title", expected: "Go"},
+		{expression: "//title", expected: []string{"Go", "XML & XPath", "Empty"}},
+		{expression: "//book[@id='b2']/title/text()", expected: "XML & XPath"},
+		{expression: "//book[last()]/@id", expected: "b3"},
+		{expression: "//book[position() < 3]/@id", expected: []string{"b1", "b2"}},
+		{expression: "//book[tag = 'go']/@id", expected: "b1"},
+		{expression: "//book[not(@available)]/@id", expected: "b3"},
+		{expression: "//book[@available='true' and p:price > 10]/@id", expected: "b1"},
+		{expression: "//book[p:price > 15 or @id = 'b3']/@id", expected: []string{"b2", "b3"}},
+		{expression: "//p:price/@currency", expected: []string{"EUR", "USD"}},
+		{expression: "//x:price", namespaces: map[string]string{"x": "urn:price"}, expected: []string{"10.5", "20"}},
+		{expression: "//p:price", namespaces: map[string]string{"p": "urn:other"}, expected: nil},
+		{expression: "//p:*/@currency", expected: []string{"EUR", "USD"}},
+		{expression: "/*/*[2]/@id", expected: "b2"},
+		{expression: "//book[1]/@*", expected: []string{"b1", "true"}},
+		{expression: "//tag[2]/preceding-sibling::tag", expected: "programming"},
+		{expression: "//title[. = 'Go']/following-sibling::*[1]", expected: "10.5"},
+		{expression: "//tag[1]/ancestor::*[last()]/@xml:lang", expected: "en"},
+		{expression: "//tag[1]/../@id", expected: "b1"},
+		{expression: "(//title)[2]", expected: "XML & XPath"},
+		{expression: "//title | //tag", expected: []string{"Go", "programming", "go", "XML & XPath", "Empty"}},
+		{expression: "count(//book)", expected: float64(3)},
+		{expression: "sum(//p:price) * 2", expected: float64(61)},
+		{expression: "count(//book) div 2", expected: 1.5},
+		{expression: "7 mod 3 - -1", expected: float64(2)},
+		{expression: "name(//p:price)", expected: "p:price"},
+		{expression: "local-name(/*)", expected: "catalog"},
+		{expression: "namespace-uri(/*)", expected: "urn:catalog"},
+		{expression: "concat(//book[1]/@id, '-', string(//book[2]/@id))", expected: "b1-b2"},
+		{expression: "starts-with(//book[2]/title, 'XML')", expected: true},
+		{expression: "contains(//book[1]/title, 'x')", expected: false},
+		{expression: "substring-before('2024-01-02', '-')", expected: "2024"},
+		{expression: "substring-after('2024-01-02', '-')", expected: "01-02"},
+		{expression: "substring('12345', 2, 3)", expected: "234"},
+		{expression: "string-length(//book[1]/title)", expected: float64(2)},
+		{expression: "normalize-space('  a   b ')", expected: "a b"},
+		{expression: "translate('abc', 'ab', 'A')", expected: "Ac"},
+		{expression: "boolean(//missing)", expected: false},
+		{expression: "number('x') = number('x')", expected: false},
+		{expression: "floor(2.5) + ceiling(2.5) + round(2.5)", expected: float64(8)},
+		{expression: "//missing", expected: nil},
+		{expression: "//book[@id = 'b1']/tag[.='go'][1]", expected: "go"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			value, err := XMLPath(strings.NewReader(catalog), test.expression, test.namespaces)
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, value)
+		})
+	}
+}
+
+func TestXMLPathErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		document   string
+		err        string
+	}{
+		{expression: "//book[", document: catalog, err: "invalid expression '//book[': unexpected end of expression"},
+		{expression: "//book]", document: catalog, err: "invalid expression '//book]': unexpected ']' at position 6"},
+		{expression: "unknown(1)", document: catalog, err: "invalid expression 'unknown(1)': unknown function 'unknown' at position 0"},
+		{expression: "count()", document: catalog, err: "invalid expression 'count()': wrong number of arguments for function 'count' at position 0"},
+		{expression: "'open", document: catalog, err: "invalid expression ''open': unterminated string at position 0"},
+		{expression: "//q:book", document: catalog, err: "evaluating '//q:book' resulted in error: 'undefined namespace prefix 'q''"},
+		{expression: "//a", document: "<a><b></a>", err: "invalid XML document: unexpected end element </a>"},
+		{expression: "//a", document: "<a>", err: "invalid XML document: element <a> is not closed"},
+		{expression: "//a", document: "", err: "invalid XML document: document has no root element"},
+		{expression: "//*", document: "<a/><b/>", err: "invalid XML document: document has more than one root element"},
+		{expression: "/a", document: "<a/>text", err: "invalid XML document: text outside of the root element"},
+		{expression: "//a", document: "<q:a/>", err: "invalid XML document: undefined namespace prefix 'q'"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			_, err := XMLPath(strings.NewReader(test.document), test.expression, nil)
+
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	tests := map[string]struct {
+		assert func() error
+		err    string
+	}{
+		"equal": {
+			assert: func() error { return Equal("//book[1]/title", nil, "Go", strings.NewReader(catalog)) },
+		},
+		"equal number": {
+			assert: func() error { return Equal("//book[2]/p:price", nil, 20, strings.NewReader(catalog)) },
+		},
+		"equal boolean": {
+			assert: func() error { return Equal("//book[1]/@available", nil, true, strings.NewReader(catalog)) },
+		},
+		"not equal": {
+			assert: func() error { return Equal("//book[1]/title", nil, "XML", strings.NewReader(catalog)) },
+			err:    `"Go" not equal to "XML"`,
+		},
+		"not equal assertion": {
+			assert: func() error { return NotEqual("//book[1]/title", nil, "Go", strings.NewReader(catalog)) },
+			err:    `"//book[1]/title" value is equal to "Go"`,
+		},
+		"contains node": {
+			assert: func() error { return Contains("//tag", nil, "go", strings.NewReader(catalog)) },
+		},
+		"contains substring": {
+			assert: func() error { return Contains("//book[2]/title", nil, "XPath", strings.NewReader(catalog)) },
+		},
+		"does not contain": {
+			assert: func() error { return Contains("//tag", nil, "rust", strings.NewReader(catalog)) },
+			err:    `"[programming go]" does not contain "rust"`,
+		},
+		"length": {
+			assert: func() error { return Length("//book", nil, 2, strings.NewReader(catalog)) },
+			err:    `"3" not equal to "2"`,
+		},
+		"length of a number": {
+			assert: func() error { return Length("count(//book)", nil, 3, strings.NewReader(catalog)) },
+			err:    "expression does not select nodes: 'count(//book)'",
+		},
+		"greater than": {
+			assert: func() error { return GreaterThan("//book", nil, 4, strings.NewReader(catalog)) },
+			err:    `"3" is less than "4"`,
+		},
+		"less than": {
+			assert: func() error { return LessThan("//book", nil, 3, strings.NewReader(catalog)) },
+		},
+		"present empty element": {
+			assert: func() error { return Present("//book[3]/tag | //catalog/@xml:lang", nil, strings.NewReader(catalog)) },
+		},
+		"not present": {
+			assert: func() error { return NotPresent("//book[3]/p:price", nil, strings.NewReader(catalog)) },
+		},
+		"present": {
+			assert: func() error { return Present("//book[3]/p:price", nil, strings.NewReader(catalog)) },
+			err:    "value not present for expression: '//book[3]/p:price'",
+		},
+		"matches": {
+			assert: func() error { return Matches("//book[1]/p:price", nil, `^\d+\.\d$`, strings.NewReader(catalog)) },
+		},
+		"does not match": {
+			assert: func() error { return Matches("//book[2]/p:price", nil, `^\d+\.\d$`, strings.NewReader(catalog)) },
+			err:    `value '20' does not match pattern '^\d+\.\d$'`,
+		},
+		"matches several nodes": {
+			assert: func() error { return Matches("//tag", nil, `.+`, strings.NewReader(catalog)) },
+			err:    "unable to match 2 nodes, the expression must select one node",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.assert()
+
+			if test.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package xmlpath_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/nao1215/spectest"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nao1215/spectest/xmlpath"
+)
+
+const getUserResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+	<soap:Body>
+		<u:GetUserResponse xmlns:u="urn:users">
+			<u:User id="42" status="active">
+				<u:Name>Ada</u:Name>
+				<u:Email>ada@example.com</u:Email>
+				<u:Role>admin</u:Role>
+				<u:Role>editor</u:Role>
+			</u:User>
+		</u:GetUserResponse>
+	</soap:Body>
+</soap:Envelope>`
+
+func soapHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(getUserResponse))
+	}
+}
+
+func TestApiTestAssertions(t *testing.T) {
+	spectest.New().
+		HandlerFunc(soapHandler()).
+		Post("/users").
+		Expect(t).
+		Assert(xmlpath.Equal("//u:User/u:Name", "Ada")).
+		Assert(xmlpath.Equal("//User/@id", 42)).
+		Assert(xmlpath.Equal("//Role", []string{"admin", "editor"})).
+		Assert(xmlpath.Equal("count(//Role) > 1", true)).
+		Assert(xmlpath.NotEqual("//User/@status", "disabled")).
+		Assert(xmlpath.Contains("//Role", "editor")).
+		Assert(xmlpath.Contains("//Email", "@example.com")).
+		Assert(xmlpath.Len("//Role", 2)).
+		Assert(xmlpath.GreaterThan("//Role", 1)).
+		Assert(xmlpath.LessThan("//Role", 3)).
+		Assert(xmlpath.Present("//soap:Body/*")).
+		Assert(xmlpath.NotPresent("//soap:Fault")).
+		Assert(xmlpath.Matches("//Email", `^[a-z]+@example\.com$`)).
+		End()
+}
+
+func TestApiTestChain(t *testing.T) {
+	spectest.New().
+		HandlerFunc(soapHandler()).
+		Post("/users").
+		Expect(t).
+		Assert(
+			xmlpath.Root("/env:Envelope/env:Body/users:GetUserResponse/users:User").
+				Namespace("env", "http://schemas.xmlsoap.org/soap/envelope/").
+				Namespace("users", "urn:users").
+				Equal("users:Name", "Ada").
+				Equal("@status", "active").
+				Contains("users:Role", "admin").
+				Len("users:Role", 2).
+				Present("users:Email").
+				NotPresent("users:Phone").
+				Matches("@id", `^\d+$`).
+				End(),
+		).
+		End()
+
+	spectest.New().
+		HandlerFunc(soapHandler()).
+		Post("/users").
+		Expect(t).
+		Assert(
+			xmlpath.Chain().
+				Equal("//Name", "Ada").
+				NotEqual("//Name", "Bob").
+				GreaterThan("//Role", 2-1).
+				LessThan("//Role", 2).
+				End(),
+		).
+		End()
+}
+
+func TestApiTestChainRunsEveryAssertion(t *testing.T) {
+	chain := xmlpath.Chain().
+		Equal("//Name", "Bob").
+		Present("//Phone").
+		Len("//Role", 1).
+		End()
+
+	err := chain(&http.Response{Body: io.NopCloser(bytes.NewBufferString(getUserResponse))}, &http.Request{})
+
+	assert.EqualError(t, err, "\"Ada\" not equal to \"Bob\"\nvalue not present for expression: '//Phone'\n\"2\" not equal to \"1\"")
+	var failure *spectest.AssertionFailure
+	assert.True(t, errors.As(err, &failure))
+	assert.Equal(t, spectest.FailureBody, failure.Category)
+	assert.Equal(t, "//Name", failure.Path)
+	assert.Equal(t, "Bob", failure.Expected)
+	assert.Equal(t, "Ada", failure.Actual)
+}
+
+func TestApiTestChainWithSoftAssertions(t *testing.T) {
+	result := spectest.New().
+		SoftAssertions().
+		Verifier(spectest.NoopVerifier{}).
+		HandlerFunc(soapHandler()).
+		Post("/users").
+		Expect(t).
+		Assert(xmlpath.Chain().Equal("//Name", "Bob").Equal("//User/@status", "active").Len("//Role", 3).End()).
+		End()
+
+	failures := result.Failures()
+	assert.Len(t, failures, 2)
+	assert.Equal(t, "//Name", failures[0].Path)
+	assert.Equal(t, "Ada", failures[0].Actual)
+	assert.Equal(t, "//Role", failures[1].Path)
+	assert.Equal(t, []string{"admin", "editor"}, failures[1].Actual)
+}
+
+func TestApiTestInvalidExpressionAndDocument(t *testing.T) {
+	err := xmlpath.Equal("//Name[", "Ada")(&http.Response{Body: io.NopCloser(bytes.NewBufferString(getUserResponse))}, nil)
+	assert.EqualError(t, err, "invalid expression '//Name[': unexpected end of expression")
+
+	err = xmlpath.Present("//Name")(&http.Response{Body: io.NopCloser(bytes.NewBufferString(`{"name": "Ada"}`))}, nil)
+	assert.EqualError(t, err, "invalid XML document: text outside of the root element")
+
+	err = xmlpath.Present("//x:Name")(&http.Response{Body: io.NopCloser(bytes.NewBufferString(getUserResponse))}, nil)
+	assert.EqualError(t, err, "evaluating '//x:Name' resulted in error: 'undefined namespace prefix 'x''")
+}
+
+func TestApiTestMatchesFailCompile(t *testing.T) {
+	err := xmlpath.Matches("//Name", `\`)(&http.Response{Body: io.NopCloser(bytes.NewBufferString(getUserResponse))}, nil)
+
+	assert.EqualError(t, err, `invalid pattern: '\'`)
+}